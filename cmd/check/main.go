@@ -15,133 +15,205 @@
 // check is a simple script that ensures a target JSON file matches a specified
 // schema.
 //
-// The schema is another JSON file that should match the desired format
-// structure of the target - check will ensure that the schema is a subset of
-// the target.
+// See the internal/check package for the schema semantics, and the
+// ./examples/*/checks/ directories for usage examples.
 //
-// check also provides simple variables and comparisons to easily evaluate
-// relationships in the target SPDX files. A property set to "=" will assign
-// the variable value to the corresponding property from the target, while a
-// property set to "==" will check the previously assigned variable to the
-// corresponding property from the target.
+// Every mismatch is printed, grouped by kind, and check exits with 1 if there
+// are any, or with 2 if the files cannot be read.
 //
-// See the ./examples/*/checks/ directories for usage examples.
-
+// With -suite, check runs the checks of a manifest instead, and reports them
+// as text, JUnit XML or TAP.
+//
+// With -generate, check prints a schema generated from the target statement
+// instead, asserting on the packages and files selected with -package and
+// -path. With -update, the assertions of the schema written as -generate
+// writes them are generated again in place, and assertions on the packages
+// and files selected with -package and -path are added; the others, such as
+// matchers and the names of variables, are kept as written.
 package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
-	"reflect"
+	"path/filepath"
+	"regexp"
 	"strings"
+
+	"github.com/docker/buildkit-syft-scanner/internal/check"
 )
 
+const (
+	exitMismatch = 1
+	exitError    = 2
+)
+
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
 func main() {
-	schemaFilename := os.Args[1]
-	targetFilename := os.Args[2]
+	var sel check.Selection
+	flag.Usage = func() {
+		name := filepath.Base(os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage:\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  %s <schema> <target>\n", name)
+		fmt.Fprintf(flag.CommandLine.Output(), "  %s -suite <manifest> [-run <regexp>] [-format text|junit|tap] [-o <file>]\n", name)
+		fmt.Fprintf(flag.CommandLine.Output(), "  %s -generate [-package <name|purl>]... [-path <path>]... <target>\n", name)
+		fmt.Fprintf(flag.CommandLine.Output(), "  %s -update [-package <name|purl>]... [-path <path>]... <schema> <target>\n\n", name)
+		flag.PrintDefaults()
+	}
+	generate := flag.Bool("generate", false, "print a schema generated from <target> instead of checking it")
+	update := flag.Bool("update", false, "refresh the generated assertions of <schema> from <target>, keeping the hand-written ones")
+	flag.Var((*stringsFlag)(&sel.Packages), "package", "name or package URL of a package to assert on when generating, may be repeated")
+	flag.Var((*stringsFlag)(&sel.Paths), "path", "path of a file to assert on when generating, may be repeated")
+	suite := flag.String("suite", "", "YAML or JSON manifest of the suites of checks to run")
+	run := flag.String("run", "", "regular expression of the names of the suites to run")
+	format := flag.String("format", check.OutputText, "output format of -suite: text, junit or tap")
+	output := flag.String("o", "", "file to write the results of -suite to, instead of stdout")
+	flag.Parse()
 
-	var schema, target map[string]interface{}
-	if dt, err := os.ReadFile(schemaFilename); err != nil {
-		panic(err)
-	} else {
-		if err := json.Unmarshal(dt, &schema); err != nil {
-			panic(err)
+	var err error
+	switch {
+	case *suite != "" && flag.NArg() == 0:
+		var failed bool
+		failed, err = runSuite(*suite, *run, *format, *output)
+		if err == nil && failed {
+			os.Exit(exitMismatch)
 		}
+	case *generate && flag.NArg() == 1:
+		err = generateSchema(os.Stdout, flag.Arg(0), sel)
+	case *update && flag.NArg() == 2:
+		err = updateSchema(flag.Arg(0), flag.Arg(1), sel)
+	case !*generate && !*update && flag.NArg() == 2:
+		err = checkSchema(flag.Arg(0), flag.Arg(1))
+	default:
+		flag.Usage()
+		os.Exit(exitError)
 	}
-	if dt, err := os.ReadFile(targetFilename); err != nil {
-		panic(err)
-	} else {
-		if err := json.Unmarshal(dt, &target); err != nil {
-			panic(err)
+	if err != nil {
+		if mismatches, ok := err.(check.Mismatches); ok {
+			fmt.Printf("%s: %v\n", flag.Arg(flag.NArg()-1), mismatches)
+			os.Exit(exitMismatch)
 		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
 	}
+}
 
-	vars := make(map[string]interface{})
-	err := check(schema, target, vars, true, "")
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+// runSuite runs the suites of the manifest matching run, and reports whether
+// any check failed.
+func runSuite(manifest string, run string, format string, output string) (bool, error) {
+	var filter *regexp.Regexp
+	if run != "" {
+		var err error
+		if filter, err = regexp.Compile(run); err != nil {
+			return false, fmt.Errorf("invalid -run: %w", err)
+		}
 	}
-	err = check(schema, target, vars, false, "")
+	m, err := check.LoadManifest(manifest)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return false, err
+	}
+	results := m.Run(filter)
+	if len(results) == 0 {
+		return false, fmt.Errorf("no suites to run in %s", manifest)
 	}
-}
 
-func check(schema interface{}, target interface{}, vars map[string]interface{}, assign bool, previous ...string) error {
-	schemaType := reflect.TypeOf(schema)
-	targetType := reflect.TypeOf(target)
-
-	if schemaType.Kind() == reflect.String {
-		if strings.HasPrefix(schema.(string), "==") {
-			if !assign {
-				key := schema.(string)[2:]
-				if _, ok := vars[key]; !ok {
-					return fmt.Errorf("variable %s not found", key)
-				}
-				if target != vars[key] {
-					return fmt.Errorf("variable mismatch on %s, expected %v, got %v", strings.Join(previous, "."), vars[key], target)
-				}
-			}
-			return nil
+	w := io.Writer(os.Stdout)
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return false, err
 		}
-		if strings.HasPrefix(schema.(string), "=") {
-			key := schema.(string)[1:]
-			if assign {
-				vars[key] = target
-			}
-			return nil
+		defer f.Close()
+		w = f
+	}
+	if err := check.WriteResults(w, format, results); err != nil {
+		return false, err
+	}
+	for _, r := range results {
+		if r.Failed() {
+			return true, nil
 		}
 	}
+	return false, nil
+}
 
-	if schemaType.Kind() != targetType.Kind() {
-		return fmt.Errorf("type mismatch on %s, expected %s, got %s", strings.Join(previous, "."), schemaType.Kind(), targetType.Kind())
+func checkSchema(schemaFilename string, targetFilename string) error {
+	var schema, target map[string]interface{}
+	if err := readJSON(schemaFilename, &schema); err != nil {
+		return err
 	}
-	switch schemaType.Kind() {
-	case reflect.Pointer:
-		return check(reflect.ValueOf(schema).Elem().Interface(), reflect.ValueOf(target).Elem().Interface(), vars, assign, previous...)
-	case reflect.Map:
-		return checkMap(schema.(map[string]interface{}), target.(map[string]interface{}), vars, assign, previous...)
-	case reflect.Array, reflect.Slice:
-		return checkSlice(schema.([]interface{}), target.([]interface{}), vars, assign, previous...)
-	default:
-		if !reflect.DeepEqual(schema, target) {
-			return fmt.Errorf("value mismatch on %s, expected %v, got %v", strings.Join(previous, "."), schema, target)
-		}
-		return nil
+	if err := readJSON(targetFilename, &target); err != nil {
+		return err
 	}
+	return check.Check(schema, target)
 }
 
-func checkMap(schema map[string]interface{}, target map[string]interface{}, vars map[string]interface{}, assign bool, previous ...string) error {
-	for k, v := range schema {
-		v2, ok := target[k]
-		if !ok {
-			return fmt.Errorf("map mismatch on %s, expected %v", strings.Join(previous, ".")+"."+k, schema)
-		}
-		if err := check(v, v2, vars, assign, append(previous, k)...); err != nil {
-			return err
-		}
+func generateSchema(w io.Writer, targetFilename string, sel check.Selection) error {
+	dt, err := generated(targetFilename, sel)
+	if err != nil {
+		return err
 	}
-	return nil
+	_, err = w.Write(dt)
+	return err
 }
 
-func checkSlice(schema []interface{}, target []interface{}, vars map[string]interface{}, assign bool, previous ...string) error {
-	if len(schema) > len(target) {
-		return fmt.Errorf("length mismatch on %s, expected at least %d, got %d", strings.Join(previous, "."), len(schema), len(target))
-	}
-	for i, v := range schema {
-		found := false
-		for _, v2 := range target {
-			if err := check(v, v2, vars, assign, append(previous, fmt.Sprintf("[%d]", i))...); err == nil {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return fmt.Errorf("slice mismatch on %s, expected %v", strings.Join(previous, ".")+fmt.Sprintf("[%d]", i), schema)
-		}
+// updateSchema updates the schema file in place from the target, for the
+// packages and files it asserts on along with sel, keeping the order of its
+// keys.
+func updateSchema(schemaFilename string, targetFilename string, sel check.Selection) error {
+	previous, err := os.ReadFile(schemaFilename)
+	if err != nil {
+		return err
+	}
+	var schema, target map[string]interface{}
+	if err := json.Unmarshal(previous, &schema); err != nil {
+		return fmt.Errorf("%s: %w", schemaFilename, err)
+	}
+	if err := readJSON(targetFilename, &target); err != nil {
+		return err
+	}
+	updated, err := check.Update(schema, target, sel)
+	if err != nil {
+		return fmt.Errorf("%s: %w", targetFilename, err)
+	}
+	dt, err := check.MarshalSchema(updated, previous)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(schemaFilename, dt, 0o644)
+}
+
+func generated(targetFilename string, sel check.Selection) ([]byte, error) {
+	var target map[string]interface{}
+	if err := readJSON(targetFilename, &target); err != nil {
+		return nil, err
+	}
+	schema, err := check.Generate(target, sel)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", targetFilename, err)
+	}
+	return check.MarshalSchema(schema, nil)
+}
+
+func readJSON(filename string, v interface{}) error {
+	dt, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(dt, v); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
 	}
 	return nil
 }
@@ -19,6 +19,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/anchore/go-logger"
 	alogrus "github.com/anchore/go-logger/adapter/logrus"
@@ -33,26 +35,43 @@ import (
 )
 
 func main() {
+	if err := run(); err != nil {
+		kind := internal.ErrorKindOf(err)
+		fmt.Fprintf(os.Stderr, "syft-scanner: %s error: %s\n", kind, internal.ErrorSummary(err))
+		if err := internal.WriteErrorReportFromEnvironment(err); err != nil {
+			fmt.Fprintf(os.Stderr, "syft-scanner: unable to write error report: %s\n", internal.ErrorSummary(err))
+		}
+		os.Exit(kind.ExitCode())
+	}
+}
+
+func run() error {
 	if err := enableLogs(); err != nil {
-		panic(fmt.Sprintf("unable to initialize logger: %+v", err))
+		return fmt.Errorf("unable to initialize logger: %w", err)
 	}
 
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// HACK: ensure that /tmp exists, as syft will fail if it does not
 	if err := os.Mkdir("/tmp", 0o777); err != nil && !errors.Is(err, os.ErrExist) {
-		panic("could not create /tmp directory")
+		return fmt.Errorf("could not create /tmp directory: %w", err)
 	}
 
 	logrus.Infof("starting syft scanner for buildkit %s", version.Version)
 
+	if len(os.Args) > 1 && os.Args[1] == "simulate" {
+		return simulate(ctx, os.Args[2:])
+	}
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		return verify(ctx, os.Args[2:])
+	}
+
 	scanner, err := internal.NewScannerFromEnvironment()
 	if err != nil {
-		panic(err)
-	}
-	if err := scanner.Scan(ctx); err != nil {
-		panic(err)
+		return err
 	}
+	return scanner.Scan(ctx)
 }
 
 const (
@@ -0,0 +1,121 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/buildkit-syft-scanner/internal"
+)
+
+type extrasFlag map[string]string
+
+func (e extrasFlag) String() string {
+	var parts []string
+	for name, p := range e {
+		parts = append(parts, name+"="+p)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (e extrasFlag) Set(v string) error {
+	name, p, ok := strings.Cut(v, "=")
+	if !ok {
+		p = v
+		name = filepath.Base(filepath.Clean(v))
+	}
+	if _, ok := e[name]; ok {
+		return fmt.Errorf("stage %q specified more than once", name)
+	}
+	e[name] = p
+	return nil
+}
+
+// simulate runs the scanner outside of BuildKit, on a layout reproducing the
+// one BuildKit provides.
+func simulate(ctx context.Context, args []string) error {
+	extras := extrasFlag{}
+	fs := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s simulate [options] <rootfs>\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(fs.Output(), "Scan <rootfs>, a directory, tarball or OCI layout, as BuildKit would.\n")
+		fmt.Fprintf(fs.Output(), "BUILDKIT_SCAN_* variables set in the environment are honoured.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Var(extras, "extra", "extra build stage to scan, as [name=]path, may be repeated")
+	output := fs.String("output", "", "directory to write the statements to, instead of printing them")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a single rootfs argument, got %d", fs.NArg())
+	}
+
+	layout, err := internal.NewLayout(fs.Arg(0), extras, *output)
+	if err != nil {
+		return err
+	}
+	defer layout.Close()
+
+	for k, v := range layout.Environ() {
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+	}
+
+	scanner, err := internal.NewScannerFromEnvironment()
+	if err != nil {
+		return err
+	}
+	if err := scanner.Scan(ctx); err != nil {
+		return err
+	}
+	if *output != "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(layout.Destination)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		fmt.Fprintf(os.Stderr, "==> %s\n", entry.Name())
+		if err := copyFile(os.Stdout, filepath.Join(layout.Destination, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(w io.Writer, p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,68 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/buildkit-syft-scanner/internal"
+)
+
+// verify checks the DSSE envelopes the scanner emitted for the statements in
+// a directory.
+func verify(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s verify --key <pem> <dir>\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(fs.Output(), "Verify that every statement in <dir> is signed with <pem>, and unchanged\n")
+		fmt.Fprintf(fs.Output(), "apart from the subject added by BuildKit. The subject is not signed: a\n")
+		fmt.Fprintf(fs.Output(), "statement copied to another image still verifies.\n\n")
+		fs.PrintDefaults()
+	}
+	key := fs.String("key", "", "PEM encoded public key, or the private key it belongs to")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if *key == "" || fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected a --key and a single directory argument")
+	}
+
+	dt, err := os.ReadFile(*key)
+	if err != nil {
+		return err
+	}
+	verifier, err := internal.LoadVerifier(dt)
+	if err != nil {
+		return fmt.Errorf("invalid key %q: %w", *key, err)
+	}
+	verified, err := verifier.VerifyStatements(ctx, fs.Arg(0))
+	for _, v := range verified {
+		fmt.Printf("%s: verified with %s\n", v.Name, v.KeyID)
+	}
+	if len(verified) > 0 {
+		// the statements are signed before BuildKit adds the image
+		fmt.Fprintf(os.Stderr, "warning: the subject of the statements is not signed, they verify whichever image they are attached to\n")
+	}
+	return err
+}
@@ -17,9 +17,11 @@
 set -eu
 
 GENERATOR=$1
+examples=()
 
 for example in "${@:2}"; do
   example=$(basename "$example")
+  examples+=("$example")
   selectCatalogers=""
   echo "[-] Building example ${example}..."
 
@@ -29,11 +31,18 @@ for example in "${@:2}"; do
 
   (set -x ; docker buildx build "./examples/${example}" --sbom="generator=${GENERATOR}${selectCatalogers}" --output="./examples/${example}/build")
 
-  echo "[-] Checking example ${example}..."
-  for file in "./examples/${example}"/checks/*.json; do
-    echo "  [-] Checking schema ${file}..."
-    go run ./cmd/check "$PWD/$file" $PWD/examples/${example}/build/${file#"./examples/${example}/checks/"}
-  done
-  
+  if [ -n "${UPDATE:-}" ]; then
+    echo "[-] Updating the schemas of example ${example}..."
+    for file in "./examples/${example}"/checks/*.json; do
+      go run ./cmd/check -update "$PWD/$file" "$PWD/examples/${example}/build/${file#"./examples/${example}/checks/"}"
+    done
+  fi
+
   echo ""
 done
+
+if [ -z "${UPDATE:-}" ]; then
+  echo "[-] Checking examples..."
+  run="^($(IFS='|'; echo "${examples[*]}"))\$"
+  go run ./cmd/check -suite ./examples/suite.yaml -run "$run" -format "${CHECK_FORMAT:-text}" ${CHECK_OUTPUT:+-o "$CHECK_OUTPUT"}
+fi
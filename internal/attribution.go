@@ -0,0 +1,284 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/anchore/syft/syft/artifact"
+	"github.com/anchore/syft/syft/file"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/sbom"
+)
+
+const (
+	// stageAnnotation and stagePathAnnotation are set on the locations of
+	// packages attributed to a build stage, to the name of the stage and the
+	// path of the file in it.
+	stageAnnotation     = propertyNamespace + ":stage"
+	stagePathAnnotation = propertyNamespace + ":stage-path"
+)
+
+// origin is a file of a build stage that the stage's SBOM associates with
+// packages.
+type origin struct {
+	stage    string
+	path     string
+	packages []pkg.Package
+	// relationships are those between packages
+	relationships []artifact.Relationship
+}
+
+// annotate records the origin o on l.
+func (o origin) annotate(l file.Location) file.Location {
+	return annotateLocation(l, map[string]string{
+		stageAnnotation:     o.stage,
+		stagePathAnnotation: o.path,
+	})
+}
+
+// annotateLocation returns a copy of l with annotations added.
+func annotateLocation(l file.Location, annotations map[string]string) file.Location {
+	// annotations are shared between copies of a location, so they are
+	// cloned rather than modified in place
+	l.Annotations = maps.Clone(l.Annotations)
+	for key, value := range annotations {
+		l = l.WithAnnotation(key, value)
+	}
+	return l
+}
+
+// attribution is a file of the core target that is byte-identical to files
+// of build stages, and was presumably copied from them.
+type attribution struct {
+	path    string
+	digest  string
+	origins []origin
+}
+
+// attributeFiles finds the files of core that are identical to files the
+// SBOMs of the stages associate with packages.
+func attributeFiles(ctx context.Context, core Target, stages []Target, stageSBOMs []sbom.SBOM) ([]attribution, error) {
+	byDigest := map[string][]origin{}
+	sizes := map[int64]struct{}{}
+	for i, stage := range stages {
+		for p, o := range stageOrigins(stage, stageSBOMs[i]) {
+			fi, err := os.Stat(filepath.Join(stage.Path, filepath.FromSlash(p)))
+			if err != nil || !fi.Mode().IsRegular() || fi.Size() == 0 {
+				continue
+			}
+			digest, err := fileDigest(filepath.Join(stage.Path, filepath.FromSlash(p)))
+			if err != nil {
+				return nil, err
+			}
+			byDigest[digest] = append(byDigest[digest], o)
+			sizes[fi.Size()] = struct{}{}
+		}
+	}
+	if len(byDigest) == 0 {
+		return nil, nil
+	}
+
+	exclude := core.config().ExcludeConfig().Paths
+	var attributions []attribution
+	err := filepath.WalkDir(core.Path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(core.Path, p)
+		if err != nil {
+			return err
+		}
+		if rel != "." && isExcluded(exclude, filepath.ToSlash(rel)) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if _, ok := sizes[fi.Size()]; !ok {
+			return nil
+		}
+		digest, err := fileDigest(p)
+		if err != nil {
+			return err
+		}
+		if origins, ok := byDigest[digest]; ok {
+			attributions = append(attributions, attribution{
+				path:    "/" + filepath.ToSlash(rel),
+				digest:  digest,
+				origins: origins,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attributions, nil
+}
+
+// stageOrigins returns the files the SBOM of stage associates with packages,
+// by path.
+func stageOrigins(stage Target, s sbom.SBOM) map[string]origin {
+	origins := map[string]origin{}
+	for _, p := range s.Artifacts.Packages.Sorted() {
+		paths := map[string]struct{}{}
+		for _, l := range p.Locations.ToSlice() {
+			paths[l.RealPath] = struct{}{}
+		}
+		for _, c := range s.CoordinatesForPackage(p, artifact.ContainsRelationship, artifact.EvidentByRelationship) {
+			paths[c.RealPath] = struct{}{}
+		}
+		for path := range paths {
+			o := origins[path]
+			o.stage, o.path = stage.Name(), path
+			o.packages = append(o.packages, p)
+			origins[path] = o
+		}
+	}
+	for path, o := range origins {
+		ids := map[artifact.ID]struct{}{}
+		for _, p := range o.packages {
+			ids[p.ID()] = struct{}{}
+		}
+		for _, r := range s.Relationships {
+			_, from := ids[r.From.ID()]
+			_, to := ids[r.To.ID()]
+			if from && to {
+				o.relationships = append(o.relationships, r)
+			}
+		}
+		origins[path] = o
+	}
+	return origins
+}
+
+// applyAttributions adds to the SBOM of the core target the packages that
+// the stages associate with the attributed files, found at the path of the
+// file in the core target. Packages already in the SBOM, by purl, are not
+// added again, but their location at that path is annotated with the origin.
+func applyAttributions(s *sbom.SBOM, attributions []attribution) {
+	byPURL := map[string]pkg.Package{}
+	for _, p := range s.Artifacts.Packages.Sorted() {
+		if p.PURL != "" {
+			byPURL[p.PURL] = p
+		}
+	}
+	if s.Artifacts.FileDigests == nil {
+		s.Artifacts.FileDigests = map[file.Coordinates][]file.Digest{}
+	}
+
+	for _, a := range attributions {
+		coords := file.Coordinates{RealPath: a.path}
+		if _, ok := s.Artifacts.FileDigests[coords]; !ok {
+			s.Artifacts.FileDigests[coords] = []file.Digest{{Algorithm: "sha256", Value: a.digest}}
+		}
+		for _, o := range a.origins {
+			added := map[artifact.ID]pkg.Package{}
+			for _, p := range o.packages {
+				if existing, ok := byPURL[p.PURL]; ok && p.PURL != "" {
+					// the package was also found in the core target, its
+					// location at the copied file records the origin
+					var locations file.LocationSet
+					for _, l := range existing.Locations.ToSlice() {
+						if l.RealPath == a.path {
+							l = o.annotate(l)
+						}
+						locations.Add(l)
+					}
+					existing.Locations = locations
+					s.Artifacts.Packages.Delete(existing.ID())
+					s.Artifacts.Packages.Add(existing)
+					byPURL[p.PURL] = existing
+					added[p.ID()] = existing
+					continue
+				}
+				loc := o.annotate(file.NewLocationFromCoordinates(coords).
+					WithAnnotation(pkg.EvidenceAnnotationKey, pkg.PrimaryEvidenceAnnotation))
+				copied := p
+				copied.Locations = file.NewLocationSet(loc)
+				copied.SetID()
+				s.Artifacts.Packages.Add(copied)
+				s.Relationships = append(s.Relationships, artifact.Relationship{
+					From: copied,
+					To:   coords,
+					Type: artifact.EvidentByRelationship,
+				})
+				added[p.ID()] = copied
+				if copied.PURL != "" {
+					byPURL[copied.PURL] = copied
+				}
+			}
+			for _, r := range o.relationships {
+				from, ok := added[r.From.ID()]
+				if !ok {
+					continue
+				}
+				to, ok := added[r.To.ID()]
+				if !ok {
+					continue
+				}
+				s.Relationships = append(s.Relationships, artifact.Relationship{From: from, To: to, Type: r.Type, Data: r.Data})
+			}
+		}
+	}
+}
+
+// attributionProperties records the provenance of attributed files, as
+// "<path>=<stage>:<path in stage>".
+func attributionProperties(attributions []attribution) []property {
+	var values []string
+	for _, a := range attributions {
+		for _, o := range a.origins {
+			values = append(values, a.path+"="+o.stage+":"+o.path)
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	sort.Strings(values)
+	return []property{{name: "copied-from", values: values}}
+}
+
+func fileDigest(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
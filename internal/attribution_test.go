@@ -0,0 +1,155 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/anchore/syft/syft/artifact"
+	"github.com/anchore/syft/syft/file"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/sbom"
+)
+
+func TestAttributeFiles(t *testing.T) {
+	dir := t.TempDir()
+	core := Target{Path: buildFixture(t, filepath.Join(dir, "sbom"), files(map[string]string{
+		"usr/bin/tool":      "tool binary",
+		"usr/bin/other":     "other binary",
+		"proc/tool":         "tool binary",
+		"usr/share/empty":   "",
+		"usr/share/unowned": "unowned",
+	}))}
+	stage := Target{Path: buildFixture(t, filepath.Join(dir, "build"), files(map[string]string{
+		"out/tool":     "tool binary",
+		"out/empty":    "",
+		"out/unowned":  "unowned",
+		"out/modified": "other binary, modified",
+	}))}
+
+	tool := pkg.Package{
+		Name:      "tool",
+		Version:   "1.0.0",
+		Type:      pkg.GoModulePkg,
+		PURL:      "pkg:golang/example.com/tool@1.0.0",
+		Locations: file.NewLocationSet(file.NewLocation("/out/tool")),
+	}
+	tool.SetID()
+	dep := pkg.Package{
+		Name:      "example.com/dep",
+		Version:   "v0.1.0",
+		Type:      pkg.GoModulePkg,
+		PURL:      "pkg:golang/example.com/dep@v0.1.0",
+		Locations: file.NewLocationSet(file.NewLocation("/out/tool")),
+	}
+	dep.SetID()
+	empty := pkg.Package{Name: "empty", Locations: file.NewLocationSet(file.NewLocation("/out/empty"))}
+	empty.SetID()
+	modified := pkg.Package{Name: "modified", Locations: file.NewLocationSet(file.NewLocation("/out/modified"))}
+	modified.SetID()
+	stageSBOM := sbom.SBOM{
+		Artifacts: sbom.Artifacts{Packages: pkg.NewCollection(tool, dep, empty, modified)},
+		Relationships: []artifact.Relationship{
+			{From: dep, To: tool, Type: artifact.DependencyOfRelationship},
+		},
+	}
+
+	attributions, err := attributeFiles(context.Background(), core, []Target{stage}, []sbom.SBOM{stageSBOM})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attributions) != 1 {
+		t.Fatalf("expected a single attributed file, got %+v", attributions)
+	}
+	if a := attributions[0]; a.path != "/usr/bin/tool" || len(a.origins) != 1 || a.origins[0].stage != "build" || a.origins[0].path != "/out/tool" {
+		t.Fatalf("unexpected attribution %+v", a)
+	}
+
+	existing := pkg.Package{Name: "example.com/dep", Version: "v0.1.0", PURL: dep.PURL, Locations: file.NewLocationSet(file.NewLocation("/usr/bin/tool"))}
+	existing.SetID()
+	coreSBOM := sbom.SBOM{Artifacts: sbom.Artifacts{Packages: pkg.NewCollection(existing)}}
+	applyAttributions(&coreSBOM, attributions)
+
+	if n := coreSBOM.Artifacts.Packages.PackageCount(); n != 2 {
+		t.Fatalf("expected 2 packages, got %d", n)
+	}
+	wantAnnotations := map[string]string{
+		stageAnnotation:     "build",
+		stagePathAnnotation: "/out/tool",
+	}
+	var added, kept pkg.Package
+	for _, p := range coreSBOM.Artifacts.Packages.Sorted() {
+		locations := p.Locations.ToSlice()
+		if len(locations) != 1 || locations[0].RealPath != "/usr/bin/tool" {
+			t.Fatalf("%s: unexpected locations %+v", p.Name, locations)
+		}
+		for k, v := range wantAnnotations {
+			if got := locations[0].Annotations[k]; got != v {
+				t.Errorf("%s: expected annotation %s=%q, got %q", p.Name, k, v, got)
+			}
+		}
+		switch p.Name {
+		case "tool":
+			added = p
+		case "example.com/dep":
+			kept = p
+		}
+	}
+	if kept.ID() != existing.ID() {
+		t.Errorf("expected the existing package to keep its ID")
+	}
+	if len(existing.Locations.ToSlice()[0].Annotations) != 0 {
+		t.Errorf("expected the original location not to be modified")
+	}
+
+	var got []string
+	for _, r := range coreSBOM.Relationships {
+		got = append(got, string(r.From.ID())+" "+string(r.Type)+" "+string(r.To.ID()))
+	}
+	want := []string{
+		string(added.ID()) + " " + string(artifact.EvidentByRelationship) + " " + string(file.Coordinates{RealPath: "/usr/bin/tool"}.ID()),
+		string(kept.ID()) + " " + string(artifact.DependencyOfRelationship) + " " + string(added.ID()),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected relationships %v, got %v", want, got)
+	}
+	if _, ok := coreSBOM.Artifacts.FileDigests[file.Coordinates{RealPath: "/usr/bin/tool"}]; !ok {
+		t.Errorf("expected the digest of the attributed file to be recorded")
+	}
+
+	props := attributionProperties(attributions)
+	if !reflect.DeepEqual(props, []property{{name: "copied-from", values: []string{"/usr/bin/tool=build:/out/tool"}}}) {
+		t.Errorf("unexpected properties %+v", props)
+	}
+}
+
+func TestAnnotateLocation(t *testing.T) {
+	l := file.NewLocation("/usr/bin/tool").WithAnnotation(pkg.EvidenceAnnotationKey, pkg.PrimaryEvidenceAnnotation)
+	annotated := annotateLocation(l, map[string]string{stageAnnotation: "build"})
+
+	want := map[string]string{
+		pkg.EvidenceAnnotationKey: pkg.PrimaryEvidenceAnnotation,
+		stageAnnotation:           "build",
+	}
+	if !reflect.DeepEqual(annotated.Annotations, want) {
+		t.Errorf("expected annotations %v, got %v", want, annotated.Annotations)
+	}
+	if _, ok := l.Annotations[stageAnnotation]; ok {
+		t.Errorf("expected the annotations of the original location to be left unchanged, got %v", l.Annotations)
+	}
+}
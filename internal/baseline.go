@@ -0,0 +1,334 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/anchore/syft/syft/artifact"
+	"github.com/anchore/syft/syft/file"
+	"github.com/anchore/syft/syft/format"
+	"github.com/anchore/syft/syft/linux"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/sbom"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// baselineAnnotation is set on the locations of the packages of the core
+// target when a base SBOM is given, to "inherited" for packages of the base
+// image and "added" for the others.
+const baselineAnnotation = propertyNamespace + ":baseline"
+
+// baselineRescan are the files that are scanned even when unchanged since
+// the base SBOM, because syft needs them to identify the distribution.
+var baselineRescan = []string{
+	"etc/os-release",
+	"usr/lib/os-release",
+	"etc/*-release",
+	"etc/debian_version",
+}
+
+// baseline is the SBOM of the base image of the core target, verified
+// against the files on disk.
+type baseline struct {
+	// docs are the paths of the base SBOMs in the core target.
+	docs []string
+	// verified are the files whose sha256 digest is the one recorded in
+	// the base SBOMs, by path.
+	verified map[string]string
+	// packages are the packages of the base SBOMs, by packageKey.
+	packages map[string]pkg.Package
+	// unchanged are the keys of the packages all files of which are on
+	// disk and unchanged.
+	unchanged map[string]struct{}
+
+	relationships []artifact.Relationship
+	distro        *linux.Release
+}
+
+// loadBaseline reads the base SBOMs at name in root, either a single
+// document or a directory of them, and verifies them against the files in
+// root. SBOMs may be in any format syft decodes, and may be wrapped in an
+// in-toto statement.
+func loadBaseline(root string, name string) (*baseline, error) {
+	p := filepath.Join(root, filepath.Clean("/"+name))
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read base sbom %q", name)
+	}
+
+	b := &baseline{
+		verified:  map[string]string{},
+		packages:  map[string]pkg.Package{},
+		unchanged: map[string]struct{}{},
+	}
+	var docs []*sbom.SBOM
+	if fi.IsDir() {
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read base sbom %q", name)
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() {
+				continue
+			}
+			doc, err := decodeSBOMFile(filepath.Join(p, entry.Name()))
+			if err != nil {
+				logrus.Debugf("skipping %s: %v", path.Join(name, entry.Name()), err)
+				continue
+			}
+			docs = append(docs, doc)
+			b.docs = append(b.docs, path.Join(filepath.ToSlash(filepath.Clean("/"+name)), entry.Name()))
+		}
+		if len(docs) == 0 {
+			return nil, errors.Errorf("no base sbom found in %q", name)
+		}
+	} else {
+		doc, err := decodeSBOMFile(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid base sbom %q", name)
+		}
+		docs = append(docs, doc)
+		b.docs = append(b.docs, filepath.ToSlash(filepath.Clean("/"+name)))
+	}
+
+	for _, doc := range docs {
+		if err := b.add(root, doc); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// add verifies the files of doc against root, and records its packages.
+func (b *baseline) add(root string, doc *sbom.SBOM) error {
+	digests := map[string]string{}
+	for coords, ds := range doc.Artifacts.FileDigests {
+		for _, d := range ds {
+			if d.Algorithm == "sha256" {
+				digests[coords.RealPath] = d.Value
+			}
+		}
+	}
+	for p, digest := range digests {
+		fi, err := os.Lstat(filepath.Join(root, filepath.FromSlash(p)))
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		actual, err := fileDigest(filepath.Join(root, filepath.FromSlash(p)))
+		if err != nil {
+			return err
+		}
+		if actual == digest {
+			b.verified[p] = digest
+		}
+	}
+
+	for _, p := range doc.Artifacts.Packages.Sorted() {
+		key := packageKey(p)
+		b.packages[key] = p
+
+		var paths []string
+		for _, l := range p.Locations.ToSlice() {
+			paths = append(paths, l.RealPath)
+		}
+		for _, c := range doc.CoordinatesForPackage(p, artifact.ContainsRelationship, artifact.EvidentByRelationship) {
+			paths = append(paths, c.RealPath)
+		}
+		unchanged := len(paths) > 0
+		for _, p := range paths {
+			if _, ok := digests[p]; ok {
+				_, verified := b.verified[p]
+				unchanged = unchanged && verified
+			} else if _, err := os.Lstat(filepath.Join(root, filepath.FromSlash(p))); err != nil {
+				unchanged = false
+			}
+		}
+		if unchanged {
+			b.unchanged[key] = struct{}{}
+		}
+	}
+	b.relationships = append(b.relationships, doc.Relationships...)
+	if b.distro == nil && doc.Artifacts.LinuxDistribution != nil {
+		b.distro = doc.Artifacts.LinuxDistribution
+	}
+	return nil
+}
+
+// skip returns the paths of the files hidden from the scan of the core
+// target: the verified files, which are described by the base SBOMs, and the
+// base SBOMs themselves.
+func (b *baseline) skip() map[string]struct{} {
+	skip := map[string]struct{}{}
+	for p := range b.verified {
+		if !rescanned(p) {
+			skip[p] = struct{}{}
+		}
+	}
+	for _, p := range b.docs {
+		skip[p] = struct{}{}
+	}
+	return skip
+}
+
+// apply completes the SBOM of the core target, scanned without the verified
+// files, with the unchanged packages of the base SBOMs. Packages are
+// annotated as inherited from the base image, or added on top of it.
+func (b *baseline) apply(s *sbom.SBOM) {
+	ids := map[artifact.ID]pkg.Package{}
+	found := map[string]struct{}{}
+	for _, p := range s.Artifacts.Packages.Sorted() {
+		key := packageKey(p)
+		found[key] = struct{}{}
+		state := "added"
+		if base, ok := b.packages[key]; ok {
+			state = "inherited"
+			ids[base.ID()] = p
+		}
+		s.Artifacts.Packages.Delete(p.ID())
+		p.Locations = annotateLocations(p.Locations, baselineAnnotation, state)
+		s.Artifacts.Packages.Add(p)
+	}
+	for key := range b.unchanged {
+		if _, ok := found[key]; ok {
+			continue
+		}
+		p := b.packages[key]
+		p.Locations = annotateLocations(p.Locations, baselineAnnotation, "inherited")
+		s.Artifacts.Packages.Add(p)
+		ids[p.ID()] = p
+	}
+
+	if s.Artifacts.FileDigests == nil {
+		s.Artifacts.FileDigests = map[file.Coordinates][]file.Digest{}
+	}
+	existing := map[[3]string]struct{}{}
+	for _, r := range s.Relationships {
+		existing[[3]string{string(r.From.ID()), string(r.Type), string(r.To.ID())}] = struct{}{}
+	}
+	for _, r := range b.relationships {
+		from, ok := ids[r.From.ID()]
+		if !ok {
+			continue
+		}
+		var to artifact.Identifiable
+		switch v := r.To.(type) {
+		case pkg.Package:
+			p, ok := ids[v.ID()]
+			if !ok {
+				continue
+			}
+			to = p
+		case file.Coordinates:
+			digest, ok := b.verified[v.RealPath]
+			if !ok {
+				continue
+			}
+			coords := file.Coordinates{RealPath: v.RealPath}
+			if _, ok := s.Artifacts.FileDigests[coords]; !ok {
+				s.Artifacts.FileDigests[coords] = []file.Digest{{Algorithm: "sha256", Value: digest}}
+			}
+			to = coords
+		default:
+			continue
+		}
+		key := [3]string{string(from.ID()), string(r.Type), string(to.ID())}
+		if _, ok := existing[key]; ok {
+			continue
+		}
+		existing[key] = struct{}{}
+		s.Relationships = append(s.Relationships, artifact.Relationship{From: from, To: to, Type: r.Type, Data: r.Data})
+	}
+
+	if s.Artifacts.LinuxDistribution == nil && b.distro != nil {
+		s.Artifacts.LinuxDistribution = b.distro
+	}
+}
+
+// properties returns the base SBOMs and the packages inherited from them,
+// recorded in the SBOMs of the core target.
+func (b *baseline) properties(s sbom.SBOM) []property {
+	var inherited []string
+	for _, p := range s.Artifacts.Packages.Sorted() {
+		for _, l := range p.Locations.ToSlice() {
+			if l.Annotations[baselineAnnotation] == "inherited" {
+				inherited = append(inherited, packageKey(p))
+				break
+			}
+		}
+	}
+	sort.Strings(inherited)
+	props := []property{{name: "base-sbom", values: b.docs}}
+	if len(inherited) > 0 {
+		props = append(props, property{name: "inherited", values: inherited})
+	}
+	return props
+}
+
+// packageKey identifies a package across SBOMs, by purl if it has one.
+func packageKey(p pkg.Package) string {
+	if p.PURL != "" {
+		return p.PURL
+	}
+	return string(p.Type) + "/" + p.Name + "@" + p.Version
+}
+
+// annotateLocations returns a copy of locations with key set to value.
+func annotateLocations(locations file.LocationSet, key, value string) file.LocationSet {
+	var annotated file.LocationSet
+	for _, l := range locations.ToSlice() {
+		annotated.Add(annotateLocation(l, map[string]string{key: value}))
+	}
+	return annotated
+}
+
+func rescanned(p string) bool {
+	for _, pattern := range baselineRescan {
+		if ok, _ := path.Match(pattern, strings.TrimPrefix(p, "/")); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeSBOMFile decodes the SBOM at p.
+func decodeSBOMFile(p string) (*sbom.SBOM, error) {
+	dt, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	var stmt struct {
+		PredicateType string          `json:"predicateType"`
+		Predicate     json.RawMessage `json:"predicate"`
+	}
+	if err := json.Unmarshal(dt, &stmt); err == nil && stmt.PredicateType != "" && len(stmt.Predicate) > 0 {
+		dt = stmt.Predicate
+	}
+	s, _, _, err := format.Decode(bytes.NewReader(dt))
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, errors.New("unknown sbom format")
+	}
+	return s, nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/anchore/syft/syft/file"
+	"github.com/anchore/syft/syft/format"
+	"github.com/anchore/syft/syft/format/syftjson"
+)
+
+// baseSBOMFixture scans the root filesystem built from fixtures, digesting
+// all files, and writes the resulting syft JSON SBOM to name.
+func baseSBOMFixture(name string, fixtures ...fixture) fixture {
+	return func(t *testing.T, root string) {
+		t.Helper()
+		cfg := DefaultConfig()
+		cfg.File.Selection = file.AllFilesSelection
+		base := Target{Path: buildFixture(t, filepath.Join(t.TempDir(), "base"), fixtures...), Config: &cfg}
+		s, err := base.Scan(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		enc, err := syftjson.NewFormatEncoderWithConfig(syftjson.DefaultEncoderConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		dt, err := format.Encode(s, enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFixtureFile(t, root, name, dt)
+	}
+}
+
+func TestScanBaseline(t *testing.T) {
+	tests := map[string]struct {
+		fixtures  []fixture
+		inherited []string
+		added     []string
+		skipped   []string
+		scanned   []string
+	}{
+		"unchanged": {
+			fixtures: []fixture{alpineFixture, npmLockFixture},
+			inherited: []string{
+				"pkg:apk/alpine/busybox@1.36.1-r15?arch=x86_64&distro=alpine-3.19.1",
+				"pkg:apk/alpine/musl@1.2.4_git20230717-r4?arch=x86_64&distro=alpine-3.19.1",
+			},
+			added:   []string{"pkg:npm/app@1.0.0", "pkg:npm/ms@2.1.3"},
+			skipped: []string{"/lib/apk/db/installed", "/var/share/base/base.syft.json"},
+			scanned: []string{"/etc/os-release"},
+		},
+		"changed": {
+			// a package was installed on top of the base image, so the
+			// database is scanned again
+			fixtures: []fixture{alpineFixture, files(map[string]string{
+				"lib/apk/db/installed": "P:musl\nV:1.2.4_git20230717-r4\nA:x86_64\nL:MIT\no:musl\n\nP:busybox\nV:1.36.1-r15\nA:x86_64\nL:GPL-2.0-only\no:busybox\n\nP:zlib\nV:1.3.1-r0\nA:x86_64\nL:Zlib\no:zlib\n\n",
+			})},
+			inherited: []string{
+				"pkg:apk/alpine/busybox@1.36.1-r15?arch=x86_64&distro=alpine-3.19.1",
+				"pkg:apk/alpine/musl@1.2.4_git20230717-r4?arch=x86_64&distro=alpine-3.19.1",
+			},
+			added:   []string{"pkg:apk/alpine/zlib@1.3.1-r0?arch=x86_64&distro=alpine-3.19.1"},
+			scanned: []string{"/lib/apk/db/installed"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.SelectCatalogers = []string{"+javascript-lock-cataloger"}
+			fixtures := append([]fixture{baseSBOMFixture("var/share/base/base.syft.json", alpineFixture)}, tc.fixtures...)
+			core := buildFixture(t, filepath.Join(t.TempDir(), "sbom"), fixtures...)
+
+			b, err := loadBaseline(core, "/var/share/base")
+			if err != nil {
+				t.Fatal(err)
+			}
+			skip := b.skip()
+			for _, p := range tc.skipped {
+				if _, ok := skip[p]; !ok {
+					t.Errorf("expected %s to be skipped, got %v", p, skip)
+				}
+			}
+			for _, p := range tc.scanned {
+				if _, ok := skip[p]; ok {
+					t.Errorf("expected %s to be scanned", p)
+				}
+			}
+
+			formats, err := ParseFormats("syft-json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			scanner := Scanner{
+				Core:        Target{Path: core, Config: &cfg},
+				Destination: t.TempDir(),
+				Formats:     formats,
+				BaseSBOM:    "/var/share/base",
+			}
+			if err := scanner.Scan(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			var stmt struct {
+				Predicate struct {
+					Artifacts []struct {
+						PURL      string `json:"purl"`
+						Locations []struct {
+							Annotations map[string]string `json:"annotations"`
+						} `json:"locations"`
+					} `json:"artifacts"`
+					Descriptor struct {
+						Configuration struct {
+							Scanner map[string][]string `json:"buildkit-syft-scanner"`
+						} `json:"configuration"`
+					} `json:"descriptor"`
+				} `json:"predicate"`
+			}
+			dt, err := os.ReadFile(filepath.Join(scanner.Destination, "sbom.syft.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(dt, &stmt); err != nil {
+				t.Fatal(err)
+			}
+
+			var inherited, added []string
+			for _, a := range stmt.Predicate.Artifacts {
+				for _, l := range a.Locations {
+					switch l.Annotations[baselineAnnotation] {
+					case "inherited":
+						inherited = append(inherited, a.PURL)
+					case "added":
+						added = append(added, a.PURL)
+					default:
+						t.Errorf("%s: location not annotated: %v", a.PURL, l.Annotations)
+					}
+				}
+			}
+			slices.Sort(inherited)
+			slices.Sort(added)
+			if !slices.Equal(inherited, tc.inherited) {
+				t.Errorf("expected inherited packages %v, got %v", tc.inherited, inherited)
+			}
+			if !slices.Equal(added, tc.added) {
+				t.Errorf("expected added packages %v, got %v", tc.added, added)
+			}
+
+			props := stmt.Predicate.Descriptor.Configuration.Scanner
+			if got := props["base-sbom"]; !slices.Equal(got, []string{"/var/share/base/base.syft.json"}) {
+				t.Errorf("unexpected base-sbom property %v", got)
+			}
+			if got := props["inherited"]; !slices.Equal(got, tc.inherited) {
+				t.Errorf("unexpected inherited property %v", got)
+			}
+		})
+	}
+}
+
+func TestLoadBaselineErrors(t *testing.T) {
+	root := buildFixture(t, t.TempDir(), files(map[string]string{
+		"var/share/sbom/notes.txt": "not an sbom",
+	}))
+	for _, name := range []string{"/missing.syft.json", "/var/share/sbom", "/var/share/sbom/notes.txt"} {
+		if _, err := loadBaseline(root, name); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
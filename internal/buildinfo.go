@@ -0,0 +1,333 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/anchore/syft/syft/format/cyclonedxjson"
+	"github.com/anchore/syft/syft/format/spdxjson"
+	"github.com/pkg/errors"
+)
+
+// DefaultLabelsFile is the file, inside the image, of OCI labels read for
+// build information, if it exists.
+const DefaultLabelsFile = "/etc/oci-labels.json"
+
+// spdxBuildSourceID is the SPDX identifier of the package describing the
+// source the image was built from.
+const spdxBuildSourceID = "SPDXRef-BuildSource"
+
+// BuildInfo describes where and how an image was built.
+type BuildInfo struct {
+	// VCSURL is the URL of the source repository.
+	VCSURL string
+	// VCSRevision is the commit the image was built from.
+	VCSRevision string
+	// Created is the time the image was built.
+	Created time.Time
+	// Dockerfile is the path of the Dockerfile in the source repository.
+	Dockerfile string
+	// ImageName is the name the image was built as.
+	ImageName string
+}
+
+// IsZero reports whether no build information is known.
+func (b BuildInfo) IsZero() bool {
+	return b == BuildInfo{}
+}
+
+// merge fills the fields of b that are not set from other.
+func (b *BuildInfo) merge(other BuildInfo) {
+	if b.VCSURL == "" {
+		b.VCSURL = other.VCSURL
+	}
+	if b.VCSRevision == "" {
+		b.VCSRevision = other.VCSRevision
+	}
+	if b.Created.IsZero() {
+		b.Created = other.Created
+	}
+	if b.Dockerfile == "" {
+		b.Dockerfile = other.Dockerfile
+	}
+	if b.ImageName == "" {
+		b.ImageName = other.ImageName
+	}
+}
+
+// DetectBuildInfo reads the build information available inside the
+// filesystem rooted at root: OCI labels in labelsFile, a JSON object of
+// labels, and the /.git directory of the source repository, if it was
+// copied into the image. Labels take precedence over the repository.
+func DetectBuildInfo(root string, labelsFile string) (BuildInfo, error) {
+	var info BuildInfo
+	if labelsFile != "" {
+		dt, err := os.ReadFile(filepath.Join(root, filepath.Clean("/"+labelsFile)))
+		if err == nil {
+			info, err = buildInfoFromLabels(dt)
+			if err != nil {
+				return BuildInfo{}, errors.Wrapf(err, "invalid labels file %q", labelsFile)
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return BuildInfo{}, err
+		}
+	}
+	git, err := buildInfoFromGit(filepath.Join(root, ".git"))
+	if err != nil {
+		return BuildInfo{}, errors.Wrap(err, "failed to read /.git")
+	}
+	info.merge(git)
+	return info, nil
+}
+
+func buildInfoFromLabels(dt []byte) (BuildInfo, error) {
+	var labels map[string]string
+	if err := json.Unmarshal(dt, &labels); err != nil {
+		return BuildInfo{}, err
+	}
+	info := BuildInfo{
+		VCSURL:      sanitizeURL(labels["org.opencontainers.image.source"]),
+		VCSRevision: labels["org.opencontainers.image.revision"],
+		Dockerfile:  labels["com.docker.image.source.entrypoint"],
+		ImageName:   labels["org.opencontainers.image.ref.name"],
+	}
+	if v := labels["org.opencontainers.image.created"]; v != "" {
+		created, err := ParseBuildTimestamp(v)
+		if err != nil {
+			return BuildInfo{}, errors.Wrap(err, "org.opencontainers.image.created")
+		}
+		info.Created = created
+	}
+	return info, nil
+}
+
+var gitHash = regexp.MustCompile(`^[0-9a-f]{40}([0-9a-f]{24})?$`)
+
+// buildInfoFromGit reads the remote URL and the checked out commit of the
+// git directory dir, if it exists.
+func buildInfoFromGit(dir string) (BuildInfo, error) {
+	var info BuildInfo
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return info, nil
+	}
+
+	head, err := os.ReadFile(filepath.Join(dir, "HEAD"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return info, err
+	}
+	rev := strings.TrimSpace(string(head))
+	if ref, ok := strings.CutPrefix(rev, "ref: "); ok {
+		rev, err = resolveGitRef(dir, ref)
+		if err != nil {
+			return info, err
+		}
+	}
+	if gitHash.MatchString(rev) {
+		info.VCSRevision = rev
+	}
+
+	config, err := os.ReadFile(filepath.Join(dir, "config"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return info, err
+	}
+	info.VCSURL = sanitizeURL(gitRemoteURL(config))
+	return info, nil
+}
+
+// resolveGitRef returns the commit ref points to, from its loose file or
+// from packed-refs.
+func resolveGitRef(dir string, ref string) (string, error) {
+	dt, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(path.Clean("/"+ref))))
+	if err == nil {
+		return strings.TrimSpace(string(dt)), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	dt, err = os.ReadFile(filepath.Join(dir, "packed-refs"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(dt))
+	for scanner.Scan() {
+		if hash, name, ok := strings.Cut(scanner.Text(), " "); ok && name == ref {
+			return hash, nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// gitRemoteURL returns the URL of the origin remote in a git config file, or
+// of the first remote if there is no origin.
+func gitRemoteURL(config []byte) string {
+	var remote, first, origin string
+	scanner := bufio.NewScanner(bytes.NewReader(config))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			remote = ""
+			if name, ok := strings.CutPrefix(line, "[remote \""); ok {
+				remote = strings.TrimSuffix(name, "\"]")
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if remote == "" || !ok || strings.TrimSpace(key) != "url" {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if first == "" {
+			first = value
+		}
+		if remote == "origin" {
+			origin = value
+		}
+	}
+	if origin != "" {
+		return origin
+	}
+	return first
+}
+
+// sanitizeURL removes credentials from u.
+func sanitizeURL(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Scheme == "" || parsed.User == nil {
+		return u
+	}
+	if _, hasPassword := parsed.User.Password(); hasPassword || parsed.Scheme == "http" || parsed.Scheme == "https" {
+		parsed.User = nil
+	}
+	return parsed.String()
+}
+
+// ParseBuildTimestamp parses a RFC 3339 timestamp, or seconds since the unix
+// epoch.
+func ParseBuildTimestamp(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := ParseSourceDateEpoch(v)
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid timestamp %q, must be RFC 3339 or seconds since the unix epoch", v)
+	}
+	return t, nil
+}
+
+// properties returns the build information recorded in the creation info
+// of the SBOMs.
+func (b BuildInfo) properties() []property {
+	var props []property
+	add := func(name, value string) {
+		if value != "" {
+			props = append(props, property{name: name, values: []string{value}})
+		}
+	}
+	add("vcs-url", b.VCSURL)
+	add("vcs-revision", b.VCSRevision)
+	if !b.Created.IsZero() {
+		add("build-timestamp", b.Created.UTC().Format(time.RFC3339))
+	}
+	add("dockerfile", b.Dockerfile)
+	add("image-name", b.ImageName)
+	return props
+}
+
+// recordBuildInfo describes the build in doc: as a package describing the
+// source of the image for SPDX, and as a reference to the source repository
+// of the root component for CycloneDX. The purpose and build date of the
+// package are only recorded from SPDX 2.3, which introduced them.
+func recordBuildInfo(f Format, doc map[string]interface{}, b BuildInfo) error {
+	switch f.Encoder.ID() {
+	case spdxjson.ID:
+		root, err := spdxRoot(doc)
+		if err != nil {
+			return err
+		}
+		name := b.ImageName
+		if name == "" && b.VCSURL != "" {
+			name = strings.TrimSuffix(path.Base(b.VCSURL), ".git")
+		}
+		if name == "" {
+			name, _ = doc["name"].(string)
+		}
+		p := map[string]interface{}{
+			"SPDXID":           spdxBuildSourceID,
+			"name":             name,
+			"downloadLocation": "NOASSERTION",
+			"filesAnalyzed":    false,
+			"licenseConcluded": "NOASSERTION",
+			"licenseDeclared":  "NOASSERTION",
+			"copyrightText":    "NOASSERTION",
+			"supplier":         "NOASSERTION",
+		}
+		spdx23 := compareVersions(f.Encoder.Version(), "2.3") >= 0
+		if spdx23 {
+			p["primaryPackagePurpose"] = "SOURCE"
+		}
+		if b.VCSRevision != "" {
+			p["versionInfo"] = b.VCSRevision
+		}
+		if strings.Contains(b.VCSURL, "://") {
+			location := "git+" + b.VCSURL
+			if b.VCSRevision != "" {
+				location += "@" + b.VCSRevision
+			}
+			p["downloadLocation"] = location
+		}
+		var info []string
+		if b.VCSURL != "" {
+			info = append(info, "repository "+b.VCSURL)
+		}
+		if b.Dockerfile != "" {
+			info = append(info, "Dockerfile "+b.Dockerfile)
+		}
+		if len(info) > 0 {
+			p["sourceInfo"] = "built from " + strings.Join(info, ", ")
+		}
+		if spdx23 && !b.Created.IsZero() {
+			p["builtDate"] = b.Created.UTC().Format(time.RFC3339)
+		}
+		doc["packages"] = append(asList(doc["packages"]), p)
+		doc["relationships"] = append(asList(doc["relationships"]),
+			map[string]interface{}{"spdxElementId": spdxDocumentID, "relationshipType": "DESCRIBES", "relatedSpdxElement": spdxBuildSourceID},
+			map[string]interface{}{"spdxElementId": root, "relationshipType": "GENERATED_FROM", "relatedSpdxElement": spdxBuildSourceID},
+		)
+	case cyclonedxjson.ID:
+		if b.VCSURL == "" {
+			return nil
+		}
+		component := objectField(objectField(doc, "metadata"), "component")
+		ref := map[string]interface{}{"type": "vcs", "url": b.VCSURL}
+		if b.VCSRevision != "" {
+			ref["comment"] = "revision " + b.VCSRevision
+		}
+		component["externalReferences"] = append(asList(component["externalReferences"]), ref)
+	}
+	return nil
+}
@@ -0,0 +1,573 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anchore/syft/syft"
+	"github.com/anchore/syft/syft/artifact"
+	"github.com/anchore/syft/syft/cataloging/pkgcataloging"
+	"github.com/anchore/syft/syft/file"
+	"github.com/anchore/syft/syft/format"
+	"github.com/anchore/syft/syft/format/syftjson"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/pkg/cataloger/golang"
+	"github.com/anchore/syft/syft/pkg/cataloger/java"
+	"github.com/anchore/syft/syft/sbom"
+	"github.com/docker/buildkit-syft-scanner/version"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// cacheSchema is bumped whenever the layout of cache entries changes.
+const cacheSchema = "2"
+
+// DefaultCacheSize is the size the cache is trimmed to when no limit is
+// given.
+const DefaultCacheSize = 1 << 30
+
+// cachedCataloger is a cataloger whose results only depend on the content of
+// a single file.
+type cachedCataloger struct {
+	new func(cfg pkgcataloging.Config) pkg.Cataloger
+	// match reports whether the cataloger may parse the file.
+	match func(name string, mode fs.FileMode) bool
+}
+
+// cachedCatalogers are the catalogers whose results are cached, by name.
+var cachedCatalogers = map[string]cachedCataloger{
+	"java-archive-cataloger": {
+		new: func(cfg pkgcataloging.Config) pkg.Cataloger {
+			return java.NewArchiveCataloger(cfg.JavaArchive)
+		},
+		match: func(name string, _ fs.FileMode) bool {
+			switch strings.ToLower(path.Ext(name)) {
+			case ".jar", ".war", ".ear", ".par", ".sar", ".nar", ".jpi", ".hpi", ".kar", ".far", ".lpkg", ".rar":
+				return true
+			}
+			return false
+		},
+	},
+	"go-module-binary-cataloger": {
+		new: func(cfg pkgcataloging.Config) pkg.Cataloger {
+			return golang.NewGoModuleBinaryCataloger(cfg.Golang)
+		},
+		match: func(_ string, mode fs.FileMode) bool {
+			return mode&0o111 != 0
+		},
+	},
+}
+
+// Cache stores the packages cataloged from files across scans, keyed by the
+// digest of the file, the cataloger, the version of syft and the scanner
+// configuration, so that unchanged artifacts are not parsed again. A Cache
+// may be shared by targets scanned concurrently.
+type Cache struct {
+	// Dir is the directory holding the cache, typically a BuildKit cache
+	// mount.
+	Dir string
+	// MaxSize is the size in bytes the cache is trimmed to after each scan,
+	// evicting the least recently used entries first. If zero,
+	// DefaultCacheSize is used.
+	MaxSize int64
+
+	mu         sync.Mutex
+	selections map[string]cacheSelection
+}
+
+// cacheEntry is the result of cataloging a file, as returned by the
+// cataloger before syft adds CPEs, ownership relationships and such.
+type cacheEntry struct {
+	// Path is the path of the file the result was cataloged from, which
+	// the locations of the packages refer to.
+	Path string `json:"path"`
+	// SBOM holds the packages of the file in the syft JSON format, and is
+	// empty if the file has none.
+	SBOM json.RawMessage `json:"sbom"`
+}
+
+// cacheHit is a file of a target found in the cache.
+type cacheHit struct {
+	entry *sbom.SBOM
+	from  string
+}
+
+// cacheSelection is what a configuration selects, found once by cataloging
+// an empty directory, as syft does not expose its cataloger selection.
+type cacheSelection struct {
+	// catalogers are the names of the cached catalogers selected.
+	catalogers []string
+	// configuration is the descriptor configuration of the SBOMs created
+	// without the cache.
+	configuration any
+}
+
+// fingerprint identifies the results a scan with cfg produces for a given
+// file.
+func (c *Cache) fingerprint(cfg Config) (string, error) {
+	// exclusions do not change the results for a file
+	cfg.Exclude = nil
+	dt, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(cacheSchema + "\x00" + version.SyftVersion + "\x00"))
+	h.Write(dt)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *Cache) entryPath(fingerprint, cataloger, digest string) string {
+	sum := sha256.Sum256([]byte(fingerprint + "\x00" + cataloger + "\x00" + digest))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(c.Dir, key[:2], key)
+}
+
+// selection returns what a scan with cfg, identified by fingerprint,
+// selects.
+func (c *Cache) selection(ctx context.Context, fingerprint string, cfg Config) (cacheSelection, error) {
+	c.mu.Lock()
+	sel, ok := c.selections[fingerprint]
+	c.mu.Unlock()
+	if ok {
+		return sel, nil
+	}
+
+	dir, err := os.MkdirTemp("", "syft-scanner-selection-")
+	if err != nil {
+		return cacheSelection{}, err
+	}
+	defer os.RemoveAll(dir)
+	src, err := syft.GetSource(ctx, dir, syft.DefaultGetSourceConfig().WithBasePath(dir))
+	if err != nil {
+		return cacheSelection{}, err
+	}
+	defer src.Close()
+	s, err := syft.CreateSBOM(ctx, src, cfg.CreateSBOMConfig())
+	if err != nil {
+		return cacheSelection{}, err
+	}
+	dt, err := json.Marshal(s.Descriptor.Configuration)
+	if err != nil {
+		return cacheSelection{}, err
+	}
+	var trail struct {
+		Catalogers struct {
+			Used []string `json:"used"`
+		} `json:"catalogers"`
+	}
+	if err := json.Unmarshal(dt, &trail); err != nil {
+		return cacheSelection{}, err
+	}
+	sel = cacheSelection{configuration: s.Descriptor.Configuration}
+	for _, name := range trail.Catalogers.Used {
+		if _, ok := cachedCatalogers[name]; ok {
+			sel.catalogers = append(sel.catalogers, name)
+		}
+	}
+	sort.Strings(sel.catalogers)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.selections == nil {
+		c.selections = map[string]cacheSelection{}
+	}
+	c.selections[fingerprint] = sel
+	return sel, nil
+}
+
+// catalogers returns the cached catalogers of sel, configured by cfg, in
+// place of the ones syft selects, which are deselected from cfg.
+func (c *Cache) catalogers(cfg *syft.CreateSBOMConfig, sel cacheSelection) []*cachingCataloger {
+	var catalogers []*cachingCataloger
+	for _, name := range sel.catalogers {
+		cc := &cachingCataloger{
+			Cataloger: cachedCatalogers[name].new(cfg.Packages),
+			match:     cachedCatalogers[name].match,
+			hits:      map[string]cacheHit{},
+			misses:    map[string]string{},
+		}
+		cfg.CatalogerSelection.AddNames = slices.DeleteFunc(slices.Clone(cfg.CatalogerSelection.AddNames), func(n string) bool {
+			return n == name
+		})
+		cfg.CatalogerSelection.RemoveNamesOrTags = append(slices.Clone(cfg.CatalogerSelection.RemoveNamesOrTags), name)
+		cfg.WithCatalogers(pkgcataloging.NewAlwaysEnabledCatalogerReference(cc))
+		catalogers = append(catalogers, cc)
+	}
+	return catalogers
+}
+
+// lookup finds the files of the target at root, outside of exclude and
+// skip, that the catalogers may parse, recording for each cataloger the
+// ones in the cache as hits and the others as misses. It returns the number
+// of hits.
+func (c *Cache) lookup(ctx context.Context, root string, fingerprint string, catalogers []*cachingCataloger, exclude []string, skip map[string]struct{}) (int, error) {
+	var hits int
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel != "." && isExcluded(exclude, rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if _, ok := skip["/"+rel]; ok {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		// the file is hashed once for all the catalogers
+		var digest string
+		for _, cc := range catalogers {
+			if !cc.match(rel, fi.Mode()) {
+				continue
+			}
+			if digest == "" {
+				if digest, err = fileDigest(p); err != nil {
+					return err
+				}
+			}
+			entry, err := c.read(c.entryPath(fingerprint, cc.Name(), digest))
+			if err != nil {
+				return err
+			}
+			if entry == nil {
+				cc.misses["/"+rel] = digest
+				continue
+			}
+			cc.hits["/"+rel] = *entry
+			hits++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return hits, nil
+}
+
+// read returns the entry at p, or nil if there is none.
+func (c *Cache) read(p string) (*cacheHit, error) {
+	dt, err := os.ReadFile(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(dt, &entry); err != nil {
+		logrus.Debugf("removing invalid cache entry %s: %v", p, err)
+		return nil, os.Remove(p)
+	}
+	s, _, _, err := syftjson.NewFormatDecoder().Decode(bytes.NewReader(entry.SBOM))
+	if err != nil {
+		logrus.Debugf("removing invalid cache entry %s: %v", p, err)
+		return nil, os.Remove(p)
+	}
+	now := time.Now()
+	if err := os.Chtimes(p, now, now); err != nil {
+		return nil, err
+	}
+	return &cacheHit{entry: s, from: entry.Path}, nil
+}
+
+// relocate returns the packages and relationships of the hit, located at
+// the path of the file in the target.
+func (h cacheHit) relocate(path string) ([]pkg.Package, []artifact.Relationship) {
+	relocate := func(coords file.Coordinates) file.Coordinates {
+		if coords.RealPath == h.from {
+			return file.Coordinates{RealPath: path}
+		}
+		return coords
+	}
+
+	var pkgs []pkg.Package
+	ids := map[artifact.ID]pkg.Package{}
+	for _, p := range h.entry.Artifacts.Packages.Sorted() {
+		id := p.ID()
+		var locations file.LocationSet
+		for _, l := range p.Locations.ToSlice() {
+			if l.RealPath == h.from {
+				l.Coordinates = relocate(l.Coordinates)
+				l.AccessPath = path + strings.TrimPrefix(l.AccessPath, h.from)
+			}
+			locations.Add(l)
+		}
+		p.Locations = locations
+		p.SetID()
+		pkgs = append(pkgs, p)
+		ids[id] = p
+	}
+	var relationships []artifact.Relationship
+	for _, r := range h.entry.Relationships {
+		from, ok := ids[r.From.ID()]
+		if !ok {
+			continue
+		}
+		var to artifact.Identifiable
+		switch v := r.To.(type) {
+		case pkg.Package:
+			if to, ok = ids[v.ID()]; !ok {
+				continue
+			}
+		case file.Coordinates:
+			to = relocate(v)
+		default:
+			continue
+		}
+		relationships = append(relationships, artifact.Relationship{From: from, To: to, Type: r.Type, Data: r.Data})
+	}
+	return pkgs, relationships
+}
+
+// cachingCataloger wraps a cached cataloger, so that it does not parse the
+// files found in the cache, whose results are returned instead. As syft
+// processes the results the same way whether they come from the cache or
+// not, the SBOM does not depend on the state of the cache.
+type cachingCataloger struct {
+	pkg.Cataloger
+	match func(name string, mode fs.FileMode) bool
+
+	// hits are the files found in the cache, and misses the digests of the
+	// others, by path in the target.
+	hits   map[string]cacheHit
+	misses map[string]string
+
+	// the results of the wrapped cataloger, stored for the misses
+	ran           bool
+	pkgs          []pkg.Package
+	relationships []artifact.Relationship
+	err           error
+}
+
+func (c *cachingCataloger) Catalog(ctx context.Context, resolver file.Resolver) ([]pkg.Package, []artifact.Relationship, error) {
+	skip := make(map[string]struct{}, len(c.hits))
+	for p := range c.hits {
+		skip[p] = struct{}{}
+	}
+	pkgs, relationships, err := c.Cataloger.Catalog(ctx, skipResolver{Resolver: resolver, skip: skip})
+	// syft updates the returned packages in place
+	c.ran, c.pkgs, c.relationships, c.err = true, slices.Clone(pkgs), slices.Clone(relationships), err
+
+	paths := make([]string, 0, len(c.hits))
+	for p := range c.hits {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		hitPkgs, hitRelationships := c.hits[p].relocate(p)
+		pkgs = append(pkgs, hitPkgs...)
+		relationships = append(relationships, hitRelationships...)
+	}
+	return pkgs, relationships, err
+}
+
+// entry returns the results of the wrapped cataloger for the file at p, or
+// false if they do not only depend on the file, or if it could not be
+// parsed.
+func (c *cachingCataloger) entry(p string) (sbom.SBOM, bool) {
+	if c.err != nil && strings.Contains(c.err.Error(), p) {
+		return sbom.SBOM{}, false
+	}
+	var pkgs []pkg.Package
+	ids := map[artifact.ID]struct{}{}
+	for _, q := range c.pkgs {
+		var at, elsewhere bool
+		for _, l := range q.Locations.ToSlice() {
+			if l.RealPath == p {
+				at = true
+			} else {
+				elsewhere = true
+			}
+		}
+		if !at {
+			continue
+		}
+		if elsewhere {
+			return sbom.SBOM{}, false
+		}
+		pkgs = append(pkgs, q)
+		ids[q.ID()] = struct{}{}
+	}
+
+	var relationships []artifact.Relationship
+	for _, r := range c.relationships {
+		_, from := ids[r.From.ID()]
+		var to bool
+		switch v := r.To.(type) {
+		case pkg.Package:
+			_, to = ids[v.ID()]
+		case file.Coordinates:
+			to = v.RealPath == p
+		}
+		if from != to {
+			return sbom.SBOM{}, false
+		}
+		if from {
+			relationships = append(relationships, r)
+		}
+	}
+	return sbom.SBOM{
+		Artifacts:     sbom.Artifacts{Packages: pkg.NewCollection(pkgs...)},
+		Relationships: relationships,
+	}, true
+}
+
+// store adds to the cache the results of the catalogers for the files that
+// were not found in it, including the files without packages. It returns
+// the number of entries stored.
+func (c *Cache) store(fingerprint string, catalogers []*cachingCataloger) (int, error) {
+	enc, err := syftjson.NewFormatEncoderWithConfig(syftjson.DefaultEncoderConfig())
+	if err != nil {
+		return 0, err
+	}
+	var stored int
+	for _, cc := range catalogers {
+		if !cc.ran {
+			continue
+		}
+		paths := make([]string, 0, len(cc.misses))
+		for p := range cc.misses {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		for _, p := range paths {
+			entry, ok := cc.entry(p)
+			if !ok {
+				continue
+			}
+			dt, err := format.Encode(entry, enc)
+			if err != nil {
+				return stored, err
+			}
+			dt, err = json.Marshal(cacheEntry{Path: p, SBOM: dt})
+			if err != nil {
+				return stored, err
+			}
+			if err := writeFileAtomic(c.entryPath(fingerprint, cc.Name(), cc.misses[p]), dt); err != nil {
+				return stored, err
+			}
+			stored++
+		}
+	}
+	return stored, nil
+}
+
+// evict removes the least recently used entries until the cache fits in
+// MaxSize, returning the number of entries removed.
+func (c *Cache) evict() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	maxSize := c.MaxSize
+	if maxSize == 0 {
+		maxSize = DefaultCacheSize
+	}
+
+	type entry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var entries []entry
+	var total int64
+	err := filepath.WalkDir(c.Dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// entries being written are skipped
+		if !d.Type().IsRegular() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{path: p, size: fi.Size(), modTime: fi.ModTime()})
+		total += fi.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+	var evicted int
+	for _, e := range entries {
+		if total <= maxSize {
+			break
+		}
+		if err := os.Remove(e.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return evicted, err
+		}
+		total -= e.size
+		evicted++
+	}
+	return evicted, nil
+}
+
+// writeFileAtomic writes dt to p, so that concurrent readers never see a
+// partial file.
+func writeFileAtomic(p string, dt []byte) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), "."+filepath.Base(p)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(dt); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
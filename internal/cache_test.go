@@ -0,0 +1,225 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anchore/syft/syft/file"
+	"github.com/anchore/syft/syft/format"
+	"github.com/anchore/syft/syft/format/syftjson"
+	"github.com/anchore/syft/syft/sbom"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	cache := &Cache{Dir: filepath.Join(dir, "cache")}
+	fixtures := []fixture{
+		goBinaryFixture("usr/local/bin/app"),
+		elfFixture("usr/local/bin/tool"),
+		jarFixture("opt/app/app-1.2.3.jar"),
+		alpineFixture,
+	}
+	cfg := DefaultConfig()
+	cfg.File.Selection = file.AllFilesSelection
+
+	cold := Target{Path: buildFixture(t, filepath.Join(dir, "cold", "rootfs"), fixtures...), Config: &cfg, Cache: cache}
+	coldSBOM, err := cold.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the executable without packages is stored too
+	if n := countCacheEntries(t, cache.Dir); n != 3 {
+		t.Fatalf("expected 3 cache entries, got %d", n)
+	}
+
+	warm := Target{Path: buildFixture(t, filepath.Join(dir, "warm", "rootfs"), fixtures...), Config: &cfg, Cache: cache}
+	fingerprint, err := cache.fingerprint(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sel, err := cache.selection(context.Background(), fingerprint, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(sel.catalogers, []string{"go-module-binary-cataloger", "java-archive-cataloger"}) {
+		t.Fatalf("unexpected cached catalogers %v", sel.catalogers)
+	}
+	catalogers := cache.catalogers(cfg.CreateSBOMConfig(), sel)
+	if hits, err := cache.lookup(context.Background(), warm.Path, fingerprint, catalogers, nil, nil); err != nil || hits != 3 {
+		t.Fatalf("expected 3 cache hits, got %d: %v", hits, err)
+	}
+	warmSBOM, err := warm.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the SBOM does not depend on the state of the cache, nor on whether
+	// it is used
+	warmSBOM.Source = coldSBOM.Source
+	uncached := Target{Path: warm.Path, Config: &cfg}
+	uncachedSBOM, err := uncached.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	uncachedSBOM.Source = coldSBOM.Source
+	want := encodeSyftJSON(t, coldSBOM)
+	if got := encodeSyftJSON(t, warmSBOM); got != want {
+		t.Errorf("expected the warm scan to match the cold one\ncold:\n%s\nwarm:\n%s", want, got)
+	}
+	if got := encodeSyftJSON(t, uncachedSBOM); got != want {
+		t.Errorf("expected the scan without cache to match the cold one\ncold:\n%s\nuncached:\n%s", want, got)
+	}
+	if len(coldSBOM.Artifacts.Executables) != 2 {
+		t.Errorf("expected the 2 executables to be cataloged, got %d", len(coldSBOM.Artifacts.Executables))
+	}
+	if n := countCacheEntries(t, cache.Dir); n != 3 {
+		t.Errorf("expected hits not to be stored again, got %d entries", n)
+	}
+
+	// the same binary at another path is restored from the cache
+	moved := Target{Path: buildFixture(t, filepath.Join(dir, "moved"), goBinaryFixture("bin/other")), Config: &cfg, Cache: cache}
+	movedSBOM, err := moved.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wantPkgs []string
+	for _, p := range packageSummary(coldSBOM, "/usr/local/bin/app", "/bin/other") {
+		if strings.HasSuffix(p, " /bin/other") {
+			wantPkgs = append(wantPkgs, p)
+		}
+	}
+	gotPkgs := packageSummary(movedSBOM, "", "")
+	if len(wantPkgs) == 0 || !slices.Equal(gotPkgs, wantPkgs) {
+		t.Errorf("expected packages %v restored from the cache, got %v", wantPkgs, gotPkgs)
+	}
+	if n := countCacheEntries(t, cache.Dir); n != 3 {
+		t.Errorf("expected hits not to be stored again, got %d entries", n)
+	}
+
+	// a different configuration does not use the cache
+	other := DefaultConfig()
+	other.DataGeneration.GenerateCPEs = !other.DataGeneration.GenerateCPEs
+	if fp, err := cache.fingerprint(other); err != nil || fp == fingerprint {
+		t.Errorf("expected the fingerprint to depend on the configuration")
+	}
+	other = cfg
+	other.Exclude = []string{"/opt"}
+	if fp, err := cache.fingerprint(other); err != nil || fp != fingerprint {
+		t.Errorf("expected the fingerprint not to depend on exclusions")
+	}
+
+	// catalogers that are not selected are not cached
+	other = cfg
+	other.SelectCatalogers = []string{"-java-archive-cataloger"}
+	fp, err := cache.fingerprint(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sel, err := cache.selection(context.Background(), fp, other); err != nil || !slices.Equal(sel.catalogers, []string{"go-module-binary-cataloger"}) {
+		t.Errorf("unexpected cached catalogers %v: %v", sel.catalogers, err)
+	}
+}
+
+func TestCacheEvict(t *testing.T) {
+	dir := t.TempDir()
+	cache := &Cache{Dir: dir, MaxSize: 10}
+	for name, content := range map[string]string{
+		"aa/old":        "0123456789",
+		"bb/new":        "0123456789",
+		"bb/.new.1.tmp": "0123456789",
+	} {
+		writeFixtureFile(t, dir, name, []byte(content))
+	}
+	old := filepath.Join(dir, "aa", "old")
+	stat, err := os.Stat(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(old, stat.ModTime().Add(-time.Hour), stat.ModTime().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	evicted, err := cache.evict()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evicted != 1 {
+		t.Errorf("expected 1 entry to be evicted, got %d", evicted)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("expected the least recently used entry to be evicted")
+	}
+	for _, name := range []string{"bb/new", "bb/.new.1.tmp"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s to be kept: %v", name, err)
+		}
+	}
+}
+
+// packageSummary lists the packages of s as "<purl> <found by> <path>",
+// with paths equal to from replaced by to.
+func packageSummary(s sbom.SBOM, from, to string) []string {
+	var out []string
+	for _, p := range s.Artifacts.Packages.Sorted() {
+		for _, l := range p.Locations.ToSlice() {
+			path := l.RealPath
+			if path == from {
+				path = to
+			}
+			out = append(out, p.PURL+" "+p.FoundBy+" "+path)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+func countCacheEntries(t *testing.T, dir string) int {
+	t.Helper()
+	var n int
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			n++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func encodeSyftJSON(t *testing.T, s sbom.SBOM) string {
+	t.Helper()
+	enc, err := syftjson.NewFormatEncoderWithConfig(syftjson.EncoderConfig{Pretty: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dt, err := format.Encode(s, enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(dt)
+}
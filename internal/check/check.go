@@ -0,0 +1,265 @@
+// Copyright 2023 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package check ensures a target JSON document matches a specified schema.
+//
+// The schema is another JSON document that should match the desired format
+// structure of the target - check will ensure that the schema is a subset of
+// the target.
+//
+// check also provides simple variables and comparisons to easily evaluate
+// relationships in the target SPDX files. A property set to "=" will assign
+// the variable value to the corresponding property from the target, while a
+// property set to "==" will check the previously assigned variable to the
+// corresponding property from the target.
+//
+// Strings starting with "~" match the target string against the regular
+// expression that follows, and strings starting with "!~" ensure it does not
+// match. Objects whose keys are all operators are matchers, whose operators
+// must all hold:
+//
+//	{"$absent": true}           the key must not be present
+//	{"$optional": schema}       the key may be absent, else must match schema
+//	{"$eq": value}              the target must equal value, literally
+//	{"$regex": "^pkg:"}         the target string must match
+//	{"$not": schema}            the target must not match schema
+//	{"$anyOf": [schema, ...]}   the target must match one of the schemas
+//	{"$type": "string"}         the target must be of the JSON type, or of one
+//	                            of an array of types
+//	{"$gt": 1}                  the target number must be > 1, and likewise
+//	                            for "$gte", "$lt" and "$lte"
+//	{"$items": [schema, ...]}   every schema must match an element, as for an
+//	                            array in the schema
+//	{"$count": 1}               the target array must have exactly 1 element,
+//	                            or at least or at most with "$min" and "$max";
+//	                            with "$where": schema, only the elements
+//	                            matching schema are counted
+//	{"$none": schema}           no element of the target array may match
+//	{"$every": schema}          every element of the target array must match
+//
+// The "$sbom" key of the schema holds assertions on the SBOM itself, decoded
+// with syft whether it is SPDX, CycloneDX or syft JSON, rather than on its
+// JSON structure:
+//
+//	{"$sbom": {
+//	  "packages": [{"name": "git"}, {"purl": "pkg:golang/stdlib"}],
+//	  "absent": [{"paths": ["~^/usr/share/doc/"]}],
+//	  "relationships": [
+//	    {"from": {"name": "app"}, "type": "DEPENDS_ON", "to": {"name": "ms"}},
+//	    {"from": {"file": "/usr/bin/git"}, "type": "CONTAINED_BY", "to": {"name": "git"}}
+//	  ],
+//	  "count": {"go-module": 1, "all": {"$min": 10}}
+//	}}
+//
+// Packages are selected on their name, version, type, purl (which matches any
+// version if it has none), language, paths and licenses, with the same
+// values and matchers as the rest of the schema, and files on their path.
+// Relationships are CONTAINS, CONTAINED_BY, DEPENDS_ON, DEPENDENCY_OF,
+// EVIDENT_BY and OWNERSHIP_BY_FILE_OVERLAP, and count asserts on the number
+// of packages of each type, or of all of them.
+//
+// Every mismatch is reported, with its JSON path in the schema, rather than
+// only the first one.
+package check
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Check returns Mismatches if schema is not a subset of target. Both are
+// expected to be decoded JSON values.
+func Check(schema interface{}, target interface{}) error {
+	return NewScope().Check(schema, target)
+}
+
+// Scope holds the variables assigned by the schemas checked in it, so that
+// a schema can compare values of another target checked before it.
+type Scope struct {
+	c checker
+}
+
+// NewScope returns a scope without variables.
+func NewScope() *Scope {
+	return &Scope{c: checker{vars: make(map[string]interface{})}}
+}
+
+// Check is like the Check function, with the variables of the scope.
+func (s *Scope) Check(schema interface{}, target interface{}) error {
+	var assertions interface{}
+	if m, ok := schema.(map[string]interface{}); ok && m[sbomKey] != nil {
+		assertions = m[sbomKey]
+		literal := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			if k != sbomKey {
+				literal[k] = v
+			}
+		}
+		schema = literal
+	}
+
+	c := &s.c
+	c.assign = true
+	c.check(schema, target, rootPath)
+	c.assign = false
+	mismatches := c.check(schema, target, rootPath)
+	if assertions != nil {
+		mismatches = append(mismatches, c.checkSBOM(assertions, target, keyPath(rootPath, sbomKey))...)
+	}
+	if len(mismatches) > 0 {
+		return mismatches
+	}
+	return nil
+}
+
+// checker walks a schema and its target twice: variables are assigned on the
+// first pass, and compared, along with everything else, on the second.
+type checker struct {
+	vars    map[string]interface{}
+	assign  bool
+	regexps map[string]*regexp.Regexp
+}
+
+func (c *checker) check(schema interface{}, target interface{}, path string) Mismatches {
+	if m, ok := schema.(map[string]interface{}); ok && isMatcher(m) {
+		return c.checkMatcher(m, target, true, path)
+	}
+
+	schemaType := reflect.TypeOf(schema)
+	targetType := reflect.TypeOf(target)
+
+	if schemaType == nil || targetType == nil {
+		if schemaType != targetType {
+			return Mismatches{{Path: path, Kind: KindValue, Expected: schema, Actual: target}}
+		}
+		return nil
+	}
+
+	if s, ok := schema.(string); ok {
+		if key, ok := strings.CutPrefix(s, "=="); ok {
+			if c.assign {
+				return nil
+			}
+			v, ok := c.vars[key]
+			if !ok {
+				return Mismatches{{Path: path, Kind: KindUnknownVariable, Message: fmt.Sprintf("variable %s not found", key)}}
+			}
+			if !reflect.DeepEqual(target, v) {
+				return Mismatches{{Path: path, Kind: KindVariable, Message: "variable " + key, Expected: v, Actual: target}}
+			}
+			return nil
+		}
+		if key, ok := strings.CutPrefix(s, "="); ok {
+			if c.assign {
+				c.vars[key] = target
+			}
+			return nil
+		}
+		if pattern, ok := strings.CutPrefix(s, "!~"); ok {
+			return c.checkRegex(pattern, target, true, path)
+		}
+		if pattern, ok := strings.CutPrefix(s, "~"); ok {
+			return c.checkRegex(pattern, target, false, path)
+		}
+	}
+
+	if schemaType.Kind() != targetType.Kind() {
+		return Mismatches{{Path: path, Kind: KindType, Expected: schema, Actual: target}}
+	}
+	switch schemaType.Kind() {
+	case reflect.Pointer:
+		return c.check(reflect.ValueOf(schema).Elem().Interface(), reflect.ValueOf(target).Elem().Interface(), path)
+	case reflect.Map:
+		return c.checkMap(schema.(map[string]interface{}), target.(map[string]interface{}), path)
+	case reflect.Array, reflect.Slice:
+		return c.checkSlice(schema.([]interface{}), target.([]interface{}), path)
+	default:
+		if !reflect.DeepEqual(schema, target) {
+			return Mismatches{{Path: path, Kind: KindValue, Expected: schema, Actual: target}}
+		}
+		return nil
+	}
+}
+
+func (c *checker) checkMap(schema map[string]interface{}, target map[string]interface{}, path string) Mismatches {
+	var mismatches Mismatches
+	for _, k := range sortedKeys(schema) {
+		v := schema[k]
+		v2, ok := target[k]
+		if !ok {
+			if m, ok := v.(map[string]interface{}); ok && isMatcher(m) {
+				mismatches = append(mismatches, c.checkMatcher(m, nil, false, keyPath(path, k))...)
+			} else {
+				mismatches = append(mismatches, Mismatch{Path: keyPath(path, k), Kind: KindMissing, Expected: v})
+			}
+			continue
+		}
+		mismatches = append(mismatches, c.check(v, v2, keyPath(path, k))...)
+	}
+	return mismatches
+}
+
+// checkSlice checks that every element of schema matches an element of
+// target. An element matching none is reported along with the mismatches of
+// the closest element, the one with the fewest.
+func (c *checker) checkSlice(schema []interface{}, target []interface{}, path string) Mismatches {
+	var mismatches Mismatches
+	if len(schema) > len(target) {
+		mismatches = append(mismatches, Mismatch{
+			Path:    path,
+			Kind:    KindLength,
+			Message: fmt.Sprintf("expected at least %d elements, got %d", len(schema), len(target)),
+		})
+	}
+	for i, v := range schema {
+		elemPath := fmt.Sprintf("%s[%d]", path, i)
+		found := false
+		closest := -1
+		var closestMismatches Mismatches
+		for j, v2 := range target {
+			m := c.check(v, v2, elemPath)
+			if len(m) == 0 {
+				found = true
+				break
+			}
+			if closest < 0 || len(m) < len(closestMismatches) {
+				closest, closestMismatches = j, m
+			}
+		}
+		if found {
+			continue
+		}
+		mismatch := Mismatch{Path: elemPath, Kind: KindElement, Expected: v}
+		if closest >= 0 {
+			mismatch.Message = fmt.Sprintf("closest is element %d", closest)
+			mismatch.Actual = target[closest]
+			mismatch.Closest = closestMismatches
+		}
+		mismatches = append(mismatches, mismatch)
+	}
+	return mismatches
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,372 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	target := `{
+		"name": "sbom",
+		"count": 2,
+		"optional": null,
+		"packages": [
+			{"SPDXID": "SPDXRef-a", "name": "a"},
+			{"SPDXID": "SPDXRef-b", "name": "b"}
+		],
+		"relationships": [
+			{"from": "SPDXRef-b", "to": "SPDXRef-a"}
+		]
+	}`
+	tests := []struct {
+		name   string
+		schema string
+		err    string
+	}{
+		{name: "empty", schema: `{}`},
+		{name: "subset", schema: `{"name": "sbom", "count": 2, "optional": null}`},
+		{name: "slice subset", schema: `{"packages": [{"name": "b"}]}`},
+		{name: "variables", schema: `{
+			"packages": [{"SPDXID": "=b", "name": "b"}, {"SPDXID": "=a", "name": "a"}],
+			"relationships": [{"from": "==b", "to": "==a"}]
+		}`},
+		{name: "value mismatch", schema: `{"name": "other"}`, err: `$.name: expected "other", got "sbom"`},
+		{name: "type mismatch", schema: `{"count": "2"}`, err: `$.count: expected string "2", got number 2`},
+		{name: "missing key", schema: `{"missing": true}`, err: "$.missing: expected true, not present"},
+		{name: "null mismatch", schema: `{"name": null}`, err: `$.name: expected null, got "sbom"`},
+		{name: "slice mismatch", schema: `{"packages": [{"name": "c"}]}`, err: `$.packages[0]: expected {"name":"c"}, closest is element 0`},
+		{name: "length mismatch", schema: `{"relationships": [{}, {}]}`, err: "$.relationships: expected at least 2 elements, got 1"},
+		{name: "variable mismatch", schema: `{
+			"packages": [{"SPDXID": "=a", "name": "a"}],
+			"relationships": [{"from": "==a"}]
+		}`, err: `$.relationships[0].from: expected "SPDXRef-a" (variable a), got "SPDXRef-b"`},
+		{name: "unknown variable", schema: `{"name": "==unset"}`, err: "$.name: variable unset not found"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var schema, tgt interface{}
+			if err := json.Unmarshal([]byte(tc.schema), &schema); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(target), &tgt); err != nil {
+				t.Fatal(err)
+			}
+			err := Check(schema, tgt)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestCheckReport(t *testing.T) {
+	var schema, target interface{}
+	if err := json.Unmarshal([]byte(`{
+		"name": "other",
+		"count": "2",
+		"missing": true,
+		"build-tool": "x",
+		"packages": [{"name": "a"}, {"name": "c", "version": "1.0"}],
+		"long": "`+strings.Repeat("x", 100)+`"
+	}`), &schema); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{
+		"name": "sbom",
+		"count": 2,
+		"packages": [{"name": "a"}, {"name": "b", "version": "1.0"}],
+		"long": "y"
+	}`), &target); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Check(schema, target)
+	mismatches, ok := err.(Mismatches)
+	if !ok {
+		t.Fatalf("expected Mismatches, got %T: %v", err, err)
+	}
+	if len(mismatches) != 6 {
+		t.Errorf("expected every mismatch to be reported, got %d", len(mismatches))
+	}
+	want := `6 mismatches
+missing field (2):
+  $["build-tool"]: expected "x", not present
+  $.missing: expected true, not present
+type mismatch (1):
+  $.count: expected string "2", got number 2
+value mismatch (2):
+  $.long: expected "` + strings.Repeat("x", 76) + `..., got "y"
+  $.name: expected "other", got "sbom"
+unmatched element (1):
+  $.packages[1]: expected {"name":"c","version":"1.0"}, closest is element 1 {"name":"b","version":"1.0"}
+    $.packages[1].name: expected "c", got "b"`
+	if err.Error() != want {
+		t.Errorf("unexpected report:\n%s\nexpected:\n%s", err, want)
+	}
+}
+
+func TestCheckMatchers(t *testing.T) {
+	target := `{
+		"$schema": "http://cyclonedx.org/schema/bom-1.5.schema.json",
+		"name": "sbom",
+		"count": 2,
+		"packages": [
+			{"name": "stdlib", "purl": "pkg:golang/stdlib@1.22.0", "path": "/usr/local/bin/app"},
+			{"name": "github.com/pkg/errors", "purl": "pkg:golang/github.com/pkg/errors@0.9.1", "path": "/usr/local/bin/app"},
+			{"name": "musl", "purl": "pkg:apk/alpine/musl@1.2.4", "path": "/lib/apk/db/installed", "license": "MIT"}
+		]
+	}`
+	tests := []struct {
+		name   string
+		schema string
+		err    string
+	}{
+		{name: "literal dollar key", schema: `{"$schema": "http://cyclonedx.org/schema/bom-1.5.schema.json"}`},
+		{name: "regex", schema: `{"packages": [{"purl": "~^pkg:golang/"}]}`},
+		{name: "regex mismatch", schema: `{"name": "~^spdx"}`, err: `$.name: expected to match /^spdx/, got "sbom"`},
+		{name: "negated regex", schema: `{"packages": {"$every": {"path": "!~^/usr/share/doc/"}}}`},
+		{name: "negated regex mismatch", schema: `{"name": "!~^sb"}`, err: `$.name: expected not to match /^sb/, got "sbom"`},
+		{name: "regex operator", schema: `{"name": {"$regex": "^s"}}`},
+		{name: "invalid regex", schema: `{"name": "~("}`, err: "invalid regular expression"},
+		{name: "absent", schema: `{"missing": {"$absent": true}}`},
+		{name: "absent mismatch", schema: `{"name": {"$absent": true}}`, err: `$.name: expected no value, got "sbom"`},
+		{name: "present", schema: `{"name": {"$absent": false}}`},
+		{name: "present mismatch", schema: `{"missing": {"$absent": false}}`, err: "$.missing: expected"},
+		{name: "optional", schema: `{"packages": {"$every": {"license": {"$optional": "MIT"}}}}`},
+		{name: "optional mismatch", schema: `{"packages": {"$every": {"license": {"$optional": "GPL-2.0"}}}}`, err: `$.packages[2].license: expected "GPL-2.0", got "MIT"`},
+		{name: "missing matcher", schema: `{"missing": {"$type": "string"}}`, err: "$.missing: expected"},
+		{name: "eq", schema: `{"name": {"$eq": "sbom"}}`},
+		{name: "not", schema: `{"name": {"$not": "other"}}`},
+		{name: "not mismatch", schema: `{"name": {"$not": "~^s"}}`, err: `$.name: expected not to match "~^s", got "sbom"`},
+		{name: "any of", schema: `{"name": {"$anyOf": ["other", "sbom"]}}`},
+		{name: "any of mismatch", schema: `{"count": {"$anyOf": [1, 3]}}`, err: "$.count: expected any of [1,3], got 2"},
+		{name: "type", schema: `{"count": {"$type": "number"}, "packages": {"$type": ["object", "array"]}}`},
+		{name: "type mismatch", schema: `{"name": {"$type": "number"}}`, err: `$.name: expected number, got string "sbom"`},
+		{name: "unknown type", schema: `{"name": {"$type": "integer"}}`, err: `unknown type "integer"`},
+		{name: "numeric", schema: `{"count": {"$gt": 1, "$lte": 2}}`},
+		{name: "numeric mismatch", schema: `{"count": {"$gte": 3}}`, err: "$.count: expected >= 3, got 2"},
+		{name: "count", schema: `{"packages": {"$count": 3}}`},
+		{name: "count where", schema: `{"packages": {"$count": 1, "$where": {"name": "stdlib"}}}`},
+		{name: "count mismatch", schema: `{"packages": {"$count": 1, "$where": {"purl": "~^pkg:golang/"}}}`, err: `$.packages: expected count 1 elements matching {"purl":"~^pkg:golang/"}, got 2`},
+		{name: "min max", schema: `{"packages": {"$min": 2, "$max": 3}}`},
+		{name: "max mismatch", schema: `{"packages": {"$max": 2}}`, err: "$.packages: expected max 2 elements, got 3"},
+		{name: "where without count", schema: `{"packages": {"$where": {}}}`, err: "$where requires"},
+		{name: "items", schema: `{"packages": {"$items": [{"name": "musl"}], "$count": 3}}`},
+		{name: "none", schema: `{"packages": {"$none": {"path": "~^/usr/share/doc/"}}}`},
+		{name: "none mismatch", schema: `{"packages": {"$none": {"name": "musl"}}}`, err: `$.packages[2]: expected no element matching {"name":"musl"}`},
+		{name: "every mismatch", schema: `{"packages": {"$every": {"purl": "~^pkg:golang/"}}}`, err: `$.packages[2].purl: expected to match /^pkg:golang//`},
+		{name: "unknown sbom assertion", schema: `{"$sbom": {"pkgs": []}}`, err: `$["$sbom"].pkgs: unknown SBOM assertion "pkgs"`},
+		{name: "not an sbom", schema: `{"$sbom": {"packages": [{"name": "stdlib"}]}}`, err: `$["$sbom"]: expected an SBOM syft can decode`},
+		{name: "array operator on object", schema: `{"name": {"$count": 1}}`, err: "$.name: expected array [], got string"},
+		{name: "variables", schema: `{
+			"packages": [{"name": "stdlib", "path": "=path"}, {"name": "github.com/pkg/errors", "path": {"$not": "==path"}}]
+		}`, err: "$.packages[1]: expected"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var schema, tgt interface{}
+			if err := json.Unmarshal([]byte(tc.schema), &schema); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(target), &tgt); err != nil {
+				t.Fatal(err)
+			}
+			err := Check(schema, tgt)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	var statement map[string]interface{}
+	if err := json.Unmarshal([]byte(`{
+		"_type": "https://in-toto.io/Statement/v1",
+		"predicateType": "https://spdx.dev/Document",
+		"predicate": {
+			"spdxVersion": "SPDX-2.3",
+			"SPDXID": "SPDXRef-DOCUMENT",
+			"name": "sbom-base",
+			"packages": [
+				{"SPDXID": "SPDXRef-Package-apk-git-1", "name": "git", "versionInfo": "2.43.0-r0", "externalRefs": [{"referenceType": "purl", "referenceLocator": "pkg:apk/alpine/git@2.43.0-r0?arch=x86_64"}]},
+				{"SPDXID": "SPDXRef-Package-apk-musl-2", "name": "musl", "versionInfo": "1.2.4-r2"}
+			],
+			"files": [
+				{"SPDXID": "SPDXRef-File-usr-bin-git-3", "fileName": "/usr/bin/git"},
+				{"SPDXID": "SPDXRef-File-lib-libc-4", "fileName": "/lib/libc.musl-x86_64.so.1"}
+			],
+			"relationships": [
+				{"spdxElementId": "SPDXRef-DOCUMENT", "relationshipType": "DESCRIBES", "relatedSpdxElement": "SPDXRef-DocumentRoot-Directory-sbom"},
+				{"spdxElementId": "SPDXRef-Package-apk-git-1", "relationshipType": "CONTAINS", "relatedSpdxElement": "SPDXRef-File-usr-bin-git-3"},
+				{"spdxElementId": "SPDXRef-Package-apk-musl-2", "relationshipType": "CONTAINS", "relatedSpdxElement": "SPDXRef-File-lib-libc-4"}
+			]
+		}
+	}`), &statement); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := Generate(statement, Selection{Packages: []string{"pkg:apk/alpine/git"}, Paths: []string{"usr/bin/git"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dt, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"_type":"https://in-toto.io/Statement/v1","predicate":{"SPDXID":"SPDXRef-DOCUMENT","files":[{"SPDXID":"=file:usr/bin/git","fileName":"/usr/bin/git"}],"name":"sbom-base","packages":[{"SPDXID":"=package:git","externalRefs":[{"referenceLocator":"pkg:apk/alpine/git@2.43.0-r0?arch=x86_64","referenceType":"purl"}],"name":"git"}],"relationships":[{"relatedSpdxElement":"==file:usr/bin/git","relationshipType":"CONTAINS","spdxElementId":"==package:git"}]},"predicateType":"https://spdx.dev/Document"}`
+	if string(dt) != want {
+		t.Errorf("unexpected schema:\n%s\nexpected:\n%s", dt, want)
+	}
+	if err := Check(schema, statement); err != nil {
+		t.Errorf("expected the generated schema to pass: %v", err)
+	}
+
+	sel := SelectionOf(schema)
+	if strings.Join(sel.Packages, ",") != "pkg:apk/alpine/git@2.43.0-r0?arch=x86_64" || strings.Join(sel.Paths, ",") != "/usr/bin/git" {
+		t.Errorf("unexpected selection %+v", sel)
+	}
+
+	_, err = Generate(statement, Selection{Packages: []string{"musl", "bash"}, Paths: []string{"/bin/sh"}})
+	if err == nil || err.Error() != "not found in the SBOM: package bash, file /bin/sh" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	var statement map[string]interface{}
+	if err := json.Unmarshal([]byte(`{
+		"_type": "https://in-toto.io/Statement/v1",
+		"predicateType": "https://spdx.dev/Document",
+		"subject": [{"name": "empty", "digest": {"sha256": "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"}}],
+		"predicate": {
+			"spdxVersion": "SPDX-2.3",
+			"SPDXID": "SPDXRef-DOCUMENT",
+			"name": "sbom-base",
+			"packages": [
+				{"SPDXID": "SPDXRef-Package-apk-git-1", "name": "git", "versionInfo": "2.44.0-r0", "externalRefs": [{"referenceType": "purl", "referenceLocator": "pkg:apk/alpine/git@2.44.0-r0?arch=x86_64"}]},
+				{"SPDXID": "SPDXRef-Package-apk-musl-2", "name": "musl", "versionInfo": "1.2.4-r2"},
+				{"SPDXID": "SPDXRef-Package-apk-busybox-3", "name": "busybox", "versionInfo": "1.36.1-r15"}
+			],
+			"files": [
+				{"SPDXID": "SPDXRef-File-usr-bin-git-4", "fileName": "/usr/bin/git"},
+				{"SPDXID": "SPDXRef-File-lib-libc-5", "fileName": "/lib/libc.musl-x86_64.so.1"}
+			],
+			"relationships": [
+				{"spdxElementId": "SPDXRef-Package-apk-git-1", "relationshipType": "CONTAINS", "relatedSpdxElement": "SPDXRef-File-usr-bin-git-4"},
+				{"spdxElementId": "SPDXRef-Package-apk-musl-2", "relationshipType": "CONTAINS", "relatedSpdxElement": "SPDXRef-File-lib-libc-5"}
+			]
+		}
+	}`), &statement); err != nil {
+		t.Fatal(err)
+	}
+	// generated for git 2.43.0, then edited by hand
+	previous := []byte(`{
+		"_type": "https://in-toto.io/Statement/v1",
+		"predicateType": "https://spdx.dev/Document",
+		"subject": [{"name": "empty", "digest": {"sha256": "~^[0-9a-f]{64}$"}}],
+		"predicate": {
+			"SPDXID": "SPDXRef-DOCUMENT",
+			"name": "sbom-base",
+			"packages": [
+				{"SPDXID": "=git", "name": "git", "externalRefs": [{"referenceType": "purl", "referenceLocator": "pkg:apk/alpine/git@2.43.0-r0?arch=x86_64"}]},
+				{"SPDXID": "=musl", "name": "musl", "versionInfo": "1.2.4-r2"},
+				{"name": "~^busy"}
+			],
+			"files": [{"SPDXID": "=filename", "fileName": "/usr/bin/git"}],
+			"relationships": [{"spdxElementId": "==git", "relationshipType": "CONTAINS", "relatedSpdxElement": "==filename"}]
+		},
+		"$sbom": {"absent": [{"name": "bash"}]}
+	}`)
+	var schema map[string]interface{}
+	if err := json.Unmarshal(previous, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := Update(schema, statement, Selection{Paths: []string{"/lib/libc.musl-x86_64.so.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dt, err := MarshalSchema(updated, previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the purl written as generated is refreshed, the rest is kept in its
+	// order, and the new file is related to musl through its variable
+	want := `{
+		"_type": "https://in-toto.io/Statement/v1",
+		"predicateType": "https://spdx.dev/Document",
+		"subject": [{"name": "empty", "digest": {"sha256": "~^[0-9a-f]{64}$"}}],
+		"predicate": {
+			"SPDXID": "SPDXRef-DOCUMENT",
+			"name": "sbom-base",
+			"packages": [
+				{"SPDXID": "=git", "name": "git", "externalRefs": [{"referenceType": "purl", "referenceLocator": "pkg:apk/alpine/git@2.44.0-r0?arch=x86_64"}]},
+				{"SPDXID": "=musl", "name": "musl", "versionInfo": "1.2.4-r2"},
+				{"name": "~^busy"}
+			],
+			"files": [
+				{"SPDXID": "=filename", "fileName": "/usr/bin/git"},
+				{"SPDXID": "=file:lib/libc.musl-x86_64.so.1", "fileName": "/lib/libc.musl-x86_64.so.1"}
+			],
+			"relationships": [
+				{"spdxElementId": "==git", "relationshipType": "CONTAINS", "relatedSpdxElement": "==filename"},
+				{"relatedSpdxElement": "==file:lib/libc.musl-x86_64.so.1", "relationshipType": "CONTAINS", "spdxElementId": "==musl"}
+			]
+		},
+		"$sbom": {"absent": [{"name": "bash"}]}
+	}`
+	var got, expected bytes.Buffer
+	if err := json.Compact(&got, dt); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Compact(&expected, []byte(want)); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != expected.String() {
+		t.Errorf("unexpected schema:\n%s\nexpected:\n%s", got.String(), expected.String())
+	}
+
+	// the toy statement is not an SBOM syft can decode
+	delete(updated, "$sbom")
+	if err := Check(updated, statement); err != nil {
+		t.Errorf("expected the updated schema to pass: %v", err)
+	}
+
+	// hand-written assertions are not dropped to make them pass
+	schema["predicate"].(map[string]interface{})["packages"] = []interface{}{
+		map[string]interface{}{"name": "git", "externalRefs": []interface{}{map[string]interface{}{"referenceLocator": "~@2\\.43\\."}}},
+		map[string]interface{}{"name": "bash", "versionInfo": "5.2.21-r0"},
+	}
+	if _, err := Update(schema, statement, Selection{}); err == nil || err.Error() != "not found in the SBOM: package bash" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
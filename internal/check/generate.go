@@ -0,0 +1,380 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Selection lists the packages and files a generated schema asserts on.
+type Selection struct {
+	// Packages are package names, or package URLs, with or without the
+	// version and qualifiers.
+	Packages []string
+	// Paths are file paths, with or without the leading slash.
+	Paths []string
+}
+
+// sbomFormat describes where an SBOM format keeps the elements a schema is
+// generated from.
+type sbomFormat struct {
+	// header are the keys of the document copied into the schema.
+	header []string
+	// packages and files name the lists of elements, and id the key of their
+	// volatile identifier.
+	packages, files, id string
+	// isFile reports whether an element of packages is a file, for formats
+	// listing both together.
+	isFile func(map[string]interface{}) bool
+	// path returns the path of a file.
+	path func(map[string]interface{}) string
+	// purl returns the package URL of a package, and withPURL asserts it in
+	// the schema of the package.
+	purl     func(map[string]interface{}) string
+	withPURL func(schema map[string]interface{}, purl string)
+	// file returns the schema of the file at path.
+	file func(path string) map[string]interface{}
+	// relationships name the list of relationships, and their keys
+	// referencing elements; the others are copied.
+	relationships string
+	refs          []string
+}
+
+var (
+	spdxFormat = sbomFormat{
+		header:   []string{"SPDXID", "name"},
+		packages: "packages",
+		files:    "files",
+		id:       "SPDXID",
+		path:     func(f map[string]interface{}) string { return stringField(f, "fileName") },
+		purl: func(p map[string]interface{}) string {
+			for _, ref := range asObjects(p["externalRefs"]) {
+				if stringField(ref, "referenceType") == "purl" {
+					return stringField(ref, "referenceLocator")
+				}
+			}
+			return ""
+		},
+		withPURL: func(schema map[string]interface{}, purl string) {
+			schema["externalRefs"] = []interface{}{map[string]interface{}{"referenceType": "purl", "referenceLocator": purl}}
+		},
+		file:          func(path string) map[string]interface{} { return map[string]interface{}{"fileName": path} },
+		relationships: "relationships",
+		refs:          []string{"spdxElementId", "relatedSpdxElement"},
+	}
+	cyclonedxFormat = sbomFormat{
+		header:        []string{"bomFormat"},
+		packages:      "components",
+		id:            "bom-ref",
+		isFile:        func(c map[string]interface{}) bool { return stringField(c, "type") == "file" },
+		path:          func(c map[string]interface{}) string { return stringField(c, "name") },
+		purl:          func(c map[string]interface{}) string { return stringField(c, "purl") },
+		withPURL:      func(schema map[string]interface{}, purl string) { schema["purl"] = purl },
+		file:          func(path string) map[string]interface{} { return map[string]interface{}{"type": "file", "name": path} },
+		relationships: "dependencies",
+		refs:          []string{"ref", "dependsOn"},
+	}
+	syftFormat = sbomFormat{
+		packages: "artifacts",
+		files:    "files",
+		id:       "id",
+		path:     func(f map[string]interface{}) string { return stringField(asObject(f["location"]), "path") },
+		purl:     func(a map[string]interface{}) string { return stringField(a, "purl") },
+		withPURL: func(schema map[string]interface{}, purl string) { schema["purl"] = purl },
+		file: func(path string) map[string]interface{} {
+			return map[string]interface{}{"location": map[string]interface{}{"path": path}}
+		},
+		relationships: "artifactRelationships",
+		refs:          []string{"parent", "child"},
+	}
+)
+
+// detectFormat returns the format of the SBOM doc, from its shape.
+func detectFormat(doc map[string]interface{}) (sbomFormat, error) {
+	switch {
+	case doc["spdxVersion"] != nil:
+		return spdxFormat, nil
+	case doc["bomFormat"] != nil:
+		return cyclonedxFormat, nil
+	case doc["artifacts"] != nil && doc["descriptor"] != nil:
+		return syftFormat, nil
+	}
+	return sbomFormat{}, fmt.Errorf("unknown SBOM format, must be SPDX, CycloneDX or syft JSON")
+}
+
+// Generate returns a minimal schema that statement, an in-toto statement of
+// an SBOM, passes: its type, the name of the document, the selected packages
+// and files, and the relationships between them. Identifiers of elements
+// change from one scan to the next, so they are matched through variables.
+func Generate(statement map[string]interface{}, sel Selection) (map[string]interface{}, error) {
+	doc, ok := statement["predicate"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("not an in-toto statement, no predicate found")
+	}
+	f, err := detectFormat(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	predicate := map[string]interface{}{}
+	for _, k := range f.header {
+		if v, ok := doc[k]; ok {
+			predicate[k] = v
+		}
+	}
+
+	vars := map[string]string{}
+	used := map[string]struct{}{}
+	variable := func(prefix string, name string, id string) string {
+		key := prefix + ":" + name
+		for i := 2; ; i++ {
+			if _, ok := used[key]; !ok {
+				break
+			}
+			key = fmt.Sprintf("%s:%s#%d", prefix, name, i)
+		}
+		used[key] = struct{}{}
+		vars[id] = key
+		return key
+	}
+
+	var missing []string
+	var packages, files []interface{}
+	for _, p := range dedup(sel.Packages) {
+		found := false
+		for _, elem := range asObjects(doc[f.packages]) {
+			if f.isFile != nil && f.isFile(elem) {
+				continue
+			}
+			name := stringField(elem, "name")
+			purl := f.purl(elem)
+			if name != p && !matchPURL(purl, p) {
+				continue
+			}
+			found = true
+			schema := map[string]interface{}{"name": name}
+			if id := stringField(elem, f.id); id != "" {
+				schema[f.id] = "=" + variable("package", name, id)
+			}
+			if strings.HasPrefix(p, "pkg:") {
+				f.withPURL(schema, purl)
+			}
+			packages = append(packages, schema)
+		}
+		if !found {
+			missing = append(missing, "package "+p)
+		}
+	}
+	fileList := doc[f.files]
+	if f.files == "" {
+		fileList = doc[f.packages]
+	}
+	for _, p := range dedup(sel.Paths) {
+		found := false
+		for _, elem := range asObjects(fileList) {
+			if f.isFile != nil && !f.isFile(elem) {
+				continue
+			}
+			path := f.path(elem)
+			if strings.TrimPrefix(path, "/") != strings.TrimPrefix(p, "/") {
+				continue
+			}
+			found = true
+			schema := f.file(path)
+			if id := stringField(elem, f.id); id != "" {
+				schema[f.id] = "=" + variable("file", strings.TrimPrefix(path, "/"), id)
+			}
+			files = append(files, schema)
+		}
+		if !found {
+			missing = append(missing, "file "+p)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("not found in the SBOM: %s", strings.Join(missing, ", "))
+	}
+
+	if f.files == "" {
+		packages = append(packages, files...)
+		files = nil
+	}
+	if len(packages) > 0 {
+		predicate[f.packages] = packages
+	}
+	if len(files) > 0 {
+		predicate[f.files] = files
+	}
+	if relationships := generateRelationships(f, doc, vars); len(relationships) > 0 {
+		predicate[f.relationships] = relationships
+	}
+
+	schema := map[string]interface{}{"predicate": predicate}
+	for _, k := range []string{"_type", "predicateType"} {
+		if v, ok := statement[k]; ok {
+			schema[k] = v
+		}
+	}
+	return schema, nil
+}
+
+// generateRelationships returns the relationships of doc between the
+// elements with a variable, referencing them through it.
+func generateRelationships(f sbomFormat, doc map[string]interface{}, vars map[string]string) []interface{} {
+	ref := func(v interface{}) (interface{}, bool) {
+		switch v := v.(type) {
+		case string:
+			key, ok := vars[v]
+			return "==" + key, ok
+		case []interface{}:
+			var refs []interface{}
+			for _, id := range v {
+				if id, ok := id.(string); ok {
+					if key, ok := vars[id]; ok {
+						refs = append(refs, "=="+key)
+					}
+				}
+			}
+			return refs, len(refs) > 0
+		}
+		return nil, false
+	}
+
+	var relationships []interface{}
+	for _, rel := range asObjects(doc[f.relationships]) {
+		schema := map[string]interface{}{}
+		complete := true
+		for _, k := range f.refs {
+			v, ok := ref(rel[k])
+			if !ok {
+				complete = false
+				break
+			}
+			schema[k] = v
+		}
+		if !complete {
+			continue
+		}
+		for k, v := range rel {
+			if _, ok := schema[k]; !ok {
+				if _, isString := v.(string); isString {
+					schema[k] = v
+				}
+			}
+		}
+		relationships = append(relationships, schema)
+	}
+	return relationships
+}
+
+// SelectionOf returns the packages and files a schema asserts on by name,
+// to generate it again.
+func SelectionOf(schema map[string]interface{}) Selection {
+	var sel Selection
+	doc, _ := schema["predicate"].(map[string]interface{})
+	f, err := detectSchemaFormat(doc)
+	if err != nil {
+		return sel
+	}
+	for _, elem := range asObjects(doc[f.packages]) {
+		if f.isFile != nil && f.isFile(elem) {
+			sel.Paths = appendLiteral(sel.Paths, f.path(elem))
+			continue
+		}
+		if purl := f.purl(elem); strings.HasPrefix(purl, "pkg:") {
+			sel.Packages = appendLiteral(sel.Packages, purl)
+		} else {
+			sel.Packages = appendLiteral(sel.Packages, stringField(elem, "name"))
+		}
+	}
+	for _, elem := range asObjects(doc[f.files]) {
+		sel.Paths = appendLiteral(sel.Paths, f.path(elem))
+	}
+	return sel
+}
+
+// detectSchemaFormat returns the format of the SBOM a schema is for, which
+// may lack the keys identifying the SBOM itself.
+func detectSchemaFormat(doc map[string]interface{}) (sbomFormat, error) {
+	if f, err := detectFormat(doc); err == nil {
+		return f, nil
+	}
+	switch {
+	case doc["SPDXID"] != nil || doc["relationships"] != nil:
+		return spdxFormat, nil
+	case doc["components"] != nil || doc["dependencies"] != nil:
+		return cyclonedxFormat, nil
+	case doc["artifacts"] != nil || doc["artifactRelationships"] != nil:
+		return syftFormat, nil
+	}
+	return sbomFormat{}, fmt.Errorf("unknown SBOM format")
+}
+
+// appendLiteral appends s to list, if it is a literal.
+func appendLiteral(list []string, s string) []string {
+	if !isLiteral(s) {
+		return list
+	}
+	return append(list, s)
+}
+
+// isLiteral reports whether s is neither empty, a pattern nor a variable.
+func isLiteral(s string) bool {
+	return s != "" && !strings.HasPrefix(s, "=") && !strings.HasPrefix(s, "~") && !strings.HasPrefix(s, "!~")
+}
+
+// dedup returns list without its repeated values.
+func dedup(list []string) []string {
+	seen := make(map[string]struct{}, len(list))
+	var out []string
+	for _, s := range list {
+		if _, ok := seen[s]; !ok {
+			seen[s] = struct{}{}
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// matchPURL reports whether purl is selector, or selector with a version,
+// qualifiers or a subpath.
+func matchPURL(purl string, selector string) bool {
+	if !strings.HasPrefix(selector, "pkg:") || !strings.HasPrefix(purl, selector) {
+		return false
+	}
+	rest := purl[len(selector):]
+	return rest == "" || strings.ContainsAny(rest[:1], "@?#")
+}
+
+func stringField(obj map[string]interface{}, key string) string {
+	s, _ := obj[key].(string)
+	return s
+}
+
+func asObject(v interface{}) map[string]interface{} {
+	obj, _ := v.(map[string]interface{})
+	return obj
+}
+
+func asObjects(v interface{}) []map[string]interface{} {
+	list, _ := v.([]interface{})
+	objects := make([]map[string]interface{}, 0, len(list))
+	for _, v := range list {
+		if obj, ok := v.(map[string]interface{}); ok {
+			objects = append(objects, obj)
+		}
+	}
+	return objects
+}
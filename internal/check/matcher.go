@@ -0,0 +1,315 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// operators are the keys of matcher objects.
+var operators = map[string]struct{}{
+	"$absent":   {},
+	"$optional": {},
+	"$eq":       {},
+	"$regex":    {},
+	"$not":      {},
+	"$anyOf":    {},
+	"$type":     {},
+	"$gt":       {},
+	"$gte":      {},
+	"$lt":       {},
+	"$lte":      {},
+	"$items":    {},
+	"$where":    {},
+	"$count":    {},
+	"$min":      {},
+	"$max":      {},
+	"$none":     {},
+	"$every":    {},
+}
+
+// isMatcher reports whether the schema object m is a matcher rather than an
+// object to match: all its keys must be operators, so that documents with
+// keys such as "$schema" can still be matched literally.
+func isMatcher(m map[string]interface{}) bool {
+	if len(m) == 0 {
+		return false
+	}
+	for k := range m {
+		if _, ok := operators[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// checkMatcher checks target against every operator of the matcher m.
+// present is false if the key of the matcher is missing from the target
+// object, in which case target is nil.
+func (c *checker) checkMatcher(m map[string]interface{}, target interface{}, present bool, path string) Mismatches {
+	if absent, ok := m["$absent"]; ok {
+		absent, ok := absent.(bool)
+		if !ok {
+			return invalid(path, "$absent must be a boolean")
+		}
+		switch {
+		case absent && present:
+			return Mismatches{{Path: path, Kind: KindAssertion, Message: "expected no value", Actual: target}}
+		case !absent && !present:
+			return Mismatches{{Path: path, Kind: KindMissing, Expected: m}}
+		}
+		if len(m) == 1 || !present {
+			return nil
+		}
+	}
+	if optional, ok := m["$optional"]; ok {
+		if !present {
+			return nil
+		}
+		if len(m) > 1 {
+			return invalid(path, "$optional cannot be combined with other operators")
+		}
+		return c.check(optional, target, path)
+	}
+	if !present {
+		return Mismatches{{Path: path, Kind: KindMissing, Expected: m}}
+	}
+
+	ops := sortedKeys(m)
+
+	var mismatches Mismatches
+	var array []interface{}
+	for _, op := range ops {
+		switch op {
+		case "$items", "$where", "$count", "$min", "$max", "$none", "$every":
+			var ok bool
+			if array, ok = target.([]interface{}); !ok {
+				return Mismatches{{Path: path, Kind: KindType, Expected: []interface{}{}, Actual: target}}
+			}
+		}
+	}
+
+	for _, op := range ops {
+		arg := m[op]
+		switch op {
+		case "$absent", "$where":
+		case "$eq":
+			if !reflect.DeepEqual(arg, target) {
+				mismatches = append(mismatches, Mismatch{Path: path, Kind: KindValue, Expected: arg, Actual: target})
+			}
+		case "$regex":
+			mismatches = append(mismatches, c.checkRegex(arg, target, false, path)...)
+		case "$not":
+			if c.assign {
+				continue
+			}
+			if len(c.check(arg, target, path)) == 0 {
+				mismatches = append(mismatches, Mismatch{Path: path, Kind: KindAssertion, Message: "expected not to match " + snippet(arg), Actual: target})
+			}
+		case "$anyOf":
+			alternatives, ok := arg.([]interface{})
+			if !ok || len(alternatives) == 0 {
+				return invalid(path, "$anyOf must be a non-empty array")
+			}
+			matched := false
+			for _, alt := range alternatives {
+				if len(c.check(alt, target, path)) == 0 {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				mismatches = append(mismatches, Mismatch{Path: path, Kind: KindAssertion, Message: "expected any of " + snippet(arg), Actual: target})
+			}
+		case "$type":
+			mismatches = append(mismatches, checkType(arg, target, path)...)
+		case "$gt", "$gte", "$lt", "$lte":
+			mismatches = append(mismatches, checkNumber(op, arg, target, path)...)
+		case "$items":
+			items, ok := arg.([]interface{})
+			if !ok {
+				return invalid(path, "$items must be an array")
+			}
+			mismatches = append(mismatches, c.checkSlice(items, array, path)...)
+		case "$count", "$min", "$max":
+			bound, ok := arg.(float64)
+			if !ok || bound < 0 || bound != float64(int(bound)) {
+				return invalid(path, op+" must be a non-negative integer")
+			}
+			n := c.count(m["$where"], array, path)
+			var failed bool
+			switch op {
+			case "$count":
+				failed = n != int(bound)
+			case "$min":
+				failed = n < int(bound)
+			case "$max":
+				failed = n > int(bound)
+			}
+			if failed {
+				what := "elements"
+				if where, ok := m["$where"]; ok {
+					what = "elements matching " + snippet(where)
+				}
+				mismatches = append(mismatches, Mismatch{
+					Path:    path,
+					Kind:    KindLength,
+					Message: fmt.Sprintf("expected %s %d %s, got %d", strings.TrimPrefix(op, "$"), int(bound), what, n),
+				})
+			}
+		case "$none":
+			if c.assign {
+				continue
+			}
+			for i, v := range array {
+				if len(c.check(arg, v, path)) == 0 {
+					mismatches = append(mismatches, Mismatch{
+						Path:    fmt.Sprintf("%s[%d]", path, i),
+						Kind:    KindAssertion,
+						Message: "expected no element matching " + snippet(arg),
+						Actual:  v,
+					})
+				}
+			}
+		case "$every":
+			for i, v := range array {
+				mismatches = append(mismatches, c.check(arg, v, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	if _, ok := m["$where"]; ok && m["$count"] == nil && m["$min"] == nil && m["$max"] == nil {
+		return invalid(path, "$where requires $count, $min or $max")
+	}
+	return mismatches
+}
+
+// count returns the number of elements of array matching where, or of all
+// elements if where is nil.
+func (c *checker) count(where interface{}, array []interface{}, path string) int {
+	if where == nil {
+		return len(array)
+	}
+	n := 0
+	for _, v := range array {
+		if len(c.check(where, v, path)) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// checkRegex checks that target is a string matching the pattern arg, or
+// not matching it if negate is set.
+func (c *checker) checkRegex(arg interface{}, target interface{}, negate bool, path string) Mismatches {
+	pattern, ok := arg.(string)
+	if !ok {
+		return invalid(path, "$regex must be a string")
+	}
+	re, ok := c.regexps[pattern]
+	if !ok {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			return invalid(path, fmt.Sprintf("invalid regular expression %q: %v", pattern, err))
+		}
+		if c.regexps == nil {
+			c.regexps = make(map[string]*regexp.Regexp)
+		}
+		c.regexps[pattern] = re
+	}
+	s, ok := target.(string)
+	if !ok {
+		return Mismatches{{Path: path, Kind: KindType, Expected: pattern, Actual: target}}
+	}
+	if re.MatchString(s) == negate {
+		verb := "to match"
+		if negate {
+			verb = "not to match"
+		}
+		return Mismatches{{Path: path, Kind: KindAssertion, Message: fmt.Sprintf("expected %s /%s/", verb, pattern), Actual: target}}
+	}
+	return nil
+}
+
+var jsonTypes = map[string]struct{}{
+	"null": {}, "boolean": {}, "number": {}, "string": {}, "array": {}, "object": {},
+}
+
+// checkType checks that target is of the JSON type arg, or of one of the
+// types if arg is an array.
+func checkType(arg interface{}, target interface{}, path string) Mismatches {
+	var types []string
+	switch arg := arg.(type) {
+	case string:
+		types = []string{arg}
+	case []interface{}:
+		for _, t := range arg {
+			if t, ok := t.(string); ok {
+				types = append(types, t)
+			}
+		}
+		if len(types) != len(arg) {
+			types = nil
+		}
+	}
+	if len(types) == 0 {
+		return invalid(path, "$type must be a type name or an array of type names")
+	}
+	actual := jsonType(target)
+	for _, t := range types {
+		if _, ok := jsonTypes[t]; !ok {
+			return invalid(path, fmt.Sprintf("unknown type %q", t))
+		}
+		if t == actual {
+			return nil
+		}
+	}
+	return Mismatches{{Path: path, Kind: KindType, Message: "expected " + strings.Join(types, " or "), Actual: target}}
+}
+
+// checkNumber checks that target is a number comparing to arg as op
+// requires.
+func checkNumber(op string, arg interface{}, target interface{}, path string) Mismatches {
+	bound, ok := arg.(float64)
+	if !ok {
+		return invalid(path, op+" must be a number")
+	}
+	n, ok := target.(float64)
+	if !ok {
+		return Mismatches{{Path: path, Kind: KindType, Expected: bound, Actual: target}}
+	}
+	var holds bool
+	var symbol string
+	switch op {
+	case "$gt":
+		holds, symbol = n > bound, ">"
+	case "$gte":
+		holds, symbol = n >= bound, ">="
+	case "$lt":
+		holds, symbol = n < bound, "<"
+	case "$lte":
+		holds, symbol = n <= bound, "<="
+	}
+	if !holds {
+		return Mismatches{{Path: path, Kind: KindAssertion, Message: fmt.Sprintf("expected %s %s", symbol, snippet(bound)), Actual: target}}
+	}
+	return nil
+}
+
+func invalid(path string, message string) Mismatches {
+	return Mismatches{{Path: path, Kind: KindSchema, Message: message}}
+}
@@ -0,0 +1,198 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Output formats of suite results.
+const (
+	OutputText  = "text"
+	OutputJUnit = "junit"
+	OutputTAP   = "tap"
+)
+
+// WriteResults writes results to w in the output format.
+func WriteResults(w io.Writer, output string, results []SuiteResult) error {
+	switch output {
+	case OutputText, "":
+		return writeText(w, results)
+	case OutputJUnit:
+		return writeJUnit(w, results)
+	case OutputTAP:
+		return writeTAP(w, results)
+	}
+	return fmt.Errorf("unknown output format %q, must be one of %s, %s, %s", output, OutputText, OutputJUnit, OutputTAP)
+}
+
+func writeText(w io.Writer, results []SuiteResult) error {
+	var failed, passed int
+	for _, suite := range results {
+		if suite.Err != nil {
+			failed++
+			fmt.Fprintf(w, "FAIL %s: %v\n", suite.Name, suite.Err)
+			continue
+		}
+		for _, r := range suite.Results {
+			switch {
+			case r.Err != nil:
+				failed++
+				fmt.Fprintf(w, "FAIL %s/%s: %v\n", suite.Name, r.Name, r.Err)
+			case len(r.Mismatches) > 0:
+				failed++
+				fmt.Fprintf(w, "FAIL %s/%s: %s\n", suite.Name, r.Name, indent(r.Mismatches.Error(), "  "))
+			default:
+				passed++
+				fmt.Fprintf(w, "ok   %s/%s\n", suite.Name, r.Name)
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d failed, %d passed\n", failed, passed)
+	return err
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// writeJUnit writes a testsuite per suite, and a testcase per schema whose
+// failure lists the mismatches.
+func writeJUnit(w io.Writer, results []SuiteResult) error {
+	var doc junitSuites
+	for _, suite := range results {
+		js := junitSuite{Name: suite.Name, Time: seconds(suite.Duration.Seconds())}
+		if suite.Err != nil {
+			js.Tests, js.Errors = 1, 1
+			js.Cases = append(js.Cases, junitCase{
+				Name:      suite.Name,
+				ClassName: suite.Name,
+				Time:      seconds(0),
+				Error:     &junitProblem{Message: suite.Err.Error(), Type: "error"},
+			})
+		}
+		for _, r := range suite.Results {
+			jc := junitCase{Name: r.Name, ClassName: suite.Name, Time: seconds(r.Duration.Seconds())}
+			switch {
+			case r.Err != nil:
+				js.Errors++
+				jc.Error = &junitProblem{Message: r.Err.Error(), Type: "error"}
+			case len(r.Mismatches) > 0:
+				js.Failures++
+				report := r.Mismatches.Error()
+				jc.Failure = &junitProblem{Message: r.Mismatches[0].String(), Type: "mismatch", Body: report}
+			}
+			js.Tests++
+			js.Cases = append(js.Cases, jc)
+		}
+		doc.Suites = append(doc.Suites, js)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// writeTAP writes a test point per schema, in TAP version 13, with the
+// mismatches of failures as a YAML diagnostic block.
+func writeTAP(w io.Writer, results []SuiteResult) error {
+	var b strings.Builder
+	n := 0
+	for _, suite := range results {
+		if suite.Err != nil {
+			n++
+			fmt.Fprintf(&b, "not ok %d - %s\n", n, suite.Name)
+			writeTAPDiagnostic(&b, suite.Err.Error(), nil)
+			continue
+		}
+		for _, r := range suite.Results {
+			n++
+			if r.Passed() {
+				fmt.Fprintf(&b, "ok %d - %s/%s\n", n, suite.Name, r.Name)
+				continue
+			}
+			fmt.Fprintf(&b, "not ok %d - %s/%s\n", n, suite.Name, r.Name)
+			if r.Err != nil {
+				writeTAPDiagnostic(&b, r.Err.Error(), nil)
+			} else {
+				writeTAPDiagnostic(&b, r.Mismatches.count(), r.Mismatches)
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "TAP version 13\n1..%d\n%s", n, b.String())
+	return err
+}
+
+func writeTAPDiagnostic(b *strings.Builder, message string, mismatches Mismatches) {
+	b.WriteString("  ---\n")
+	fmt.Fprintf(b, "  message: %s\n", yamlString(message))
+	if len(mismatches) > 0 {
+		b.WriteString("  mismatches:\n")
+		for _, m := range mismatches {
+			fmt.Fprintf(b, "    - path: %s\n", yamlString(m.Path))
+			fmt.Fprintf(b, "      kind: %s\n", yamlString(string(m.Kind)))
+			fmt.Fprintf(b, "      detail: %s\n", yamlString(strings.TrimPrefix(m.String(), m.Path+": ")))
+		}
+	}
+	b.WriteString("  ...\n")
+}
+
+// yamlString quotes s as a JSON string, which is a valid YAML scalar.
+func yamlString(s string) string {
+	dt, _ := json.Marshal(s)
+	return string(dt)
+}
+
+func seconds(s float64) string {
+	return fmt.Sprintf("%.3f", s)
+}
+
+func indent(s string, prefix string) string {
+	return strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
@@ -0,0 +1,192 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// rootPath is the JSON path of the whole document.
+const rootPath = "$"
+
+// snippetLength is the length values are truncated to in reports.
+const snippetLength = 80
+
+// Kind classifies a mismatch.
+type Kind string
+
+const (
+	// KindMissing is a key of the schema missing from the target.
+	KindMissing Kind = "missing field"
+	// KindType is a value of another JSON type than in the schema.
+	KindType Kind = "type mismatch"
+	// KindValue is a value different from the schema.
+	KindValue Kind = "value mismatch"
+	// KindLength is an array shorter than in the schema.
+	KindLength Kind = "length mismatch"
+	// KindElement is an element of an array in the schema matching no
+	// element of the target.
+	KindElement Kind = "unmatched element"
+	// KindVariable is a value different from the variable it is compared
+	// to.
+	KindVariable Kind = "variable mismatch"
+	// KindUnknownVariable is a comparison to a variable never assigned.
+	KindUnknownVariable Kind = "unknown variable"
+	// KindAssertion is a value failing an assertion of a matcher, such as a
+	// regular expression or a numeric comparison.
+	KindAssertion Kind = "failed assertion"
+	// KindSchema is a matcher of the schema that is not valid.
+	KindSchema Kind = "invalid schema"
+)
+
+// kinds orders the groups of a report.
+var kinds = []Kind{KindMissing, KindType, KindValue, KindLength, KindElement, KindVariable, KindUnknownVariable, KindAssertion, KindSchema}
+
+// Mismatch is a difference between the schema and the target.
+type Mismatch struct {
+	// Path is the JSON path of the mismatch in the schema, e.g.
+	// "$.predicate.packages[0].name".
+	Path string
+	Kind Kind
+	// Message details the mismatch, if needed.
+	Message string
+	// Expected is the value in the schema.
+	Expected interface{}
+	// Actual is the value in the target.
+	Actual interface{}
+	// Closest are the mismatches of the closest target element, for
+	// KindElement.
+	Closest Mismatches
+}
+
+func (m Mismatch) String() string {
+	var s string
+	switch m.Kind {
+	case KindMissing:
+		s = fmt.Sprintf("expected %s, not present", snippet(m.Expected))
+	case KindType:
+		if m.Message != "" {
+			s = fmt.Sprintf("%s, got %s %s", m.Message, jsonType(m.Actual), snippet(m.Actual))
+			break
+		}
+		s = fmt.Sprintf("expected %s %s, got %s %s", jsonType(m.Expected), snippet(m.Expected), jsonType(m.Actual), snippet(m.Actual))
+	case KindLength, KindUnknownVariable, KindSchema:
+		s = m.Message
+	case KindAssertion:
+		s = m.Message + ", got " + snippet(m.Actual)
+	case KindElement:
+		s = "expected " + snippet(m.Expected)
+		if m.Closest != nil {
+			s += ", " + m.Message + " " + snippet(m.Actual)
+		} else if m.Message != "" {
+			s += ", " + m.Message
+		} else {
+			s += ", array is empty"
+		}
+	case KindVariable:
+		s = fmt.Sprintf("expected %s (%s), got %s", snippet(m.Expected), m.Message, snippet(m.Actual))
+	default:
+		s = fmt.Sprintf("expected %s, got %s", snippet(m.Expected), snippet(m.Actual))
+	}
+	return m.Path + ": " + s
+}
+
+// Mismatches are all the differences between a schema and a target.
+type Mismatches []Mismatch
+
+// Error reports the mismatches grouped by kind, along with the mismatches of
+// the closest element of unmatched array elements.
+func (ms Mismatches) Error() string {
+	var b strings.Builder
+	b.WriteString(ms.count())
+	for _, kind := range kinds {
+		var group Mismatches
+		for _, m := range ms {
+			if m.Kind == kind {
+				group = append(group, m)
+			}
+		}
+		if len(group) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s (%d):", kind, len(group))
+		group.write(&b, "  ")
+	}
+	return b.String()
+}
+
+// count returns the number of mismatches, e.g. "2 mismatches".
+func (ms Mismatches) count() string {
+	if len(ms) == 1 {
+		return "1 mismatch"
+	}
+	return fmt.Sprintf("%d mismatches", len(ms))
+}
+
+func (ms Mismatches) write(b *strings.Builder, indent string) {
+	for _, m := range ms {
+		b.WriteString("\n" + indent + m.String())
+		m.Closest.write(b, indent+"  ")
+	}
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// keyPath returns the JSON path of key in the object at path.
+func keyPath(path string, key string) string {
+	if identifier.MatchString(key) {
+		return path + "." + key
+	}
+	dt, _ := json.Marshal(key)
+	return path + "[" + string(dt) + "]"
+}
+
+// snippet returns v as compact JSON, truncated to snippetLength.
+func snippet(v interface{}) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	s := []rune(strings.TrimSuffix(buf.String(), "\n"))
+	if len(s) > snippetLength {
+		return string(s[:snippetLength-3]) + "..."
+	}
+	return string(s)
+}
+
+// jsonType returns the JSON type name of a decoded value.
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64, json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,341 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/anchore/syft/syft/artifact"
+	"github.com/anchore/syft/syft/file"
+	"github.com/anchore/syft/syft/format"
+	"github.com/anchore/syft/syft/format/cyclonedxjson"
+	"github.com/anchore/syft/syft/format/spdxjson"
+	"github.com/anchore/syft/syft/format/syftjson"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/sbom"
+)
+
+// sbomKey is the key of the schema holding the assertions on the decoded
+// SBOM.
+const sbomKey = "$sbom"
+
+var decoders = format.NewDecoderCollection(
+	spdxjson.NewFormatDecoder(),
+	cyclonedxjson.NewFormatDecoder(),
+	syftjson.NewFormatDecoder(),
+)
+
+// relationshipTypes maps the relationship types of assertions to the syft
+// relationship type, and whether its direction is reversed.
+var relationshipTypes = map[string]struct {
+	typ      artifact.RelationshipType
+	reversed bool
+}{
+	"CONTAINS":                  {artifact.ContainsRelationship, false},
+	"CONTAINED_BY":              {artifact.ContainsRelationship, true},
+	"DEPENDS_ON":                {artifact.DependencyOfRelationship, true},
+	"DEPENDENCY_OF":             {artifact.DependencyOfRelationship, false},
+	"EVIDENT_BY":                {artifact.EvidentByRelationship, false},
+	"OWNERSHIP_BY_FILE_OVERLAP": {artifact.OwnershipByFileOverlapRelationship, false},
+}
+
+// element is an element of a decoded SBOM, with the view of it selectors
+// are matched against.
+type element struct {
+	id   artifact.ID
+	view map[string]interface{}
+}
+
+// decodedSBOM is an SBOM decoded by syft, whatever its format.
+type decodedSBOM struct {
+	packages      []element
+	files         map[artifact.ID]element
+	relationships []artifact.Relationship
+}
+
+// decodeSBOM decodes the predicate of the statement target, or target itself
+// if it is not a statement.
+func decodeSBOM(target interface{}) (*decodedSBOM, error) {
+	doc := target
+	if statement, ok := target.(map[string]interface{}); ok && statement["predicate"] != nil {
+		doc = statement["predicate"]
+	}
+	dt, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	s, _, _, err := decoders.Decode(bytes.NewReader(dt))
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, fmt.Errorf("not an SPDX, CycloneDX or syft JSON document")
+	}
+	return newDecodedSBOM(s), nil
+}
+
+func newDecodedSBOM(s *sbom.SBOM) *decodedSBOM {
+	d := &decodedSBOM{files: map[artifact.ID]element{}, relationships: s.Relationships}
+	for _, p := range s.Artifacts.Packages.Sorted() {
+		d.packages = append(d.packages, element{id: p.ID(), view: packageView(p)})
+	}
+	for _, r := range s.Relationships {
+		for _, end := range []artifact.Identifiable{r.From, r.To} {
+			var path string
+			switch end := end.(type) {
+			case file.Coordinates:
+				path = end.RealPath
+			case file.Location:
+				path = end.RealPath
+			default:
+				continue
+			}
+			d.files[end.ID()] = element{id: end.ID(), view: map[string]interface{}{"file": "/" + strings.TrimPrefix(path, "/")}}
+		}
+	}
+	return d
+}
+
+// packageView returns the fields of p selectors match.
+func packageView(p pkg.Package) map[string]interface{} {
+	paths := []interface{}{}
+	for _, l := range p.Locations.ToSlice() {
+		paths = append(paths, l.RealPath)
+	}
+	licenses := []interface{}{}
+	for _, l := range p.Licenses.ToSlice() {
+		if l.SPDXExpression != "" {
+			licenses = append(licenses, l.SPDXExpression)
+		} else {
+			licenses = append(licenses, l.Value)
+		}
+	}
+	return map[string]interface{}{
+		"name":     p.Name,
+		"version":  p.Version,
+		"type":     string(p.Type),
+		"purl":     p.PURL,
+		"language": string(p.Language),
+		"paths":    paths,
+		"licenses": licenses,
+	}
+}
+
+// checkSBOM checks the assertions of schema on the SBOM of target:
+//
+//	"packages": [selector, ...]   every selector must match a package
+//	"absent": [selector, ...]     no selector may match a package
+//	"relationships": [{"from": selector, "type": "DEPENDS_ON", "to": selector}, ...]
+//	                              a relationship of the type must link
+//	                              elements matching the selectors
+//	"count": {"apk": 3, "all": {"$min": 1}}
+//	                              the number of packages of each type, or of
+//	                              all packages, must match
+func (c *checker) checkSBOM(schema interface{}, target interface{}, path string) Mismatches {
+	assertions, ok := schema.(map[string]interface{})
+	if !ok {
+		return invalid(path, sbomKey+" must be an object")
+	}
+	for _, k := range sortedKeys(assertions) {
+		switch k {
+		case "packages", "absent", "relationships", "count":
+		default:
+			return invalid(keyPath(path, k), fmt.Sprintf("unknown SBOM assertion %q", k))
+		}
+	}
+	d, err := decodeSBOM(target)
+	if err != nil {
+		return Mismatches{{Path: path, Kind: KindAssertion, Message: "expected an SBOM syft can decode: " + err.Error(), Actual: target}}
+	}
+
+	for _, k := range []string{"packages", "absent", "relationships"} {
+		if v, ok := assertions[k]; ok {
+			if _, ok := v.([]interface{}); !ok {
+				return invalid(path+"."+k, k+" must be an array")
+			}
+		}
+	}
+
+	var mismatches Mismatches
+	for i, selector := range asList(assertions["packages"]) {
+		p := fmt.Sprintf("%s.packages[%d]", path, i)
+		matched, m := c.selectPackages(selector, d, p)
+		if m != nil {
+			mismatches = append(mismatches, m...)
+			continue
+		}
+		if len(matched) == 0 {
+			mismatches = append(mismatches, Mismatch{Path: p, Kind: KindElement, Expected: selector, Message: "no package matches"})
+		}
+	}
+	for i, selector := range asList(assertions["absent"]) {
+		p := fmt.Sprintf("%s.absent[%d]", path, i)
+		matched, m := c.selectPackages(selector, d, p)
+		if m != nil {
+			mismatches = append(mismatches, m...)
+			continue
+		}
+		for _, e := range matched {
+			id := e.view["purl"]
+			if id == "" {
+				id = e.view["name"]
+			}
+			mismatches = append(mismatches, Mismatch{Path: p, Kind: KindAssertion, Message: "expected no package matching " + snippet(selector), Actual: id})
+		}
+	}
+	for i, rel := range asList(assertions["relationships"]) {
+		mismatches = append(mismatches, c.checkRelationship(rel, d, fmt.Sprintf("%s.relationships[%d]", path, i))...)
+	}
+	if counts, ok := assertions["count"]; ok {
+		counts, ok := counts.(map[string]interface{})
+		if !ok {
+			return invalid(path+".count", "count must be an object of package types")
+		}
+		for _, typ := range sortedKeys(counts) {
+			n := 0
+			for _, e := range d.packages {
+				if typ == "all" || e.view["type"] == typ {
+					n++
+				}
+			}
+			want := counts[typ]
+			if m := c.check(want, float64(n), keyPath(path+".count", typ)); len(m) > 0 {
+				mismatches = append(mismatches, Mismatch{
+					Path:    keyPath(path+".count", typ),
+					Kind:    KindLength,
+					Message: fmt.Sprintf("expected %s packages of type %s, got %d", snippet(want), typ, n),
+				})
+			}
+		}
+	}
+	return mismatches
+}
+
+// checkRelationship checks that a relationship of the SBOM links elements
+// matching the selectors of rel.
+func (c *checker) checkRelationship(rel interface{}, d *decodedSBOM, path string) Mismatches {
+	r, ok := rel.(map[string]interface{})
+	if !ok {
+		return invalid(path, "a relationship must be an object")
+	}
+	name, _ := r["type"].(string)
+	typ, ok := relationshipTypes[name]
+	if !ok {
+		names := make([]string, 0, len(relationshipTypes))
+		for n := range relationshipTypes {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return invalid(path+".type", fmt.Sprintf("unknown relationship type %q, must be one of %s", name, strings.Join(names, ", ")))
+	}
+
+	ends := map[string]map[artifact.ID]struct{}{}
+	var mismatches Mismatches
+	for _, k := range []string{"from", "to"} {
+		matched, m := c.selectElements(r[k], d, path+"."+k)
+		if m != nil {
+			return m
+		}
+		ids := map[artifact.ID]struct{}{}
+		for _, e := range matched {
+			ids[e.id] = struct{}{}
+		}
+		if len(ids) == 0 {
+			mismatches = append(mismatches, Mismatch{Path: path + "." + k, Kind: KindElement, Expected: r[k], Message: "no element matches"})
+		}
+		ends[k] = ids
+	}
+	if len(mismatches) > 0 {
+		return mismatches
+	}
+
+	from, to := ends["from"], ends["to"]
+	if typ.reversed {
+		from, to = to, from
+	}
+	for _, rel := range d.relationships {
+		if rel.Type != typ.typ || rel.From == nil || rel.To == nil {
+			continue
+		}
+		_, okFrom := from[rel.From.ID()]
+		_, okTo := to[rel.To.ID()]
+		if okFrom && okTo {
+			return nil
+		}
+	}
+	return Mismatches{{Path: path, Kind: KindElement, Expected: rel, Message: "no such relationship"}}
+}
+
+// selectPackages returns the packages of d matching selector. Package URLs
+// without a version match every version.
+func (c *checker) selectPackages(selector interface{}, d *decodedSBOM, path string) ([]element, Mismatches) {
+	sel, ok := selector.(map[string]interface{})
+	if !ok {
+		return nil, invalid(path, "a selector must be an object")
+	}
+	var matched []element
+	for _, e := range d.packages {
+		if c.matchSelector(sel, e, path) {
+			matched = append(matched, e)
+		}
+	}
+	return matched, nil
+}
+
+// selectElements returns the packages, or the files for a selector on
+// "file", of d matching selector.
+func (c *checker) selectElements(selector interface{}, d *decodedSBOM, path string) ([]element, Mismatches) {
+	sel, ok := selector.(map[string]interface{})
+	if !ok {
+		return nil, invalid(path, "a selector must be an object")
+	}
+	if _, ok := sel["file"]; !ok {
+		return c.selectPackages(sel, d, path)
+	}
+	var matched []element
+	for _, e := range d.files {
+		if c.matchSelector(sel, e, path) {
+			matched = append(matched, e)
+		}
+	}
+	return matched, nil
+}
+
+func (c *checker) matchSelector(sel map[string]interface{}, e element, path string) bool {
+	for k, v := range sel {
+		if purl, ok := v.(string); ok && k == "purl" && matchPURL(stringField(e.view, "purl"), purl) {
+			continue
+		}
+		if k == "file" {
+			if s, ok := v.(string); ok && !strings.HasPrefix(s, "~") && !strings.HasPrefix(s, "!~") && !strings.HasPrefix(s, "=") {
+				v = "/" + strings.TrimPrefix(s, "/")
+			}
+		}
+		actual, ok := e.view[k]
+		if !ok || len(c.check(v, actual, keyPath(path, k))) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func asList(v interface{}) []interface{} {
+	list, _ := v.([]interface{})
+	return list
+}
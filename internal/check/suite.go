@@ -0,0 +1,249 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest lists suites of schemas to check targets against.
+//
+//	suites:
+//	  - name: alpine
+//	    dir: alpine              # relative to the manifest
+//	    schemas: checks/*.json   # each checked against the file of the
+//	    targets: build           # same name in targets
+//	    checks:                  # and/or explicit pairs
+//	      - schema: checks/extra.json
+//	        target: build/sbom.spdx.json
+type Manifest struct {
+	Suites []Suite `yaml:"suites" json:"suites"`
+}
+
+// Suite is a set of checks sharing a Scope: variables assigned by a schema
+// can be compared by the schemas checked after it in the suite.
+type Suite struct {
+	Name string `yaml:"name" json:"name"`
+	// Dir is the directory paths of the suite are relative to, itself
+	// relative to the manifest.
+	Dir string `yaml:"dir" json:"dir"`
+	// Schemas is a glob of schemas, each checked against the file of the
+	// same name in the Targets directory.
+	Schemas string `yaml:"schemas" json:"schemas"`
+	Targets string `yaml:"targets" json:"targets"`
+	Checks  []Pair `yaml:"checks" json:"checks"`
+}
+
+// Pair is a schema and the target checked against it.
+type Pair struct {
+	Schema string `yaml:"schema" json:"schema"`
+	Target string `yaml:"target" json:"target"`
+}
+
+// ParseManifest parses a YAML or JSON manifest. Relative paths are resolved
+// from dir.
+func ParseManifest(dt []byte, isJSON bool, dir string) (*Manifest, error) {
+	var m Manifest
+	if isJSON {
+		dec := json.NewDecoder(bytes.NewReader(dt))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&m); err != nil {
+			return nil, err
+		}
+	} else if len(bytes.TrimSpace(dt)) > 0 {
+		dec := yaml.NewDecoder(bytes.NewReader(dt))
+		dec.KnownFields(true)
+		if err := dec.Decode(&m); err != nil {
+			return nil, err
+		}
+	}
+
+	names := map[string]struct{}{}
+	for i := range m.Suites {
+		s := &m.Suites[i]
+		if s.Name == "" {
+			return nil, fmt.Errorf("suite %d: name is required", i)
+		}
+		if _, ok := names[s.Name]; ok {
+			return nil, fmt.Errorf("suite %q defined more than once", s.Name)
+		}
+		names[s.Name] = struct{}{}
+		if (s.Schemas == "") != (s.Targets == "") {
+			return nil, fmt.Errorf("suite %q: schemas and targets must be set together", s.Name)
+		}
+		if s.Schemas == "" && len(s.Checks) == 0 {
+			return nil, fmt.Errorf("suite %q: no checks", s.Name)
+		}
+		for _, p := range s.Checks {
+			if p.Schema == "" || p.Target == "" {
+				return nil, fmt.Errorf("suite %q: checks need a schema and a target", s.Name)
+			}
+		}
+		if !filepath.IsAbs(s.Dir) {
+			s.Dir = filepath.Join(dir, s.Dir)
+		}
+	}
+	return &m, nil
+}
+
+// LoadManifest reads the manifest at p.
+func LoadManifest(p string) (*Manifest, error) {
+	dt, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ParseManifest(dt, strings.EqualFold(filepath.Ext(p), ".json"), filepath.Dir(p))
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest %q: %w", p, err)
+	}
+	return m, nil
+}
+
+// pairs returns the schemas and targets of s, globbed schemas first.
+func (s Suite) pairs() ([]Pair, error) {
+	var pairs []Pair
+	if s.Schemas != "" {
+		schemas, err := filepath.Glob(filepath.Join(s.Dir, s.Schemas))
+		if err != nil {
+			return nil, fmt.Errorf("suite %q: %w", s.Name, err)
+		}
+		if len(schemas) == 0 {
+			return nil, fmt.Errorf("suite %q: no schemas match %q", s.Name, s.Schemas)
+		}
+		sort.Strings(schemas)
+		for _, schema := range schemas {
+			pairs = append(pairs, Pair{Schema: schema, Target: filepath.Join(s.Dir, s.Targets, filepath.Base(schema))})
+		}
+	}
+	for _, p := range s.Checks {
+		pairs = append(pairs, Pair{Schema: filepath.Join(s.Dir, p.Schema), Target: filepath.Join(s.Dir, p.Target)})
+	}
+	return pairs, nil
+}
+
+// SuiteResult is the outcome of the checks of a suite.
+type SuiteResult struct {
+	Name    string
+	Results []Result
+	// Err is set if the checks of the suite could not be listed.
+	Err      error
+	Duration time.Duration
+}
+
+// Failed reports whether a check of the suite did not pass.
+func (r SuiteResult) Failed() bool {
+	if r.Err != nil {
+		return true
+	}
+	for _, res := range r.Results {
+		if !res.Passed() {
+			return true
+		}
+	}
+	return false
+}
+
+// Result is the outcome of checking a target against a schema.
+type Result struct {
+	// Name is the name of the schema, relative to the suite directory.
+	Name   string
+	Schema string
+	Target string
+	// Mismatches are the mismatches of the target.
+	Mismatches Mismatches
+	// Err is set if the schema or the target could not be read.
+	Err      error
+	Duration time.Duration
+}
+
+// Passed reports whether the target matched the schema.
+func (r Result) Passed() bool {
+	return r.Err == nil && len(r.Mismatches) == 0
+}
+
+// Run checks the suites of m whose name matches filter, if set.
+func (m *Manifest) Run(filter *regexp.Regexp) []SuiteResult {
+	var results []SuiteResult
+	for _, s := range m.Suites {
+		if filter != nil && !filter.MatchString(s.Name) {
+			continue
+		}
+		results = append(results, s.run())
+	}
+	return results
+}
+
+func (s Suite) run() SuiteResult {
+	start := time.Now()
+	result := SuiteResult{Name: s.Name}
+	pairs, err := s.pairs()
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	scope := NewScope()
+	for _, p := range pairs {
+		result.Results = append(result.Results, scope.run(s.Dir, p))
+	}
+	result.Duration = time.Since(start)
+	return result
+}
+
+func (s *Scope) run(dir string, p Pair) Result {
+	start := time.Now()
+	name, err := filepath.Rel(dir, p.Schema)
+	if err != nil {
+		name = p.Schema
+	}
+	result := Result{Name: filepath.ToSlash(name), Schema: p.Schema, Target: p.Target}
+	result.Mismatches, result.Err = s.checkFiles(p)
+	result.Duration = time.Since(start)
+	return result
+}
+
+func (s *Scope) checkFiles(p Pair) (Mismatches, error) {
+	var schema, target interface{}
+	if err := readJSON(p.Schema, &schema); err != nil {
+		return nil, err
+	}
+	if err := readJSON(p.Target, &target); err != nil {
+		return nil, err
+	}
+	if err := s.Check(schema, target); err != nil {
+		return err.(Mismatches), nil
+	}
+	return nil, nil
+}
+
+func readJSON(p string, v interface{}) error {
+	dt, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(dt, v); err != nil {
+		return fmt.Errorf("%s: %w", p, err)
+	}
+	return nil
+}
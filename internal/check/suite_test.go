@@ -0,0 +1,148 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSuite(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"suite.yaml": `suites:
+  - name: alpine
+    dir: alpine
+    schemas: checks/*.json
+    targets: build
+    checks:
+      - schema: extra/stage.json
+        target: build/a.json
+  - name: golang
+    dir: golang
+    schemas: checks/*.json
+    targets: build
+`,
+		// variables are shared by the checks of a suite, in order
+		"alpine/checks/a.json":     `{"name": "sbom", "id": "=id"}`,
+		"alpine/checks/b.json":     `{"id": "==id", "count": 2}`,
+		"alpine/extra/stage.json":  `{"id": "==id"}`,
+		"alpine/build/a.json":      `{"name": "sbom", "id": "1"}`,
+		"alpine/build/b.json":      `{"id": "1", "count": 3}`,
+		"golang/checks/sbom.json":  `{"name": "==id"}`,
+		"golang/checks/other.json": `{}`,
+		"golang/build/sbom.json":   `{"name": "1"}`,
+	})
+	m, err := LoadManifest(filepath.Join(dir, "suite.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := m.Run(nil)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 suite results, got %d", len(results))
+	}
+
+	var got []string
+	for _, suite := range results {
+		for _, r := range suite.Results {
+			status := "ok"
+			switch {
+			case r.Err != nil:
+				status = "error"
+			case len(r.Mismatches) > 0:
+				status = r.Mismatches[0].String()
+			}
+			got = append(got, suite.Name+"/"+r.Name+": "+status)
+		}
+	}
+	want := []string{
+		"alpine/checks/a.json: ok",
+		"alpine/checks/b.json: $.count: expected 2, got 3",
+		"alpine/extra/stage.json: ok",
+		"golang/checks/other.json: error",
+		"golang/checks/sbom.json: $.name: variable id not found",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected results:\n%s\nexpected:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	var junit bytes.Buffer
+	if err := WriteResults(&junit, OutputJUnit, results); err != nil {
+		t.Fatal(err)
+	}
+	var doc junitSuites
+	if err := xml.Unmarshal(junit.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JUnit XML: %v\n%s", err, junit.String())
+	}
+	if len(doc.Suites) != 2 || doc.Suites[0].Tests != 3 || doc.Suites[0].Failures != 1 || doc.Suites[1].Errors != 1 {
+		t.Errorf("unexpected JUnit suites %+v", doc.Suites)
+	}
+	if f := doc.Suites[0].Cases[1].Failure; f == nil || f.Message != "$.count: expected 2, got 3" || !strings.Contains(f.Body, "value mismatch (1):") {
+		t.Errorf("unexpected JUnit failure %+v", f)
+	}
+
+	var tap bytes.Buffer
+	if err := WriteResults(&tap, OutputTAP, results); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"TAP version 13\n1..5\n",
+		"ok 1 - alpine/checks/a.json\n",
+		"not ok 2 - alpine/checks/b.json\n  ---\n  message: \"1 mismatch\"\n  mismatches:\n    - path: \"$.count\"\n      kind: \"value mismatch\"\n      detail: \"expected 2, got 3\"\n  ...\n",
+		"not ok 4 - golang/checks/other.json\n",
+	} {
+		if !strings.Contains(tap.String(), line) {
+			t.Errorf("expected TAP output to contain %q:\n%s", line, tap.String())
+		}
+	}
+
+	filtered := m.Run(regexp.MustCompile("^golang$"))
+	if len(filtered) != 1 || filtered[0].Name != "golang" {
+		t.Errorf("expected only the golang suite to run, got %+v", filtered)
+	}
+}
+
+func TestParseManifestErrors(t *testing.T) {
+	for name, manifest := range map[string]string{
+		"unknown":   "suites:\n  - name: a\n    schema: x\n",
+		"name":      "suites:\n  - checks: [{schema: a, target: b}]\n",
+		"duplicate": "suites:\n  - {name: a, checks: [{schema: a, target: b}]}\n  - {name: a, checks: [{schema: a, target: b}]}\n",
+		"targets":   "suites:\n  - {name: a, schemas: '*.json'}\n",
+		"empty":     "suites:\n  - {name: a}\n",
+		"pair":      "suites:\n  - {name: a, checks: [{schema: a}]}\n",
+	} {
+		if _, err := ParseManifest([]byte(manifest), false, "."); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
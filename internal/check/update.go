@@ -0,0 +1,468 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Update returns schema updated from statement, an in-toto statement of an
+// SBOM, for the packages and files it asserts on along with sel.
+//
+// Only what Generate would write is refreshed: the elements exactly as
+// generated, e.g. a package asserted on by name and identifier alone, are
+// generated again, and the elements of sel and the relationships between
+// them are added. Everything else is kept as written, such as the subject,
+// "$sbom" assertions, matchers, pinned versions and the names of variables,
+// which the added relationships reference.
+func Update(schema map[string]interface{}, statement map[string]interface{}, sel Selection) (map[string]interface{}, error) {
+	doc, ok := statement["predicate"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("not an in-toto statement, no predicate found")
+	}
+	f, err := detectFormat(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	result := clone(schema).(map[string]interface{})
+	if result["predicate"] == nil {
+		result["predicate"] = map[string]interface{}{}
+	}
+	predicate, ok := result["predicate"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the predicate of the schema must be an object")
+	}
+	if sf, err := detectSchemaFormat(predicate); err == nil && sf.packages != f.packages {
+		return nil, fmt.Errorf("the schema is for another SBOM format than the statement")
+	}
+
+	lists := []string{f.packages}
+	if f.files != "" {
+		lists = append(lists, f.files)
+	}
+	var selection Selection
+	for _, l := range lists {
+		for _, elem := range asObjects(predicate[l]) {
+			if f.isFileElement(l, elem) {
+				selection.Paths = appendLiteral(selection.Paths, f.path(elem))
+				continue
+			}
+			purl := f.purl(elem)
+			switch {
+			case strings.HasPrefix(purl, "pkg:") && f.generated(l, elem):
+				// generated again for the current version
+				selection.Packages = append(selection.Packages, purlBase(purl))
+			case strings.HasPrefix(purl, "pkg:"):
+				selection.Packages = append(selection.Packages, purl)
+			default:
+				selection.Packages = appendLiteral(selection.Packages, stringField(elem, "name"))
+			}
+		}
+	}
+	selection.Packages = append(selection.Packages, sel.Packages...)
+	selection.Paths = append(selection.Paths, sel.Paths...)
+	generated, err := Generate(statement, selection)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range generated {
+		if _, ok := result[k]; !ok && k != "predicate" {
+			result[k] = v
+		}
+	}
+	generatedPredicate := generated["predicate"].(map[string]interface{})
+	for _, k := range f.header {
+		if _, ok := predicate[k]; !ok && generatedPredicate[k] != nil {
+			predicate[k] = generatedPredicate[k]
+		}
+	}
+
+	// rename maps the variables of the generated elements to the ones of the
+	// schema, and unbound lists those matching elements without one
+	defined := map[string]struct{}{}
+	definedVariables(schema, defined)
+	rename := map[string]string{}
+	unbound := map[string]struct{}{}
+	for _, l := range lists {
+		elems, _ := predicate[l].([]interface{})
+		matched := make([]bool, len(elems))
+		for _, gen := range asObjects(generatedPredicate[l]) {
+			genVar := strings.TrimPrefix(stringField(gen, f.id), "=")
+			id := f.identity(l, gen)
+			i := -1
+			for j, elem := range elems {
+				if obj, ok := elem.(map[string]interface{}); ok && !matched[j] && id != "" && f.identity(l, obj) == id {
+					i = j
+					break
+				}
+			}
+			if i < 0 {
+				if genVar != "" {
+					name := genVar
+					for n := 2; ; n++ {
+						if _, ok := defined[name]; !ok {
+							break
+						}
+						name = fmt.Sprintf("%s#%d", genVar, n)
+					}
+					defined[name] = struct{}{}
+					rename[genVar] = name
+					gen[f.id] = "=" + name
+				}
+				elems = append(elems, gen)
+				continue
+			}
+
+			matched[i] = true
+			elem := elems[i].(map[string]interface{})
+			if v, ok := elem[f.id].(string); ok && isDefinition(v) {
+				rename[genVar] = v[1:]
+			} else {
+				unbound[genVar] = struct{}{}
+			}
+			if f.generated(l, elem) {
+				if v, ok := elem[f.id]; ok {
+					gen[f.id] = v
+				} else {
+					delete(gen, f.id)
+				}
+				elems[i] = gen
+			}
+		}
+		if len(elems) > 0 {
+			predicate[l] = elems
+		}
+	}
+
+	relationships, _ := predicate[f.relationships].([]interface{})
+	for _, rel := range asObjects(generatedPredicate[f.relationships]) {
+		rel, ok := renameRefs(f, rel, rename, unbound)
+		if !ok {
+			continue
+		}
+		exists := false
+		for _, r := range relationships {
+			if reflect.DeepEqual(r, rel) {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			relationships = append(relationships, rel)
+		}
+	}
+	if len(relationships) > 0 {
+		predicate[f.relationships] = relationships
+	}
+	return result, nil
+}
+
+// isFileElement reports whether elem of the list l is a file.
+func (f sbomFormat) isFileElement(l string, elem map[string]interface{}) bool {
+	return l == f.files || f.isFile != nil && f.isFile(elem)
+}
+
+// identity returns what elem of the list l asserts on, the package URL
+// without version of a package, its name, or the path of a file, or an
+// empty string for an element matched through patterns or variables.
+func (f sbomFormat) identity(l string, elem map[string]interface{}) string {
+	if f.isFileElement(l, elem) {
+		if path := f.path(elem); isLiteral(path) {
+			return "file:" + strings.TrimPrefix(path, "/")
+		}
+		return ""
+	}
+	if purl := f.purl(elem); strings.HasPrefix(purl, "pkg:") {
+		return "package:" + purlBase(purl)
+	}
+	if name := stringField(elem, "name"); isLiteral(name) {
+		return "package:" + name
+	}
+	return ""
+}
+
+// generated reports whether elem of the list l is written as Generate
+// writes it, so that it can be generated again.
+func (f sbomFormat) generated(l string, elem map[string]interface{}) bool {
+	var schema map[string]interface{}
+	if f.isFileElement(l, elem) {
+		path := f.path(elem)
+		if !isLiteral(path) {
+			return false
+		}
+		schema = f.file(path)
+	} else {
+		name := stringField(elem, "name")
+		if !isLiteral(name) {
+			return false
+		}
+		schema = map[string]interface{}{"name": name}
+		if purl := f.purl(elem); purl != "" {
+			if !strings.HasPrefix(purl, "pkg:") {
+				return false
+			}
+			f.withPURL(schema, purl)
+		}
+	}
+	if v, ok := elem[f.id]; ok {
+		if s, ok := v.(string); !ok || !isDefinition(s) {
+			return false
+		}
+		schema[f.id] = v
+	}
+	return reflect.DeepEqual(schema, elem)
+}
+
+// renameRefs returns the generated relationship rel referencing the
+// variables of the schema, and false if it references an element the schema
+// has no variable for.
+func renameRefs(f sbomFormat, rel map[string]interface{}, rename map[string]string, unbound map[string]struct{}) (map[string]interface{}, bool) {
+	ref := func(v interface{}) (string, bool) {
+		s, _ := v.(string)
+		key := strings.TrimPrefix(s, "==")
+		if _, ok := unbound[key]; ok {
+			return "", false
+		}
+		name, ok := rename[key]
+		return "==" + name, ok
+	}
+	out := map[string]interface{}{}
+	for k, v := range rel {
+		out[k] = v
+	}
+	for _, k := range f.refs {
+		switch v := rel[k].(type) {
+		case string:
+			r, ok := ref(v)
+			if !ok {
+				return nil, false
+			}
+			out[k] = r
+		case []interface{}:
+			var refs []interface{}
+			for _, id := range v {
+				if r, ok := ref(id); ok {
+					refs = append(refs, r)
+				}
+			}
+			if len(refs) == 0 {
+				return nil, false
+			}
+			out[k] = refs
+		}
+	}
+	return out, true
+}
+
+// definedVariables adds the variables assigned in schema to names.
+func definedVariables(schema interface{}, names map[string]struct{}) {
+	switch v := schema.(type) {
+	case string:
+		if isDefinition(v) {
+			names[v[1:]] = struct{}{}
+		}
+	case map[string]interface{}:
+		for _, v := range v {
+			definedVariables(v, names)
+		}
+	case []interface{}:
+		for _, v := range v {
+			definedVariables(v, names)
+		}
+	}
+}
+
+// isDefinition reports whether s assigns a variable.
+func isDefinition(s string) bool {
+	return strings.HasPrefix(s, "=") && !strings.HasPrefix(s, "==")
+}
+
+// purlBase returns purl without its version, qualifiers and subpath.
+func purlBase(purl string) string {
+	if i := strings.IndexAny(purl, "@?#"); i >= 0 {
+		return purl[:i]
+	}
+	return purl
+}
+
+func clone(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, v := range v {
+			out[k] = clone(v)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, v := range v {
+			out[i] = clone(v)
+		}
+		return out
+	}
+	return v
+}
+
+// MarshalSchema encodes schema as indented JSON. The keys of the objects
+// found in previous, the JSON document schema was updated from, keep their
+// order there, and the others are sorted.
+func MarshalSchema(schema map[string]interface{}, previous []byte) ([]byte, error) {
+	var order *keyOrder
+	if previous != nil {
+		var err error
+		if order, err = readKeyOrder(json.NewDecoder(bytes.NewReader(previous))); err != nil {
+			return nil, err
+		}
+	}
+	var compact bytes.Buffer
+	if err := encodeOrdered(&compact, schema, order); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, compact.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// keyOrder is the order of the keys of a JSON object, and of the objects
+// it contains.
+type keyOrder struct {
+	keys   []string
+	fields map[string]*keyOrder
+	items  []*keyOrder
+}
+
+func (o *keyOrder) field(k string) *keyOrder {
+	if o == nil {
+		return nil
+	}
+	return o.fields[k]
+}
+
+func (o *keyOrder) item(i int) *keyOrder {
+	if o == nil || i >= len(o.items) {
+		return nil
+	}
+	return o.items[i]
+}
+
+func readKeyOrder(dec *json.Decoder) (*keyOrder, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		o := &keyOrder{fields: map[string]*keyOrder{}}
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			k, _ := tok.(string)
+			child, err := readKeyOrder(dec)
+			if err != nil {
+				return nil, err
+			}
+			o.keys = append(o.keys, k)
+			o.fields[k] = child
+		}
+		_, err := dec.Token()
+		return o, err
+	case json.Delim('['):
+		o := &keyOrder{}
+		for dec.More() {
+			child, err := readKeyOrder(dec)
+			if err != nil {
+				return nil, err
+			}
+			o.items = append(o.items, child)
+		}
+		_, err := dec.Token()
+		return o, err
+	}
+	return nil, nil
+}
+
+func encodeOrdered(buf *bytes.Buffer, v interface{}, o *keyOrder) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		var keys []string
+		seen := map[string]struct{}{}
+		if o != nil {
+			for _, k := range o.keys {
+				if _, ok := v[k]; ok {
+					keys = append(keys, k)
+					seen[k] = struct{}{}
+				}
+			}
+		}
+		var rest []string
+		for k := range v {
+			if _, ok := seen[k]; !ok {
+				rest = append(rest, k)
+			}
+		}
+		sort.Strings(rest)
+		buf.WriteByte('{')
+		for i, k := range append(keys, rest...) {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeValue(buf, k); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := encodeOrdered(buf, v[k], o.field(k)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeOrdered(buf, item, o.item(i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		return encodeValue(buf, v)
+	}
+	return nil
+}
+
+func encodeValue(buf *bytes.Buffer, v interface{}) error {
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	buf.Write(bytes.TrimSuffix(out.Bytes(), []byte("\n")))
+	return nil
+}
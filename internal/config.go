@@ -0,0 +1,357 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/anchore/syft/syft"
+	"github.com/anchore/syft/syft/cataloging"
+	"github.com/anchore/syft/syft/cataloging/filecataloging"
+	"github.com/anchore/syft/syft/cataloging/pkgcataloging"
+	"github.com/anchore/syft/syft/file"
+	"github.com/anchore/syft/syft/file/cataloger/executable"
+	"github.com/anchore/syft/syft/file/cataloger/filecontent"
+	"github.com/anchore/syft/syft/pkg/cataloger/cpp"
+	"github.com/anchore/syft/syft/pkg/cataloger/dotnet"
+	"github.com/anchore/syft/syft/pkg/cataloger/golang"
+	"github.com/anchore/syft/syft/pkg/cataloger/java"
+	"github.com/anchore/syft/syft/pkg/cataloger/javascript"
+	"github.com/anchore/syft/syft/pkg/cataloger/kernel"
+	"github.com/anchore/syft/syft/pkg/cataloger/nix"
+	"github.com/anchore/syft/syft/pkg/cataloger/python"
+	"github.com/anchore/syft/syft/source"
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigPaths are the locations, relative to the root of the scanned
+// filesystem, where a configuration file is looked up when none is
+// explicitly requested.
+var DefaultConfigPaths = []string{
+	"/etc/buildkit-syft-scanner.yaml",
+	"/etc/buildkit-syft-scanner.yml",
+	"/etc/buildkit-syft-scanner.json",
+}
+
+// Config is the scanner configuration file, which maps onto syft's
+// CreateSBOMConfig. Fields that are not set in the file keep syft's defaults.
+type Config struct {
+	Scope            string                          `yaml:"scope" json:"scope"`
+	Exclude          []string                        `yaml:"exclude" json:"exclude"`
+	Parallelism      int                             `yaml:"parallelism" json:"parallelism"`
+	SelectCatalogers []string                        `yaml:"select-catalogers" json:"select-catalogers"`
+	License          cataloging.LicenseConfig        `yaml:"license" json:"license"`
+	Compliance       cataloging.ComplianceConfig     `yaml:"compliance" json:"compliance"`
+	Relationships    cataloging.RelationshipsConfig  `yaml:"relationships" json:"relationships"`
+	Unknowns         UnknownsConfig                  `yaml:"unknowns" json:"unknowns"`
+	DataGeneration   cataloging.DataGenerationConfig `yaml:"data-generation" json:"data-generation"`
+	File             FileConfig                      `yaml:"file" json:"file"`
+	Packages         PackagesConfig                  `yaml:"packages" json:"packages"`
+}
+
+// UnknownsConfig mirrors cataloging.UnknownsConfig, which has no
+// serialization tags.
+type UnknownsConfig struct {
+	RemoveWhenPackagesDefined         bool `yaml:"remove-when-packages-defined" json:"remove-when-packages-defined"`
+	IncludeExecutablesWithoutPackages bool `yaml:"executables-without-packages" json:"executables-without-packages"`
+	IncludeUnexpandedArchives         bool `yaml:"unexpanded-archives" json:"unexpanded-archives"`
+}
+
+// FileConfig mirrors filecataloging.Config, with hashers named by string.
+type FileConfig struct {
+	Selection  file.Selection     `yaml:"selection" json:"selection"`
+	Hashers    []string           `yaml:"hashers" json:"hashers"`
+	Content    filecontent.Config `yaml:"content" json:"content"`
+	Executable executable.Config  `yaml:"executable" json:"executable"`
+}
+
+// PackagesConfig holds the per-ecosystem cataloger configs. The binary
+// classifiers are not configurable, since they are defined in code.
+type PackagesConfig struct {
+	Cpp         cpp.CatalogerConfig               `yaml:"cpp" json:"cpp"`
+	Dotnet      dotnet.CatalogerConfig            `yaml:"dotnet" json:"dotnet"`
+	Golang      golang.CatalogerConfig            `yaml:"golang" json:"golang"`
+	JavaArchive java.ArchiveCatalogerConfig       `yaml:"java-archive" json:"java-archive"`
+	JavaScript  javascript.CatalogerConfig        `yaml:"javascript" json:"javascript"`
+	LinuxKernel kernel.LinuxKernelCatalogerConfig `yaml:"linux-kernel" json:"linux-kernel"`
+	Nix         nix.Config                        `yaml:"nix" json:"nix"`
+	Python      python.CatalogerConfig            `yaml:"python" json:"python"`
+}
+
+// DefaultExcludes are the paths excluded from scans when the configuration
+// does not list any: virtual filesystems and package manager caches, which
+// never hold installed packages.
+var DefaultExcludes = []string{
+	"/proc",
+	"/sys",
+	"/dev",
+	"/var/cache/apk",
+	"/var/cache/apt",
+	"/var/lib/apt/lists",
+	"/var/cache/dnf",
+	"/var/cache/yum",
+	"/var/cache/zypp",
+	"/root/.cache",
+	"/root/.npm",
+	"/home/*/.cache",
+	"/home/*/.npm",
+}
+
+var hashers = map[string]crypto.Hash{
+	"md5":    crypto.MD5,
+	"sha1":   crypto.SHA1,
+	"sha224": crypto.SHA224,
+	"sha256": crypto.SHA256,
+	"sha384": crypto.SHA384,
+	"sha512": crypto.SHA512,
+}
+
+// DefaultConfig returns the configuration used when no configuration file is
+// found, which matches syft.DefaultCreateSBOMConfig.
+func DefaultConfig() Config {
+	d := syft.DefaultCreateSBOMConfig()
+
+	var hasherNames []string
+	for _, h := range d.Files.Hashers {
+		for name, h2 := range hashers {
+			if h == h2 {
+				hasherNames = append(hasherNames, name)
+			}
+		}
+	}
+
+	return Config{
+		Scope:          d.Search.Scope.String(),
+		Exclude:        append([]string(nil), DefaultExcludes...),
+		Parallelism:    d.Parallelism,
+		License:        d.Licenses,
+		Compliance:     d.Compliance,
+		Relationships:  d.Relationships,
+		Unknowns:       UnknownsConfig(d.Unknowns),
+		DataGeneration: d.DataGeneration,
+		File: FileConfig{
+			Selection:  d.Files.Selection,
+			Hashers:    hasherNames,
+			Content:    d.Files.Content,
+			Executable: d.Files.Executable,
+		},
+		Packages: PackagesConfig{
+			Cpp:         d.Packages.Cpp,
+			Dotnet:      d.Packages.Dotnet,
+			Golang:      d.Packages.Golang,
+			JavaArchive: d.Packages.JavaArchive,
+			JavaScript:  d.Packages.JavaScript,
+			LinuxKernel: d.Packages.LinuxKernel,
+			Nix:         d.Packages.Nix,
+			Python:      d.Packages.Python,
+		},
+	}
+}
+
+// ParseConfig decodes a YAML or JSON configuration file on top of
+// DefaultConfig, and validates the result.
+func ParseConfig(dt []byte, isJSON bool) (*Config, error) {
+	cfg := DefaultConfig()
+	if isJSON {
+		dec := json.NewDecoder(bytes.NewReader(dt))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&cfg); err != nil {
+			return nil, err
+		}
+	} else if len(bytes.TrimSpace(dt)) > 0 {
+		dec := yaml.NewDecoder(bytes.NewReader(dt))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// LoadConfig reads the configuration file at name inside the filesystem
+// rooted at root. If name is empty, the DefaultConfigPaths are tried, and
+// DefaultConfig is used if none of them exist.
+func LoadConfig(root string, name string) (*Config, error) {
+	paths := DefaultConfigPaths
+	if name != "" {
+		paths = []string{name}
+	}
+	for _, p := range paths {
+		dt, err := os.ReadFile(filepath.Join(root, filepath.Clean("/"+p)))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) && name == "" {
+				continue
+			}
+			return nil, errors.Wrapf(err, "failed to read config %q", p)
+		}
+		cfg, err := ParseConfig(dt, strings.EqualFold(filepath.Ext(p), ".json"))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid config %q", p)
+		}
+		return cfg, nil
+	}
+	cfg := DefaultConfig()
+	return &cfg, nil
+}
+
+// Validate checks the values that syft would otherwise silently ignore or
+// reinterpret.
+func (c Config) Validate() error {
+	if source.ParseScope(c.Scope) == source.UnknownScope {
+		return errors.Errorf("scope: unknown scope %q", c.Scope)
+	}
+	for _, p := range c.Exclude {
+		if p == "" || !doublestar.ValidatePattern(excludeGlob(p)) {
+			return errors.Errorf("exclude: invalid glob %q", p)
+		}
+	}
+	if c.Parallelism < 0 {
+		return errors.Errorf("parallelism: must not be negative, got %d", c.Parallelism)
+	}
+
+	switch c.License.IncludeContent {
+	case cataloging.LicenseContentIncludeAll, cataloging.LicenseContentIncludeUnknown, cataloging.LicenseContentExcludeAll:
+	default:
+		return errors.Errorf("license.include-content: must be one of all, unknown, none, got %q", c.License.IncludeContent)
+	}
+	if c.License.Coverage < 0 || c.License.Coverage > 100 {
+		return errors.Errorf("license.coverage: must be between 0 and 100, got %v", c.License.Coverage)
+	}
+
+	for name, action := range map[string]cataloging.ComplianceAction{
+		"compliance.missing-name":    c.Compliance.MissingName,
+		"compliance.missing-version": c.Compliance.MissingVersion,
+	} {
+		switch strings.ToLower(string(action)) {
+		case "keep", "include", "drop", "exclude", "stub", "replace":
+		default:
+			return errors.Errorf("%s: must be one of keep, drop, stub, got %q", name, action)
+		}
+	}
+
+	switch c.File.Selection {
+	case file.NoFilesSelection, file.FilesOwnedByPackageSelection, file.AllFilesSelection:
+	default:
+		return errors.Errorf("file.selection: must be one of none, owned-by-package, all, got %q", c.File.Selection)
+	}
+	for _, h := range c.File.Hashers {
+		if _, ok := hashers[strings.ToLower(h)]; !ok {
+			var names []string
+			for name := range hashers {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return errors.Errorf("file.hashers: unknown hasher %q, must be one of %s", h, strings.Join(names, ", "))
+		}
+	}
+	if c.File.Content.SkipFilesAboveSize < 0 {
+		return errors.Errorf("file.content.skip-files-above-size: must not be negative, got %d", c.File.Content.SkipFilesAboveSize)
+	}
+	return nil
+}
+
+// ExcludeConfig returns the exclusions as syft expects them for directory
+// sources.
+func (c Config) ExcludeConfig() source.ExcludeConfig {
+	// syft rewrites the paths in place, so they must not alias c.Exclude
+	paths := make([]string, 0, len(c.Exclude))
+	for _, p := range c.Exclude {
+		paths = append(paths, excludeGlob(p))
+	}
+	return source.ExcludeConfig{Paths: paths}
+}
+
+// excludeGlob converts an exclusion, given as a glob on paths from the root
+// of the scanned filesystem, into syft's relative form: "/usr/share/doc" and
+// "usr/share/doc" both become "./usr/share/doc", while globs starting with
+// "*/" or "**/" match at any depth and are kept as-is.
+func excludeGlob(p string) string {
+	p = strings.TrimSuffix(p, "/")
+	switch {
+	case strings.HasPrefix(p, "./"), strings.HasPrefix(p, "*/"), strings.HasPrefix(p, "**/"):
+		return p
+	default:
+		return "./" + strings.TrimLeft(p, "/")
+	}
+}
+
+// isExcluded reports whether p, relative to the root of the scanned
+// filesystem, matches one of the normalized globs, as syft matches them.
+func isExcluded(globs []string, p string) bool {
+	for _, g := range globs {
+		if ok, _ := doublestar.Match("/"+strings.TrimPrefix(g, "./"), "/"+p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateSBOMConfig maps the configuration onto syft's CreateSBOMConfig.
+func (c Config) CreateSBOMConfig() *syft.CreateSBOMConfig {
+	var fileHashers []crypto.Hash
+	for _, h := range c.File.Hashers {
+		fileHashers = append(fileHashers, hashers[strings.ToLower(h)])
+	}
+
+	packages := pkgcataloging.DefaultConfig()
+	packages.Cpp = c.Packages.Cpp
+	packages.Dotnet = c.Packages.Dotnet
+	packages.Golang = c.Packages.Golang
+	packages.JavaArchive = c.Packages.JavaArchive
+	packages.JavaScript = c.Packages.JavaScript
+	packages.LinuxKernel = c.Packages.LinuxKernel
+	packages.Nix = c.Packages.Nix
+	packages.Python = c.Packages.Python
+
+	sr := pkgcataloging.NewSelectionRequest().
+		WithDefaults(
+			pkgcataloging.ImageTag,
+			filecataloging.FileTag, // https://github.com/anchore/syft/pull/3505
+		).
+		WithAdditions(
+			"sbom-cataloger",
+		)
+	if len(c.SelectCatalogers) > 0 {
+		sr = pkgcataloging.NewSelectionRequest().WithExpression(c.SelectCatalogers...)
+	}
+
+	return syft.DefaultCreateSBOMConfig().
+		WithSearchConfig(cataloging.SearchConfig{Scope: source.ParseScope(c.Scope)}).
+		WithParallelism(c.Parallelism).
+		WithLicenseConfig(c.License).
+		WithComplianceConfig(c.Compliance.Parse()).
+		WithRelationshipsConfig(c.Relationships).
+		WithUnknownsConfig(cataloging.UnknownsConfig(c.Unknowns)).
+		WithDataGenerationConfig(c.DataGeneration).
+		WithFilesConfig(filecataloging.Config{
+			Selection:  c.File.Selection,
+			Hashers:    fileHashers,
+			Content:    c.File.Content,
+			Executable: c.File.Executable,
+		}).
+		WithPackagesConfig(packages).
+		WithCatalogerSelection(sr)
+}
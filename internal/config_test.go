@@ -0,0 +1,236 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"crypto"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/anchore/syft/syft"
+	"github.com/anchore/syft/syft/cataloging"
+	"github.com/anchore/syft/syft/file"
+	"github.com/anchore/syft/syft/source"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	want := syft.DefaultCreateSBOMConfig()
+	got := DefaultConfig().CreateSBOMConfig()
+
+	if got.Search != want.Search {
+		t.Errorf("search: expected %+v, got %+v", want.Search, got.Search)
+	}
+	if got.Licenses != want.Licenses {
+		t.Errorf("licenses: expected %+v, got %+v", want.Licenses, got.Licenses)
+	}
+	if got.Compliance != want.Compliance {
+		t.Errorf("compliance: expected %+v, got %+v", want.Compliance, got.Compliance)
+	}
+	if got.Relationships != want.Relationships {
+		t.Errorf("relationships: expected %+v, got %+v", want.Relationships, got.Relationships)
+	}
+	if got.Unknowns != want.Unknowns {
+		t.Errorf("unknowns: expected %+v, got %+v", want.Unknowns, got.Unknowns)
+	}
+	if !reflect.DeepEqual(got.Files, want.Files) {
+		t.Errorf("files: expected %+v, got %+v", want.Files, got.Files)
+	}
+	if !reflect.DeepEqual(got.Packages.Golang, want.Packages.Golang) {
+		t.Errorf("packages.golang: expected %+v, got %+v", want.Packages.Golang, got.Packages.Golang)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	const yamlConfig = `
+scope: all-layers
+exclude: [/usr/share/doc, "**/testdata"]
+parallelism: 2
+select-catalogers: [+javascript-lock-cataloger]
+license:
+  include-content: all
+compliance:
+  missing-version: drop
+unknowns:
+  unexpanded-archives: true
+data-generation:
+  generate-cpes: false
+file:
+  selection: all
+  hashers: [sha1, SHA512]
+packages:
+  javascript:
+    include-dev-dependencies: true
+  java-archive:
+    include-unindexed-archives: true
+`
+	const jsonConfig = `{
+  "scope": "all-layers",
+  "exclude": ["/usr/share/doc", "**/testdata"],
+  "parallelism": 2,
+  "select-catalogers": ["+javascript-lock-cataloger"],
+  "license": {"include-content": "all"},
+  "compliance": {"missing-version": "drop"},
+  "unknowns": {"unexpanded-archives": true},
+  "data-generation": {"generate-cpes": false},
+  "file": {"selection": "all", "hashers": ["sha1", "SHA512"]},
+  "packages": {
+    "javascript": {"include-dev-dependencies": true},
+    "java-archive": {"include-unindexed-archives": true}
+  }
+}`
+
+	for name, tc := range map[string]struct {
+		dt     string
+		isJSON bool
+	}{
+		"yaml": {dt: yamlConfig},
+		"json": {dt: jsonConfig, isJSON: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := ParseConfig([]byte(tc.dt), tc.isJSON)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := cfg.CreateSBOMConfig()
+
+			if c.Search.Scope != source.AllLayersScope {
+				t.Errorf("unexpected scope %q", c.Search.Scope)
+			}
+			if paths := cfg.ExcludeConfig().Paths; !reflect.DeepEqual(paths, []string{"./usr/share/doc", "**/testdata"}) {
+				t.Errorf("unexpected excludes %v", paths)
+			}
+			if c.Parallelism != 2 {
+				t.Errorf("unexpected parallelism %d", c.Parallelism)
+			}
+			if !reflect.DeepEqual(c.CatalogerSelection.DefaultNamesOrTags, []string(nil)) || !reflect.DeepEqual(c.CatalogerSelection.AddNames, []string{"javascript-lock-cataloger"}) {
+				t.Errorf("unexpected cataloger selection %+v", c.CatalogerSelection)
+			}
+			if c.Licenses.IncludeContent != cataloging.LicenseContentIncludeAll {
+				t.Errorf("unexpected license content %q", c.Licenses.IncludeContent)
+			}
+			if c.Compliance.MissingVersion != cataloging.ComplianceActionDrop || c.Compliance.MissingName != cataloging.ComplianceActionDrop {
+				t.Errorf("unexpected compliance %+v", c.Compliance)
+			}
+			if !c.Unknowns.IncludeUnexpandedArchives {
+				t.Errorf("unexpected unknowns %+v", c.Unknowns)
+			}
+			if c.DataGeneration.GenerateCPEs {
+				t.Errorf("unexpected data generation %+v", c.DataGeneration)
+			}
+			if c.Files.Selection != file.AllFilesSelection || !reflect.DeepEqual(c.Files.Hashers, []crypto.Hash{crypto.SHA1, crypto.SHA512}) {
+				t.Errorf("unexpected files %+v", c.Files)
+			}
+			if !c.Packages.JavaScript.IncludeDevDependencies {
+				t.Errorf("unexpected javascript config %+v", c.Packages.JavaScript)
+			}
+			// unset fields in a section keep their defaults
+			if !c.Packages.JavaArchive.IncludeUnindexedArchives || !c.Packages.JavaArchive.IncludeIndexedArchives {
+				t.Errorf("unexpected java config %+v", c.Packages.JavaArchive)
+			}
+		})
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		dt      string
+		isJSON  bool
+		wantErr string
+	}{
+		{name: "unknown field", dt: "scopes: squashed", wantErr: "field scopes not found"},
+		{name: "unknown json field", dt: `{"scopes": "squashed"}`, isJSON: true, wantErr: `unknown field "scopes"`},
+		{name: "scope", dt: "scope: everything", wantErr: `scope: unknown scope "everything"`},
+		{name: "exclude", dt: `exclude: ["/usr/[share"]`, wantErr: `exclude: invalid glob "/usr/[share"`},
+		{name: "parallelism", dt: "parallelism: -1", wantErr: "parallelism: must not be negative"},
+		{name: "license content", dt: "license: {include-content: some}", wantErr: "license.include-content"},
+		{name: "license coverage", dt: "license: {coverage: 101}", wantErr: "license.coverage"},
+		{name: "compliance", dt: "compliance: {missing-name: ignore}", wantErr: "compliance.missing-name"},
+		{name: "file selection", dt: "file: {selection: some}", wantErr: "file.selection"},
+		{name: "hashers", dt: "file: {hashers: [crc32]}", wantErr: `file.hashers: unknown hasher "crc32"`},
+		{name: "type", dt: "parallelism: lots", wantErr: "cannot unmarshal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(tt.dt), tt.isJSON)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestExcludeGlob(t *testing.T) {
+	for in, want := range map[string]string{
+		"/proc":             "./proc",
+		"/home/*/.cache/":   "./home/*/.cache",
+		"usr/share/doc":     "./usr/share/doc",
+		"./usr/share/doc":   "./usr/share/doc",
+		"*/testdata":        "*/testdata",
+		"**/node_modules/x": "**/node_modules/x",
+	} {
+		if got := excludeGlob(in); got != want {
+			t.Errorf("%s: expected %s, got %s", in, want, got)
+		}
+	}
+
+	// syft rewrites the exclusions it is given in place
+	cfg := DefaultConfig()
+	cfg.ExcludeConfig().Paths[0] = "changed"
+	if cfg.Exclude[0] != DefaultExcludes[0] {
+		t.Errorf("expected config to be left untouched, got %v", cfg.Exclude)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	root := t.TempDir()
+
+	cfg, err := LoadConfig(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*cfg, DefaultConfig()) {
+		t.Fatalf("expected default config, got %+v", cfg)
+	}
+
+	if _, err := LoadConfig(root, "/missing.yaml"); err == nil {
+		t.Fatal("expected error for missing explicit config")
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, "etc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "etc", "buildkit-syft-scanner.json"), []byte(`{"parallelism": 3}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = LoadConfig(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Parallelism != 3 {
+		t.Fatalf("expected discovered config, got %+v", cfg)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "scanner.yaml"), []byte("scope: bad"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadConfig(root, "scanner.yaml")
+	if err == nil || !strings.Contains(err.Error(), `invalid config "scanner.yaml"`) {
+		t.Fatalf("expected invalid config error, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var cvss3Weights = map[string]map[string]float64{
+	"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+	"AC": {"L": 0.77, "H": 0.44},
+	"PR": {"N": 0.85, "L": 0.62, "H": 0.27},
+	"UI": {"N": 0.85, "R": 0.62},
+	"C":  {"H": 0.56, "L": 0.22, "N": 0},
+	"I":  {"H": 0.56, "L": 0.22, "N": 0},
+	"A":  {"H": 0.56, "L": 0.22, "N": 0},
+}
+
+// cvss3BaseScore computes the base score of a CVSS v3.0 or v3.1 vector,
+// e.g. "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H".
+func cvss3BaseScore(vector string) (float64, error) {
+	parts := strings.Split(vector, "/")
+	if len(parts) == 0 || (parts[0] != "CVSS:3.0" && parts[0] != "CVSS:3.1") {
+		return 0, errors.Errorf("unsupported cvss vector %q", vector)
+	}
+	metrics := map[string]string{}
+	for _, part := range parts[1:] {
+		k, v, ok := strings.Cut(part, ":")
+		if !ok {
+			return 0, errors.Errorf("invalid cvss vector %q", vector)
+		}
+		metrics[k] = v
+	}
+
+	scope := metrics["S"]
+	if scope != "U" && scope != "C" {
+		return 0, errors.Errorf("invalid cvss vector %q: missing scope", vector)
+	}
+	w := map[string]float64{}
+	for metric, weights := range cvss3Weights {
+		weight, ok := weights[metrics[metric]]
+		if !ok {
+			return 0, errors.Errorf("invalid cvss vector %q: missing %s", vector, metric)
+		}
+		w[metric] = weight
+	}
+	// privileges weigh more when the scope changes
+	if scope == "C" {
+		switch metrics["PR"] {
+		case "L":
+			w["PR"] = 0.68
+		case "H":
+			w["PR"] = 0.5
+		}
+	}
+
+	iss := 1 - (1-w["C"])*(1-w["I"])*(1-w["A"])
+	var impact float64
+	if scope == "U" {
+		impact = 6.42 * iss
+	} else {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	if impact <= 0 {
+		return 0, nil
+	}
+	exploitability := 8.22 * w["AV"] * w["AC"] * w["PR"] * w["UI"]
+	if scope == "U" {
+		return cvssRoundUp(math.Min(impact+exploitability, 10)), nil
+	}
+	return cvssRoundUp(math.Min(1.08*(impact+exploitability), 10)), nil
+}
+
+// cvssRoundUp rounds up to one decimal, as defined by CVSS v3.1.
+func cvssRoundUp(x float64) float64 {
+	i := int64(math.Round(x * 100000))
+	if i%10000 == 0 {
+		return float64(i) / 100000
+	}
+	return float64(i/10000+1) / 10
+}
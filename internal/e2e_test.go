@@ -0,0 +1,632 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/buildkit-syft-scanner/internal/check"
+)
+
+// e2eCase scans a synthetic root filesystem and checks the statements
+// written for it against schemas, as cmd/check does for ./examples.
+type e2eCase struct {
+	core   []fixture
+	extras map[string][]fixture
+	config func(*Config)
+	merge  bool
+	// checks maps statement file names to their expected schema.
+	checks map[string]string
+	// absent maps statement file names to strings they must not contain.
+	absent map[string][]string
+}
+
+func TestEndToEnd(t *testing.T) {
+	tests := map[string]e2eCase{
+		"scratch": {
+			checks: map[string]string{
+				"sbom.spdx.json": `{
+					"_type": "https://in-toto.io/Statement/v1",
+					"predicateType": "https://spdx.dev/Document",
+					"predicate": {
+						"SPDXID": "SPDXRef-DOCUMENT",
+						"name": "sbom"
+					}
+				}`,
+			},
+		},
+		"alpine": {
+			core: []fixture{alpineFixture},
+			checks: map[string]string{
+				"sbom.spdx.json": `{
+					"predicate": {
+						"packages": [
+							{
+								"SPDXID": "=package",
+								"name": "musl",
+								"versionInfo": "1.2.4_git20230717-r4",
+								"licenseDeclared": "MIT",
+								"externalRefs": [
+									{
+										"referenceType": "purl",
+										"referenceLocator": "pkg:apk/alpine/musl@1.2.4_git20230717-r4?arch=x86_64&distro=alpine-3.19.1"
+									}
+								]
+							},
+							{"name": "busybox", "versionInfo": "1.36.1-r15"}
+						],
+						"relationships": [
+							{
+								"spdxElementId": "SPDXRef-DocumentRoot-Directory-sbom",
+								"relationshipType": "CONTAINS",
+								"relatedSpdxElement": "==package"
+							}
+						]
+					}
+				}`,
+			},
+		},
+		"debian": {
+			core: []fixture{debianFixture},
+			checks: map[string]string{
+				"sbom.spdx.json": `{
+					"predicate": {
+						"packages": [
+							{
+								"name": "libc6",
+								"versionInfo": "2.36-9+deb12u4",
+								"externalRefs": [
+									{"referenceLocator": "pkg:deb/debian/libc6@2.36-9%2Bdeb12u4?arch=amd64&distro=debian-12&upstream=glibc"}
+								]
+							},
+							{"name": "base-files"}
+						]
+					}
+				}`,
+			},
+		},
+		"rpm": {
+			core: []fixture{rpmFixture(
+				rpmPackage{Name: "bash", Version: "5.2.21", Release: "1.fc39", Arch: "x86_64", License: "GPL-3.0-or-later"},
+			)},
+			checks: map[string]string{
+				"sbom.spdx.json": `{
+					"predicate": {
+						"packages": [
+							{
+								"name": "bash",
+								"versionInfo": "5.2.21-1.fc39",
+								"licenseDeclared": "GPL-3.0-or-later",
+								"externalRefs": [
+									{"referenceLocator": "pkg:rpm/fedora/bash@5.2.21-1.fc39?arch=x86_64&distro=fedora-39"}
+								]
+							}
+						]
+					}
+				}`,
+			},
+		},
+		"golang": {
+			core: []fixture{goBinaryFixture("bin/app")},
+			checks: map[string]string{
+				"sbom.spdx.json": `{
+					"predicate": {
+						"packages": {
+							"$items": [{"SPDXID": "=package", "name": "stdlib", "versionInfo": "~^go1\\."}],
+							"$count": 1,
+							"$where": {"name": "stdlib"}
+						},
+						"files": [
+							{"SPDXID": "=filename", "fileName": "bin/app"}
+						],
+						"relationships": [
+							{
+								"spdxElementId": "==package",
+								"relationshipType": "OTHER",
+								"comment": "evident-by: indicates the package's existence is evident by the given file",
+								"relatedSpdxElement": "==filename"
+							}
+						]
+					}
+				}`,
+			},
+		},
+		"npm-lock": {
+			core: []fixture{npmLockFixture},
+			config: func(cfg *Config) {
+				cfg.SelectCatalogers = []string{"+javascript-lock-cataloger"}
+			},
+			checks: map[string]string{
+				"sbom.spdx.json": `{
+					"predicate": {
+						"packages": [
+							{
+								"name": "ms",
+								"versionInfo": "2.1.3",
+								"externalRefs": [
+									{"referenceLocator": "pkg:npm/ms@2.1.3"}
+								]
+							}
+						]
+					}
+				}`,
+			},
+		},
+		"sbom-cataloger": {
+			extras: map[string][]fixture{
+				"sbom-base": {embeddedSBOMFixture},
+			},
+			checks: map[string]string{
+				"sbom.spdx.json": `{
+					"predicate": {"name": "sbom"}
+				}`,
+				"sbom-base.spdx.json": `{
+					"predicate": {
+						"name": "sbom-base",
+						"packages": [
+							{
+								"name": "foo",
+								"versionInfo": "1.0",
+								"externalRefs": [
+									{"referenceLocator": "pkg:generic/foo@1.0"}
+								]
+							}
+						]
+					}
+				}`,
+			},
+		},
+		"exclude": {
+			core: []fixture{
+				debianFixture,
+				// noise under a default and a configured exclusion
+				files(map[string]string{"root/.cache/lib/apk/db/installed": "P:musl\nV:1.2.4-r4\n\n"}),
+				func(t *testing.T, root string) { npmLockFixture(t, filepath.Join(root, "src", "testdata")) },
+			},
+			config: func(cfg *Config) {
+				cfg.Exclude = append(cfg.Exclude, "**/testdata")
+				cfg.SelectCatalogers = []string{"+javascript-lock-cataloger"}
+			},
+			checks: map[string]string{
+				"sbom.spdx.json": `{
+					"predicate": {
+						"packages": {
+							"$items": [{"name": "libc6"}],
+							"$none": {"name": {"$anyOf": ["musl", "ms"]}}
+						}
+					}
+				}`,
+				"sbom.cdx.json": `{
+					"predicate": {
+						"metadata": {
+							"properties": [
+								{"name": "buildkit-syft-scanner:exclude", "value": "./root/.cache"},
+								{"name": "buildkit-syft-scanner:exclude", "value": "**/testdata"}
+							]
+						}
+					}
+				}`,
+				"sbom.syft.json": `{
+					"predicate": {
+						"descriptor": {
+							"configuration": {
+								"buildkit-syft-scanner": {"exclude": ["./proc", "**/testdata"]}
+							}
+						}
+					}
+				}`,
+			},
+			absent: map[string][]string{
+				"sbom.spdx.json": {`"musl"`, `"ms"`},
+			},
+		},
+		"tool": {
+			checks: map[string]string{
+				"sbom.spdx.json": `{
+					"predicate": {
+						"creationInfo": {"creators": ["Tool: buildkit-syft-scanner-v0.0.0+unknown"]}
+					}
+				}`,
+				"sbom.cdx.json": `{
+					"predicate": {
+						"metadata": {
+							"tools": {
+								"components": [{"type": "application", "author": "docker", "name": "buildkit-syft-scanner", "version": "v0.0.0+unknown"}]
+							},
+							"properties": [
+								{"name": "buildkit-syft-scanner:config-digest", "value": "=digest"},
+								{"name": "buildkit-syft-scanner:config"}
+							]
+						}
+					}
+				}`,
+				"sbom.syft.json": `{
+					"predicate": {
+						"descriptor": {
+							"configuration": {
+								"buildkit-syft-scanner": {"version": ["v0.0.0+unknown"], "config-digest": ["=digest"], "config": ["=config"]}
+							}
+						}
+					}
+				}`,
+			},
+		},
+		"copied-from-stage": {
+			core: []fixture{goBinaryFixture("usr/local/bin/app")},
+			extras: map[string][]fixture{
+				"sbom-build": {goBinaryFixture("go/bin/app")},
+			},
+			checks: map[string]string{
+				"sbom.cdx.json": `{
+					"predicate": {
+						"metadata": {
+							"properties": [
+								{"name": "buildkit-syft-scanner:copied-from", "value": "/usr/local/bin/app=sbom-build:/go/bin/app"}
+							]
+						}
+					}
+				}`,
+				"sbom.syft.json": `{
+					"predicate": {
+						"artifacts": [
+							{
+								"name": "stdlib",
+								"locations": [
+									{
+										"path": "/usr/local/bin/app",
+										"annotations": {
+											"buildkit-syft-scanner:stage": "sbom-build",
+											"buildkit-syft-scanner:stage-path": "/go/bin/app"
+										}
+									}
+								]
+							}
+						]
+					}
+				}`,
+			},
+		},
+		"merged": {
+			core: []fixture{alpineFixture, goBinaryFixture("bin/app")},
+			extras: map[string][]fixture{
+				"sbom-build": {debianFixture, goBinaryFixture("src/app")},
+			},
+			merge: true,
+			checks: map[string]string{
+				"sbom-merged.spdx.json": `{
+					"predicate": {
+						"name": "sbom-merged",
+						"packages": [
+							{"SPDXID": "=musl", "name": "musl"},
+							{"SPDXID": "=libc6", "name": "libc6"},
+							{"SPDXID": "=stdlib", "name": "stdlib"},
+							{"SPDXID": "SPDXRef-DocumentRoot-Directory-sbom-build", "name": "sbom-build"}
+						],
+						"relationships": [
+							{
+								"spdxElementId": "SPDXRef-DOCUMENT",
+								"relationshipType": "DESCRIBES",
+								"relatedSpdxElement": "SPDXRef-DocumentRoot-Directory-sbom"
+							},
+							{
+								"spdxElementId": "SPDXRef-DocumentRoot-Directory-sbom-build",
+								"relationshipType": "BUILD_DEPENDENCY_OF",
+								"relatedSpdxElement": "SPDXRef-DocumentRoot-Directory-sbom"
+							},
+							{
+								"spdxElementId": "SPDXRef-DocumentRoot-Directory-sbom",
+								"relationshipType": "CONTAINS",
+								"relatedSpdxElement": "==musl"
+							},
+							{
+								"spdxElementId": "SPDXRef-DocumentRoot-Directory-sbom-build",
+								"relationshipType": "CONTAINS",
+								"relatedSpdxElement": "==libc6"
+							},
+							{
+								"spdxElementId": "SPDXRef-DocumentRoot-Directory-sbom",
+								"relationshipType": "CONTAINS",
+								"relatedSpdxElement": "==stdlib"
+							},
+							{
+								"spdxElementId": "SPDXRef-DocumentRoot-Directory-sbom-build",
+								"relationshipType": "CONTAINS",
+								"relatedSpdxElement": "==stdlib"
+							}
+						]
+					}
+				}`,
+				"sbom.spdx.json": `{
+					"predicate": {"name": "sbom"}
+				}`,
+				"sbom-build.spdx.json": `{
+					"predicate": {"name": "sbom-build"}
+				}`,
+			},
+		},
+		"cyclonedx": {
+			core: []fixture{alpineFixture},
+			checks: map[string]string{
+				"sbom.cdx.json": `{
+					"_type": "https://in-toto.io/Statement/v1",
+					"predicateType": "https://cyclonedx.org/bom",
+					"predicate": {
+						"bomFormat": "CycloneDX",
+						"components": [
+							{"type": "library", "name": "musl", "version": "1.2.4_git20230717-r4"}
+						]
+					}
+				}`,
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			dest := scanFixtures(t, tc)
+			for file, schema := range tc.checks {
+				assertStatement(t, filepath.Join(dest, file), schema)
+			}
+			for file, absent := range tc.absent {
+				dt, err := os.ReadFile(filepath.Join(dest, file))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, s := range absent {
+					if strings.Contains(string(dt), s) {
+						t.Errorf("%s: expected %s to be absent", file, s)
+					}
+				}
+			}
+		})
+	}
+}
+
+// scanFixtures runs the Scanner over the root filesystems of tc, named as
+// BuildKit would present them, returning the destination.
+func scanFixtures(t *testing.T, tc e2eCase) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	cfg := DefaultConfig()
+	if tc.config != nil {
+		tc.config(&cfg)
+	}
+	scanner := Scanner{
+		Core:        Target{Path: buildFixture(t, filepath.Join(dir, "sbom"), tc.core...), Config: &cfg},
+		Destination: filepath.Join(dir, "out"),
+		Merge:       tc.merge,
+	}
+	for name, fixtures := range tc.extras {
+		scanner.Extras = append(scanner.Extras, Target{
+			Path:   buildFixture(t, filepath.Join(dir, "extras", name), fixtures...),
+			Config: &cfg,
+		})
+	}
+	for file := range tc.checks {
+		f, ok := formatForFile(file)
+		if !ok {
+			t.Fatalf("no format for %q", file)
+		}
+		if !containsFormat(scanner.Formats, f) {
+			scanner.Formats = append(scanner.Formats, f)
+		}
+	}
+	if err := os.Mkdir(scanner.Destination, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := scanner.Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return scanner.Destination
+}
+
+func formatForFile(name string) (Format, bool) {
+	for _, id := range supportedFormats() {
+		formats, err := ParseFormats(id)
+		if err != nil {
+			continue
+		}
+		if f := formats[0]; strings.HasSuffix(name, f.Extension) {
+			return f, true
+		}
+	}
+	return Format{}, false
+}
+
+func containsFormat(formats []Format, f Format) bool {
+	for _, other := range formats {
+		if other.String() == f.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// assertStatement checks that the statement at p matches schema.
+func assertStatement(t *testing.T, p string, schema string) {
+	t.Helper()
+	var expected, actual interface{}
+	if err := json.Unmarshal([]byte(schema), &expected); err != nil {
+		t.Fatalf("invalid schema for %s: %v", filepath.Base(p), err)
+	}
+	dt, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(dt, &actual); err != nil {
+		t.Fatal(err)
+	}
+	if err := check.Check(expected, actual); err != nil {
+		t.Errorf("%s: %v", filepath.Base(p), err)
+	}
+}
+
+func TestGenerateSchema(t *testing.T) {
+	dest := scanFixtures(t, e2eCase{
+		core: []fixture{goBinaryFixture("bin/app")},
+		checks: map[string]string{
+			"sbom.spdx.json": `{}`,
+			"sbom.cdx.json":  `{}`,
+			"sbom.syft.json": `{}`,
+		},
+	})
+	for file, sel := range map[string]check.Selection{
+		"sbom.spdx.json": {Packages: []string{"stdlib"}, Paths: []string{"/bin/app"}},
+		"sbom.cdx.json":  {Packages: []string{"pkg:golang/stdlib"}},
+		"sbom.syft.json": {Packages: []string{"stdlib"}, Paths: []string{"/bin/app"}},
+	} {
+		t.Run(file, func(t *testing.T) {
+			var statement map[string]interface{}
+			dt, err := os.ReadFile(filepath.Join(dest, file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(dt, &statement); err != nil {
+				t.Fatal(err)
+			}
+			schema, err := check.Generate(statement, sel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := check.Check(schema, statement); err != nil {
+				t.Errorf("expected the generated schema to pass: %v", err)
+			}
+			if len(sel.Paths) > 0 {
+				dt, err := json.Marshal(schema)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(dt), `"==package:stdlib"`) || !strings.Contains(string(dt), `"==file:bin/app"`) {
+					t.Errorf("expected the relationship between stdlib and /bin/app in %s", dt)
+				}
+			}
+		})
+	}
+}
+
+func TestSBOMAssertions(t *testing.T) {
+	installed := `P:musl
+V:1.2.4_git20230717-r4
+A:x86_64
+L:MIT
+p:so:libc.musl-x86_64.so.1=1
+F:lib
+R:ld-musl-x86_64.so.1
+
+P:busybox
+V:1.36.1-r15
+A:x86_64
+L:GPL-2.0-only
+D:so:libc.musl-x86_64.so.1
+F:bin
+R:busybox
+
+`
+	schema := `{
+		"$sbom": {
+			"packages": [
+				{"name": "musl", "version": "~^1\\.2\\.", "type": "apk", "licenses": ["MIT"]},
+				{"purl": "pkg:npm/ms"}
+			],
+			"absent": [{"name": "left-pad"}, {"paths": ["~^/usr/share/doc/"]}],
+			"relationships": [
+				{"from": {"name": "busybox"}, "type": "DEPENDS_ON", "to": {"name": "musl"}}
+			],
+			"count": {"apk": 2, "npm": 1, "all": {"$gte": 3}}
+		}
+	}`
+	dest := scanFixtures(t, e2eCase{
+		core: []fixture{
+			files(map[string]string{
+				"etc/os-release":          "ID=alpine\nVERSION_ID=3.19.1\n",
+				"lib/apk/db/installed":    installed,
+				"lib/ld-musl-x86_64.so.1": "musl",
+				"bin/busybox":             "busybox",
+			}),
+			npmLockFixture,
+		},
+		config: func(cfg *Config) {
+			cfg.SelectCatalogers = []string{"+javascript-lock-cataloger"}
+		},
+		checks: map[string]string{
+			"sbom.spdx.json": schema,
+			"sbom.cdx.json":  schema,
+			"sbom.syft.json": schema,
+		},
+	})
+
+	// files are only related to their packages in SPDX and syft JSON
+	for _, file := range []string{"sbom.spdx.json", "sbom.syft.json"} {
+		assertStatement(t, filepath.Join(dest, file), `{
+			"$sbom": {
+				"relationships": [
+					{"from": {"file": "lib/ld-musl-x86_64.so.1"}, "type": "CONTAINED_BY", "to": {"purl": "pkg:apk/alpine/musl"}}
+				]
+			}
+		}`)
+	}
+
+	var statement interface{}
+	dt, err := os.ReadFile(filepath.Join(dest, "sbom.cdx.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(dt, &statement); err != nil {
+		t.Fatal(err)
+	}
+	var failing interface{}
+	if err := json.Unmarshal([]byte(`{
+		"$sbom": {
+			"packages": [{"name": "bash"}],
+			"absent": [{"name": "busybox"}],
+			"relationships": [{"from": {"name": "musl"}, "type": "DEPENDS_ON", "to": {"name": "busybox"}}],
+			"count": {"apk": 1}
+		}
+	}`), &failing); err != nil {
+		t.Fatal(err)
+	}
+	err = check.Check(failing, statement)
+	mismatches, ok := err.(check.Mismatches)
+	if !ok || len(mismatches) != 4 {
+		t.Errorf("expected 4 mismatches, got %v", err)
+	}
+
+	// selectors that are not objects would otherwise match nothing, and
+	// absent ones always hold
+	for _, sel := range []string{
+		`{"absent": ["busybox"]}`,
+		`{"absent": "busybox"}`,
+		`{"packages": ["musl"]}`,
+		`{"relationships": [{"from": "busybox", "type": "DEPENDS_ON", "to": {"name": "musl"}}]}`,
+	} {
+		var invalid interface{}
+		if err := json.Unmarshal([]byte(`{"$sbom": `+sel+`}`), &invalid); err != nil {
+			t.Fatal(err)
+		}
+		err := check.Check(invalid, statement)
+		mismatches, ok := err.(check.Mismatches)
+		if !ok || len(mismatches) != 1 || mismatches[0].Kind != check.KindSchema {
+			t.Errorf("%s: expected an invalid schema, got %v", sel, err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrorKind classifies scanner failures by the stage they occurred in.
+type ErrorKind string
+
+const (
+	ErrorUnknown   ErrorKind = "unknown"
+	ErrorConfig    ErrorKind = "config"
+	ErrorSource    ErrorKind = "source"
+	ErrorCataloger ErrorKind = "cataloger"
+	ErrorWrite     ErrorKind = "write"
+	ErrorAborted   ErrorKind = "aborted"
+	ErrorPolicy    ErrorKind = "policy"
+)
+
+var exitCodes = map[ErrorKind]int{
+	ErrorUnknown:   1,
+	ErrorConfig:    2,
+	ErrorSource:    3,
+	ErrorCataloger: 4,
+	ErrorWrite:     5,
+	ErrorAborted:   6,
+	ErrorPolicy:    7,
+}
+
+// ExitCode is the process exit code used for errors of this kind.
+func (k ErrorKind) ExitCode() int {
+	if code, ok := exitCodes[k]; ok {
+		return code
+	}
+	return exitCodes[ErrorUnknown]
+}
+
+// Error is a classified scanner failure.
+type Error struct {
+	Kind ErrorKind
+	// Target is the name of the scanned target the error relates to, if any.
+	Target string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(kind ErrorKind, target string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Target: target, Err: err}
+}
+
+// ErrorKindOf returns the kind of the first classified error in err's chain.
+func ErrorKindOf(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return ErrorUnknown
+}
+
+// ErrorSummary formats err on a single line.
+func ErrorSummary(err error) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(err.Error(), "\n", "; ")), " ")
+}
+
+// ErrorReportFile is the name of the machine-readable error report written to
+// the destination when enabled. BuildKit attaches every .json file of the
+// destination as an in-toto statement, so the report, although encoded as
+// JSON, must not have a .json extension.
+const ErrorReportFile = "syft-scanner-error.report"
+
+// ErrorReport is the machine-readable form of a scanner failure.
+type ErrorReport struct {
+	Kind     ErrorKind `json:"kind"`
+	ExitCode int       `json:"exitCode"`
+	Target   string    `json:"target,omitempty"`
+	Message  string    `json:"message"`
+}
+
+// NewErrorReport classifies err into an ErrorReport.
+func NewErrorReport(err error) ErrorReport {
+	report := ErrorReport{
+		Kind:    ErrorUnknown,
+		Message: ErrorSummary(err),
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		report.Kind = e.Kind
+		report.Target = e.Target
+	}
+	report.ExitCode = report.Kind.ExitCode()
+	return report
+}
+
+// WriteErrorReport writes the ErrorReport for scanErr to dir.
+func WriteErrorReport(dir string, scanErr error) error {
+	dt, err := json.MarshalIndent(NewErrorReport(scanErr), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ErrorReportFile), append(dt, '\n'), 0o644)
+}
@@ -0,0 +1,94 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestErrorKindOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorKind
+	}{
+		{err: errors.New("plain"), want: ErrorUnknown},
+		{err: newError(ErrorConfig, "", errors.New("bad")), want: ErrorConfig},
+		{err: fmt.Errorf("wrapped: %w", newError(ErrorWrite, "core", errors.New("bad"))), want: ErrorWrite},
+		{err: errors.Join(errors.New("plain"), newError(ErrorSource, "stage", errors.New("bad"))), want: ErrorSource},
+	}
+	for _, tt := range tests {
+		if got := ErrorKindOf(tt.err); got != tt.want {
+			t.Errorf("%v: expected %s, got %s", tt.err, tt.want, got)
+		}
+	}
+}
+
+func TestScanErrorKinds(t *testing.T) {
+	scanner := Scanner{
+		Core:        Target{Path: filepath.Join(t.TempDir(), "missing")},
+		Destination: t.TempDir(),
+	}
+	err := scanner.Scan(context.Background())
+	if kind := ErrorKindOf(err); kind != ErrorSource {
+		t.Fatalf("expected source error, got %s: %v", kind, err)
+	}
+
+	t.Setenv(envScanDestination, t.TempDir())
+	t.Setenv(envScanSource, t.TempDir())
+	t.Setenv(envScanFormat, "spdx-tag-value")
+	_, err = NewScannerFromEnvironment()
+	if kind := ErrorKindOf(err); kind != ErrorConfig {
+		t.Fatalf("expected config error, got %s: %v", kind, err)
+	}
+}
+
+func TestWriteErrorReport(t *testing.T) {
+	dest := t.TempDir()
+	err := errors.Join(
+		newError(ErrorCataloger, "core", errors.New("failed to run tasks")),
+		errors.New("second\nline"),
+	)
+	if err := WriteErrorReport(dest, err); err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasSuffix(ErrorReportFile, ".json") {
+		t.Fatalf("error report %s would be attached by BuildKit as an in-toto statement", ErrorReportFile)
+	}
+
+	dt, err := os.ReadFile(filepath.Join(dest, ErrorReportFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report ErrorReport
+	if err := json.Unmarshal(dt, &report); err != nil {
+		t.Fatal(err)
+	}
+	want := ErrorReport{
+		Kind:     ErrorCataloger,
+		ExitCode: 4,
+		Target:   "core",
+		Message:  "failed to run tasks; second; line",
+	}
+	if report != want {
+		t.Fatalf("expected %+v, got %+v", want, report)
+	}
+}
@@ -0,0 +1,301 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// fixture populates a synthetic root filesystem at root.
+type fixture func(t *testing.T, root string)
+
+// buildFixture creates a root filesystem at dir from fixtures.
+func buildFixture(t *testing.T, dir string, fixtures ...fixture) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range fixtures {
+		f(t, dir)
+	}
+	return dir
+}
+
+// files is a fixture writing each file, keyed by its path in the root
+// filesystem.
+func files(files map[string]string) fixture {
+	return func(t *testing.T, root string) {
+		t.Helper()
+		for name, content := range files {
+			writeFixtureFile(t, root, name, []byte(content))
+		}
+	}
+}
+
+func writeFixtureFile(t *testing.T, root string, name string, content []byte) {
+	t.Helper()
+	p := filepath.Join(root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var alpineFixture = files(map[string]string{
+	"etc/os-release": "ID=alpine\nVERSION_ID=3.19.1\nPRETTY_NAME=\"Alpine Linux v3.19\"\n",
+	"lib/apk/db/installed": `C:Q1p78yvTLG094tHE1+dToJGbmYzQE=
+P:musl
+V:1.2.4_git20230717-r4
+A:x86_64
+S:383152
+I:622592
+T:the musl c library (libc) implementation
+U:https://musl.libc.org/
+L:MIT
+o:musl
+m:Timo Teräs <timo.teras@iki.fi>
+F:lib
+R:ld-musl-x86_64.so.1
+
+C:Q1rOTuQ8jDV5SYVVxXQmoZhxUEp8s=
+P:busybox
+V:1.36.1-r15
+A:x86_64
+S:509306
+I:924286
+T:Size optimized toolbox of many common UNIX utilities
+U:https://busybox.net/
+L:GPL-2.0-only
+o:busybox
+D:so:libc.musl-x86_64.so.1
+F:bin
+R:busybox
+
+`,
+})
+
+var debianFixture = files(map[string]string{
+	"etc/os-release": "ID=debian\nVERSION_ID=\"12\"\nVERSION_CODENAME=bookworm\nPRETTY_NAME=\"Debian GNU/Linux 12 (bookworm)\"\n",
+	"var/lib/dpkg/status": `Package: base-files
+Status: install ok installed
+Priority: required
+Section: admin
+Installed-Size: 340
+Maintainer: Santiago Vila <sanvila@debian.org>
+Architecture: amd64
+Version: 12.4+deb12u5
+Description: Debian base system miscellaneous files
+
+Package: libc6
+Status: install ok installed
+Priority: optional
+Section: libs
+Installed-Size: 12986
+Maintainer: GNU Libc Maintainers <debian-glibc@lists.debian.org>
+Architecture: amd64
+Multi-Arch: same
+Source: glibc
+Version: 2.36-9+deb12u4
+Description: GNU C Library: Shared libraries
+`,
+})
+
+// rpmPackage is the subset of an RPM header written by rpmFixture.
+type rpmPackage struct {
+	Name, Version, Release, Arch, License string
+}
+
+// rpmFixture writes a sqlite rpmdb, as used by Fedora and derivatives,
+// containing pkgs.
+func rpmFixture(pkgs ...rpmPackage) fixture {
+	return func(t *testing.T, root string) {
+		t.Helper()
+		writeFixtureFile(t, root, "etc/os-release", []byte("ID=fedora\nVERSION_ID=39\nPRETTY_NAME=\"Fedora Linux 39\"\n"))
+
+		p := filepath.Join(root, "var", "lib", "rpm", "rpmdb.sqlite")
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		db, err := sql.Open("sqlite", p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		if _, err := db.Exec("CREATE TABLE Packages (hnum INTEGER PRIMARY KEY AUTOINCREMENT, blob BLOB NOT NULL)"); err != nil {
+			t.Fatal(err)
+		}
+		for _, pkg := range pkgs {
+			hdr := rpmHeader(map[int32]string{
+				1000: pkg.Name,    // RPMTAG_NAME
+				1001: pkg.Version, // RPMTAG_VERSION
+				1002: pkg.Release, // RPMTAG_RELEASE
+				1014: pkg.License, // RPMTAG_LICENSE
+				1022: pkg.Arch,    // RPMTAG_ARCH
+			})
+			if _, err := db.Exec("INSERT INTO Packages (blob) VALUES (?)", hdr); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+// rpmHeader encodes string tags as a legacy (region-less) RPM header blob.
+func rpmHeader(tags map[int32]string) []byte {
+	keys := make([]int32, 0, len(tags))
+	for tag := range tags {
+		keys = append(keys, tag)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	const rpmStringType = 6
+	var index, data bytes.Buffer
+	for _, tag := range keys {
+		binary.Write(&index, binary.BigEndian, []int32{tag, rpmStringType, int32(data.Len()), 1})
+		data.WriteString(tags[tag])
+		data.WriteByte(0)
+	}
+
+	var hdr bytes.Buffer
+	binary.Write(&hdr, binary.BigEndian, []int32{int32(len(keys)), int32(data.Len())})
+	hdr.Write(index.Bytes())
+	hdr.Write(data.Bytes())
+	return hdr.Bytes()
+}
+
+// goBinaryFixture compiles a Go program with no dependencies into name, so
+// that it carries build information for the go-module-binary-cataloger.
+func goBinaryFixture(name string) fixture {
+	return func(t *testing.T, root string) {
+		t.Helper()
+		goBin, err := exec.LookPath("go")
+		if err != nil {
+			t.Skip("go toolchain not available")
+		}
+
+		src := t.TempDir()
+		writeFixtureFile(t, src, "go.mod", []byte("module example.com/app\n\ngo 1.21\n"))
+		writeFixtureFile(t, src, "main.go", []byte("package main\n\nfunc main() {}\n"))
+
+		out := filepath.Join(root, filepath.FromSlash(name))
+		cmd := exec.Command(goBin, "build", "-trimpath", "-o", out, ".")
+		cmd.Dir = src
+		cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off", "GOTOOLCHAIN=local", "CGO_ENABLED=0", "GOPROXY=off")
+		if dt, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("failed to build go binary: %v\n%s", err, dt)
+		}
+	}
+}
+
+// elfFixture writes a Go program into name with its build information
+// mangled, so that it is an executable without packages.
+func elfFixture(name string) fixture {
+	return func(t *testing.T, root string) {
+		t.Helper()
+		goBinaryFixture(name)(t, root)
+		p := filepath.Join(root, filepath.FromSlash(name))
+		dt, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dt = bytes.ReplaceAll(dt, []byte("\xff Go buildinf:"), []byte("\xff No buildinf:"))
+		if err := os.WriteFile(p, dt, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// jarFixture writes a jar with a manifest into name, for the
+// java-archive-cataloger.
+func jarFixture(name string) fixture {
+	return func(t *testing.T, root string) {
+		t.Helper()
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		w, err := zw.Create("META-INF/MANIFEST.MF")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte("Manifest-Version: 1.0\r\nImplementation-Title: app\r\nImplementation-Version: 1.2.3\r\n\r\n")); err != nil {
+			t.Fatal(err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		writeFixtureFile(t, root, name, buf.Bytes())
+	}
+}
+
+var npmLockFixture = files(map[string]string{
+	"app/package.json": `{"name": "app", "version": "1.0.0", "dependencies": {"ms": "^2.1.3"}}`,
+	"app/package-lock.json": `{
+  "name": "app",
+  "version": "1.0.0",
+  "lockfileVersion": 3,
+  "requires": true,
+  "packages": {
+    "": {
+      "name": "app",
+      "version": "1.0.0",
+      "dependencies": {
+        "ms": "^2.1.3"
+      }
+    },
+    "node_modules/ms": {
+      "version": "2.1.3",
+      "resolved": "https://registry.npmjs.org/ms/-/ms-2.1.3.tgz",
+      "integrity": "sha512-6FlzubTLZG3J2a/NVCAleEhjzq5oxgHyaCU9yYXvcLsvoVaHJq/s5xXI6/XXP6tz7R9xAOtHnSO/tXtF3WRTlA==",
+      "license": "MIT"
+    }
+  }
+}
+`,
+})
+
+var embeddedSBOMFixture = files(map[string]string{
+	"var/share/sbom/foo.spdx.json": `{
+ "spdxVersion": "SPDX-2.3",
+ "dataLicense": "CC0-1.0",
+ "SPDXID": "SPDXRef-DOCUMENT",
+ "name": "foo",
+ "documentNamespace": "https://example.com/foo",
+ "creationInfo": {"created": "2024-01-01T00:00:00Z", "creators": ["Tool: test"]},
+ "packages": [
+  {
+   "name": "foo",
+   "SPDXID": "SPDXRef-Package-foo",
+   "versionInfo": "1.0",
+   "downloadLocation": "NOASSERTION",
+   "externalRefs": [
+    {
+     "referenceCategory": "PACKAGE-MANAGER",
+     "referenceType": "purl",
+     "referenceLocator": "pkg:generic/foo@1.0"
+    }
+   ]
+  }
+ ]
+}
+`,
+})
@@ -0,0 +1,186 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/CycloneDX/cyclonedx-go"
+	"github.com/anchore/syft/syft/format"
+	"github.com/anchore/syft/syft/format/cyclonedxjson"
+	"github.com/anchore/syft/syft/format/cyclonedxxml"
+	"github.com/anchore/syft/syft/format/spdxjson"
+	"github.com/anchore/syft/syft/format/syftjson"
+	"github.com/anchore/syft/syft/sbom"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/pkg/errors"
+)
+
+// PredicateSyft is the in-toto predicate type used for syft's native JSON
+// format.
+const PredicateSyft = "https://syft.dev/bom"
+
+// PredicateCycloneDXXML is the in-toto predicate type used for CycloneDX XML
+// documents. It differs from intoto.PredicateCycloneDX, whose consumers
+// expect the predicate to be the CycloneDX JSON document itself.
+const PredicateCycloneDXXML = "https://cyclonedx.org/bom/xml"
+
+// XMLPredicate is the predicate of statements of XML formats: in-toto
+// predicates are JSON objects, so the XML document is carried as a string
+// along with its media type.
+type XMLPredicate struct {
+	MediaType string `json:"mediaType"`
+	Content   string `json:"content"`
+}
+
+// DefaultFormat is the format emitted when no format is requested.
+const DefaultFormat = "spdx-json"
+
+// formatInfo describes how an SBOM encoded with a syft format is wrapped into
+// an in-toto statement.
+//
+// Only formats with a well-known predicate type are supported: consumers
+// select SBOMs by predicate type, so formats without one (github-json) would
+// not be picked up by any tooling. In-toto predicates must be JSON objects,
+// so cyclonedx-xml is wrapped in an XMLPredicate, and the other text formats
+// (spdx-tag-value, purls, ...) are not supported.
+type formatInfo struct {
+	predicateType string
+	extension     string
+	// xml is set for cyclonedx-xml, see Format.
+	xml bool
+}
+
+var formatInfos = map[sbom.FormatID]formatInfo{
+	spdxjson.ID:      {predicateType: intoto.PredicateSPDX, extension: ".spdx.json"},
+	cyclonedxjson.ID: {predicateType: intoto.PredicateCycloneDX, extension: ".cdx.json"},
+	syftjson.ID:      {predicateType: PredicateSyft, extension: ".syft.json"},
+	cyclonedxxml.ID:  {predicateType: PredicateCycloneDXXML, extension: ".cdx.xml.json", xml: true},
+}
+
+// Format is an SBOM output format, along with the in-toto predicate type and
+// file extension used for statements of that format.
+type Format struct {
+	Encoder       sbom.FormatEncoder
+	PredicateType string
+	Extension     string
+
+	// xml is set for cyclonedx-xml. Encoder is then the CycloneDX JSON
+	// encoder of the same version, so that the document is finalized as
+	// JSON like the other formats, and only converted to XML last.
+	xml bool
+}
+
+func (f Format) String() string {
+	id := f.Encoder.ID()
+	if f.xml {
+		id = cyclonedxxml.ID
+	}
+	if f.Encoder.Version() == sbom.AnyVersion {
+		return string(id)
+	}
+	return string(id) + "@" + f.Encoder.Version()
+}
+
+// Predicate encodes s into the JSON document of the predicate of an in-toto
+// statement, which finalizePredicate then completes.
+func (f Format) Predicate(s sbom.SBOM) (json.RawMessage, error) {
+	output, err := format.Encode(s, f.Encoder)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(output), nil
+}
+
+// ParseFormats parses a comma-separated list of syft format names, each
+// optionally suffixed with a version, e.g. "spdx-json,cyclonedx-json@1.5".
+// An empty list selects DefaultFormat.
+func ParseFormats(s string) ([]Format, error) {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		names = []string{DefaultFormat}
+	}
+
+	encoders, err := format.DefaultEncodersConfig().Encoders()
+	if err != nil {
+		return nil, err
+	}
+	collection := format.NewEncoderCollection(encoders...)
+
+	var formats []Format
+	seen := map[sbom.FormatID]struct{}{}
+	for _, name := range names {
+		enc := collection.GetByString(name)
+		if enc == nil {
+			return nil, errors.Errorf("unknown sbom format %q", name)
+		}
+		info, ok := formatInfos[enc.ID()]
+		if !ok {
+			return nil, errors.Errorf("unsupported sbom format %q (%s), must be one of %s", name, enc.ID(), strings.Join(supportedFormats(), ", "))
+		}
+		if _, ok := seen[enc.ID()]; ok {
+			return nil, errors.Errorf("sbom format %q requested more than once", enc.ID())
+		}
+		seen[enc.ID()] = struct{}{}
+
+		if info.xml {
+			enc, err = cyclonedxjson.NewFormatEncoderWithConfig(cyclonedxjson.EncoderConfig{Version: enc.Version()})
+			if err != nil {
+				return nil, errors.Wrapf(err, "sbom format %q", name)
+			}
+		}
+		formats = append(formats, Format{
+			Encoder:       enc,
+			PredicateType: info.predicateType,
+			Extension:     info.extension,
+			xml:           info.xml,
+		})
+	}
+	return formats, nil
+}
+
+// cyclonedxXML converts a CycloneDX JSON document to XML, in the same
+// specification version, wrapped in an XMLPredicate.
+func cyclonedxXML(doc []byte) (json.RawMessage, error) {
+	var bom cyclonedx.BOM
+	if err := cyclonedx.NewBOMDecoder(bytes.NewReader(doc), cyclonedx.BOMFileFormatJSON).Decode(&bom); err != nil {
+		return nil, errors.Wrap(err, "failed to decode cyclonedx document")
+	}
+	var buf bytes.Buffer
+	if err := cyclonedx.NewBOMEncoder(&buf, cyclonedx.BOMFileFormatXML).EncodeVersion(&bom, bom.SpecVersion); err != nil {
+		return nil, errors.Wrap(err, "failed to encode cyclonedx xml document")
+	}
+	return json.Marshal(XMLPredicate{
+		MediaType: "application/vnd.cyclonedx+xml",
+		Content:   buf.String(),
+	})
+}
+
+func supportedFormats() []string {
+	var names []string
+	for id := range formatInfos {
+		names = append(names, string(id))
+	}
+	sort.Strings(names)
+	return names
+}
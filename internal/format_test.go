@@ -0,0 +1,242 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CycloneDX/cyclonedx-go"
+	"github.com/anchore/syft/syft/format"
+	"github.com/anchore/syft/syft/format/spdxjson"
+	"github.com/anchore/syft/syft/format/syftjson"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/sbom"
+	"github.com/anchore/syft/syft/source"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+)
+
+func TestParseFormats(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []string
+		wantErr string
+	}{
+		{input: "", want: []string{"spdx-json@2.3"}},
+		{input: "  ", want: []string{"spdx-json@2.3"}},
+		{input: " , ,", want: []string{"spdx-json@2.3"}},
+		{input: "spdx-json", want: []string{"spdx-json@2.3"}},
+		{input: "spdx-json@2.2", want: []string{"spdx-json@2.2"}},
+		{input: "spdx-json, cyclonedx-json@1.5", want: []string{"spdx-json@2.3", "cyclonedx-json@1.5"}},
+		{input: "json", want: []string{"syft-json@" + syftjson.NewFormatEncoder().Version()}},
+		{input: "syft", want: []string{"syft-json@" + syftjson.NewFormatEncoder().Version()}},
+		{input: "cdx", want: []string{"cyclonedx-xml@1.7"}},
+		{input: "cyclonedx-xml@1.5,cyclonedx-json", want: []string{"cyclonedx-xml@1.5", "cyclonedx-json@1.7"}},
+		{input: "spdx-tag-value", wantErr: `unsupported sbom format "spdx-tag-value"`},
+		{input: "github-json", wantErr: `unsupported sbom format "github-json"`},
+		{input: "spdx-json@2.2,spdx-json@2.3", wantErr: `sbom format "spdx-json" requested more than once`},
+		{input: "spdx-json@9.9", wantErr: `unknown sbom format "spdx-json@9.9"`},
+		{input: "foo", wantErr: `unknown sbom format "foo"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			formats, err := ParseFormats(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, f := range formats {
+				got = append(got, f.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseFormatsPredicateTypes(t *testing.T) {
+	formats, err := ParseFormats("spdx-json,cyclonedx-json,syft-json,cyclonedx-xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]string{
+		{intoto.PredicateSPDX, ".spdx.json"},
+		{intoto.PredicateCycloneDX, ".cdx.json"},
+		{PredicateSyft, ".syft.json"},
+		{PredicateCycloneDXXML, ".cdx.xml.json"},
+	}
+	for i, f := range formats {
+		if got := [2]string{f.PredicateType, f.Extension}; got != want[i] {
+			t.Errorf("%s: expected %v, got %v", f, want[i], got)
+		}
+	}
+}
+
+func TestDefaultFormatPredicate(t *testing.T) {
+	s := testSBOM()
+
+	formats, err := ParseFormats("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	predicate, err := formats[0].Predicate(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the default format must match the encoder the scanner used before
+	// formats were configurable
+	enc, err := spdxjson.NewFormatEncoderWithConfig(spdxjson.DefaultEncoderConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := format.Encode(s, enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := stripVolatileSPDX(t, predicate), stripVolatileSPDX(t, legacy); !reflect.DeepEqual(got, want) {
+		t.Fatalf("default format differs from legacy encoder:\n%v\n%v", got, want)
+	}
+}
+
+func TestFormatPredicate(t *testing.T) {
+	s := testSBOM()
+	for _, name := range []string{"spdx-json", "cyclonedx-json", "syft-json"} {
+		t.Run(name, func(t *testing.T) {
+			formats, err := ParseFormats(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			predicate, err := formats[0].Predicate(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stmt, err := json.Marshal(intoto.Statement{Predicate: predicate})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded struct {
+				Predicate map[string]interface{} `json:"predicate"`
+			}
+			if err := json.Unmarshal(stmt, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if len(decoded.Predicate) == 0 {
+				t.Fatalf("expected predicate to be a JSON object, got %s", predicate)
+			}
+			if !strings.Contains(string(predicate), "apk-tools") {
+				t.Fatalf("expected predicate to contain package, got %s", predicate)
+			}
+		})
+	}
+}
+
+func TestFormatPredicateXML(t *testing.T) {
+	formats, err := ParseFormats("cyclonedx-xml@1.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := formats[0]
+	predicate, err := f.Predicate(testSBOM())
+	if err != nil {
+		t.Fatal(err)
+	}
+	props := []property{{name: "exclude", values: []string{"./proc"}}}
+	predicate, err = finalizePredicate(f, predicate, props, nil, time.Unix(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wrapped XMLPredicate
+	if err := json.Unmarshal(predicate, &wrapped); err != nil {
+		t.Fatal(err)
+	}
+	if wrapped.MediaType != "application/vnd.cyclonedx+xml" {
+		t.Errorf("unexpected media type %q", wrapped.MediaType)
+	}
+	var bom cyclonedx.BOM
+	if err := cyclonedx.NewBOMDecoder(strings.NewReader(wrapped.Content), cyclonedx.BOMFileFormatXML).Decode(&bom); err != nil {
+		t.Fatalf("expected a cyclonedx xml document, got %v:\n%s", err, wrapped.Content)
+	}
+	if bom.XMLNS != "http://cyclonedx.org/schema/bom/1.5" {
+		t.Errorf("expected a cyclonedx 1.5 document, got namespace %q", bom.XMLNS)
+	}
+	if bom.Components == nil || len(*bom.Components) != 1 || (*bom.Components)[0].Name != "apk-tools" {
+		t.Errorf("expected the apk-tools component, got %+v", bom.Components)
+	}
+
+	// the document is finalized before it is converted
+	if bom.Metadata == nil || bom.Metadata.Timestamp != "1970-01-01T00:00:00Z" {
+		t.Errorf("expected a reproducible timestamp, got %+v", bom.Metadata)
+	}
+	var tools []string
+	for _, c := range *bom.Metadata.Tools.Components {
+		tools = append(tools, c.Name)
+	}
+	if !reflect.DeepEqual(tools, []string{"syft", "buildkit-syft-scanner"}) {
+		t.Errorf("expected the scanner to be recorded as a tool, got %v", tools)
+	}
+	if bom.Metadata.Properties == nil || !reflect.DeepEqual(*bom.Metadata.Properties, []cyclonedx.Property{{Name: "buildkit-syft-scanner:exclude", Value: "./proc"}}) {
+		t.Errorf("expected the properties to be recorded, got %+v", bom.Metadata.Properties)
+	}
+}
+
+func testSBOM() sbom.SBOM {
+	return sbom.SBOM{
+		Artifacts: sbom.Artifacts{
+			Packages: pkg.NewCollection(pkg.Package{
+				Name:    "apk-tools",
+				Version: "2.14.0-r5",
+				Type:    pkg.ApkPkg,
+				PURL:    "pkg:apk/alpine/apk-tools@2.14.0-r5",
+			}),
+		},
+		Source: source.Description{
+			Name:     "test",
+			Metadata: source.DirectoryMetadata{Path: "/test"},
+		},
+		Descriptor: sbom.Descriptor{
+			Name:    "syft",
+			Version: "v0.0.0",
+		},
+	}
+}
+
+// stripVolatileSPDX decodes an SPDX JSON document, removing the fields that
+// change on every encode.
+func stripVolatileSPDX(t *testing.T, dt []byte) map[string]interface{} {
+	t.Helper()
+	var doc map[string]interface{}
+	if err := json.Unmarshal(dt, &doc); err != nil {
+		t.Fatal(err)
+	}
+	delete(doc, "documentNamespace")
+	if info, ok := doc["creationInfo"].(map[string]interface{}); ok {
+		delete(info, "created")
+	}
+	return doc
+}
@@ -0,0 +1,290 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/pkg/errors"
+)
+
+// Layout reproduces, outside of BuildKit, the filesystem layout that BuildKit
+// provides to the scanner: the scanned root filesystem, a directory of extra
+// build stages, and a destination for the statements.
+type Layout struct {
+	Root        string
+	Source      string
+	Extras      string
+	Destination string
+}
+
+// NewLayout creates a Layout in a new temporary directory. src and the values
+// of extras, keyed by stage name, may each be a directory, a (possibly
+// gzipped) tarball of a root filesystem, or an OCI image layout. If dest is
+// empty, a destination is created inside the layout.
+func NewLayout(src string, extras map[string]string, dest string) (_ *Layout, retErr error) {
+	root, err := os.MkdirTemp("", "syft-scanner-layout-")
+	if err != nil {
+		return nil, err
+	}
+	l := &Layout{
+		Root:   root,
+		Source: filepath.Join(root, "core"),
+		Extras: filepath.Join(root, "extras"),
+	}
+	defer func() {
+		if retErr != nil {
+			l.Close()
+		}
+	}()
+
+	if err := materialize(src, l.Source); err != nil {
+		return nil, err
+	}
+	if err := os.Mkdir(l.Extras, 0o755); err != nil {
+		return nil, err
+	}
+	for name, p := range extras {
+		if name == "" || name != filepath.Base(name) {
+			return nil, errors.Errorf("invalid stage name %q", name)
+		}
+		if err := materialize(p, filepath.Join(l.Extras, name)); err != nil {
+			return nil, err
+		}
+	}
+
+	l.Destination = dest
+	if l.Destination == "" {
+		l.Destination = filepath.Join(root, "out")
+	}
+	if err := os.MkdirAll(l.Destination, 0o755); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Environ returns the variables BuildKit would set for this layout.
+func (l *Layout) Environ() map[string]string {
+	return map[string]string{
+		envScanSource:       l.Source,
+		envScanSourceExtras: l.Extras,
+		envScanDestination:  l.Destination,
+	}
+}
+
+// Close removes the layout, leaving the inputs and a destination outside the
+// layout untouched.
+func (l *Layout) Close() error {
+	return os.RemoveAll(l.Root)
+}
+
+// materialize makes the root filesystem in src available at dst.
+func materialize(src string, dst string) error {
+	fi, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return extractTarball(src, dst)
+	}
+	if _, err := os.Stat(filepath.Join(src, "oci-layout")); err == nil {
+		return extractOCILayout(src, dst)
+	}
+	abs, err := filepath.Abs(src)
+	if err != nil {
+		return err
+	}
+	return os.Symlink(abs, dst)
+}
+
+func extractTarball(src string, dst string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var rd io.Reader = r
+	if magic, err := r.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		rd = gz
+	}
+	return errors.Wrapf(extractTar(rd, dst), "failed to extract %q", src)
+}
+
+func extractOCILayout(src string, dst string) error {
+	idx, err := layout.ImageIndexFromPath(src)
+	if err != nil {
+		return err
+	}
+	img, err := findImage(idx)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read OCI layout %q", src)
+	}
+	rc := mutate.Extract(img)
+	defer rc.Close()
+	return errors.Wrapf(extractTar(rc, dst), "failed to extract OCI layout %q", src)
+}
+
+// findImage returns the image in idx for the current platform, or the first
+// image if none matches.
+func findImage(idx v1.ImageIndex) (v1.Image, error) {
+	manifest, err := idx.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	var first v1.Image
+	for _, desc := range manifest.Manifests {
+		var img v1.Image
+		switch {
+		case desc.MediaType.IsIndex():
+			child, err := idx.ImageIndex(desc.Digest)
+			if err != nil {
+				return nil, err
+			}
+			if img, err = findImage(child); err != nil {
+				continue
+			}
+		case desc.MediaType.IsImage():
+			if img, err = idx.Image(desc.Digest); err != nil {
+				return nil, err
+			}
+			if desc.Platform != nil && (desc.Platform.OS != runtime.GOOS || desc.Platform.Architecture != runtime.GOARCH) {
+				if first == nil {
+					first = img
+				}
+				continue
+			}
+		default:
+			continue
+		}
+		return img, nil
+	}
+	if first == nil {
+		return nil, errors.New("no image found")
+	}
+	return first, nil
+}
+
+// extractTar extracts the tar stream r into dst, refusing entries that would
+// escape it.
+func extractTar(r io.Reader, dst string) error {
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return err
+	}
+	dst, err := filepath.EvalSymlinks(dst)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Clean(filepath.FromSlash("/" + hdr.Name))
+		if name == string(filepath.Separator) {
+			continue
+		}
+		p := filepath.Join(dst, name)
+		// symlinks extracted earlier must not redirect writes outside dst
+		if ok, err := resolvesWithin(dst, filepath.Dir(p)); err != nil {
+			return err
+		} else if !ok {
+			return errors.Errorf("invalid entry %q, escapes root through a symlink", hdr.Name)
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return err
+		}
+		if fi, err := os.Lstat(p); err == nil && !fi.IsDir() {
+			os.Remove(p)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(p, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			f, err := os.OpenFile(p, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, hdr.FileInfo().Mode().Perm()|0o600)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.Symlink(hdr.Linkname, p); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			target := filepath.Join(dst, filepath.Clean(filepath.FromSlash("/"+hdr.Linkname)))
+			if ok, err := resolvesWithin(dst, target); err != nil {
+				return err
+			} else if !ok {
+				return errors.Errorf("invalid hardlink %q to %q", hdr.Name, hdr.Linkname)
+			}
+			if err := os.Link(target, p); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// resolvesWithin checks that the closest existing ancestor of p resolves to a
+// path inside root.
+func resolvesWithin(root string, p string) (bool, error) {
+	for {
+		resolved, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			return isWithin(root, resolved), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return false, err
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return false, nil
+		}
+		p = parent
+	}
+}
+
+func isWithin(root string, p string) bool {
+	return p == root || strings.HasPrefix(p, root+string(filepath.Separator))
+}
@@ -0,0 +1,164 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	body     string
+	linkname string
+}
+
+func writeTar(t *testing.T, entries ...tarEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{
+			Name:     e.name,
+			Typeflag: e.typeflag,
+			Linkname: e.linkname,
+			Mode:     0o644,
+			Size:     int64(len(e.body)),
+		}
+		if e.typeflag == tar.TypeDir {
+			hdr.Mode = 0o755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestNewLayout(t *testing.T) {
+	dir := t.TempDir()
+
+	rootfs := filepath.Join(dir, "rootfs")
+	if err := os.MkdirAll(filepath.Join(rootfs, "etc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tarPath := filepath.Join(dir, "stage.tar")
+	if err := os.WriteFile(tarPath, writeTar(t,
+		tarEntry{name: "etc/", typeflag: tar.TypeDir},
+		tarEntry{name: "etc/os-release", typeflag: tar.TypeReg, body: "ID=test\n"},
+		tarEntry{name: "etc/release", typeflag: tar.TypeSymlink, linkname: "os-release"},
+	), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(writeTar(t,
+			tarEntry{name: "usr/lib/os-release", typeflag: tar.TypeReg, body: "ID=oci\n"},
+		))), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := mutate.AppendLayers(empty.Image, layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ociPath := filepath.Join(dir, "oci")
+	p, err := layout.Write(ociPath, empty.Index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.AppendImage(img); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := NewLayout(rootfs, map[string]string{"tar": tarPath, "oci": ociPath}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if fi, err := os.Stat(filepath.Join(l.Source, "etc")); err != nil || !fi.IsDir() {
+		t.Errorf("expected core rootfs to be available, got %v", err)
+	}
+	if dt, err := os.ReadFile(filepath.Join(l.Extras, "tar", "etc", "release")); err != nil || string(dt) != "ID=test\n" {
+		t.Errorf("unexpected tarball content %q: %v", dt, err)
+	}
+	if dt, err := os.ReadFile(filepath.Join(l.Extras, "oci", "usr", "lib", "os-release")); err != nil || string(dt) != "ID=oci\n" {
+		t.Errorf("unexpected OCI layout content %q: %v", dt, err)
+	}
+	if fi, err := os.Stat(l.Destination); err != nil || !fi.IsDir() {
+		t.Errorf("expected destination to exist, got %v", err)
+	}
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(rootfs, "etc")); err != nil {
+		t.Errorf("expected closing the layout to keep its inputs, got %v", err)
+	}
+}
+
+func TestExtractTarEscapes(t *testing.T) {
+	outside := t.TempDir()
+	tests := map[string][]tarEntry{
+		"symlink": {
+			{name: "escape", typeflag: tar.TypeSymlink, linkname: outside},
+			{name: "escape/file", typeflag: tar.TypeReg, body: "x"},
+		},
+		"hardlink": {
+			{name: "escape", typeflag: tar.TypeSymlink, linkname: "/etc"},
+			{name: "file", typeflag: tar.TypeLink, linkname: "escape/passwd"},
+		},
+	}
+	for name, entries := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := extractTar(bytes.NewReader(writeTar(t, entries...)), t.TempDir())
+			if err == nil || !strings.Contains(err.Error(), "invalid") {
+				t.Fatalf("expected error, got %v", err)
+			}
+			if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+				t.Fatalf("expected nothing written outside the root, got %v", entries)
+			}
+		})
+	}
+
+	// parent traversal is confined to the root
+	dst := t.TempDir()
+	if err := extractTar(bytes.NewReader(writeTar(t, tarEntry{name: "../../file", typeflag: tar.TypeReg, body: "x"})), dst); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "file")); err != nil {
+		t.Fatal(err)
+	}
+}
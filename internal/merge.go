@@ -0,0 +1,198 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// MergedSuffix is appended to the name of the core target for the merged
+// statement, e.g. "sbom-merged.spdx.json".
+const MergedSuffix = "-merged"
+
+const spdxDocumentID = "SPDXRef-DOCUMENT"
+
+// spdxDoc is a decoded SPDX JSON document.
+type spdxDoc = map[string]interface{}
+
+// mergeSPDX merges the SPDX documents of the build stages into the document
+// of the core image, describing the whole build:
+//
+//   - the root element of each stage is a BUILD_DEPENDENCY_OF the root
+//     element of the core image,
+//   - each package is CONTAINed by the root element of every target it was
+//     found in, which records its origin,
+//   - packages found in several targets, identified by their purl, appear
+//     once.
+//
+// The documents are modified in place.
+func mergeSPDX(core spdxDoc, stages []spdxDoc) (spdxDoc, error) {
+	coreRoot, err := spdxRoot(core)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := core
+	ids := map[string]struct{}{}
+	byPURL := map[string]string{}
+	for _, p := range spdxElements(merged, "packages") {
+		id, _ := p["SPDXID"].(string)
+		ids[id] = struct{}{}
+		if purl := spdxPURL(p); purl != "" {
+			byPURL[purl] = id
+		}
+	}
+	for _, f := range spdxElements(merged, "files") {
+		id, _ := f["SPDXID"].(string)
+		ids[id] = struct{}{}
+	}
+	relationships := map[[4]string]struct{}{}
+	for _, r := range spdxElements(merged, "relationships") {
+		relationships[relationshipKey(r)] = struct{}{}
+	}
+	addRelationship := func(r map[string]interface{}) {
+		key := relationshipKey(r)
+		if _, ok := relationships[key]; ok {
+			return
+		}
+		relationships[key] = struct{}{}
+		merged["relationships"] = append(asList(merged["relationships"]), r)
+	}
+
+	for _, stage := range stages {
+		stageRoot, err := spdxRoot(stage)
+		if err != nil {
+			return nil, err
+		}
+		name, _ := stage["name"].(string)
+
+		// elements of the stage are renamed if they clash with an unrelated
+		// element of the same ID
+		rename := map[string]string{}
+		uniqueID := func(id string) string {
+			if _, ok := ids[id]; !ok {
+				return id
+			}
+			return id + "-" + spdxIDSafe.ReplaceAllString(name, "-")
+		}
+
+		var contained []string
+		for _, p := range spdxElements(stage, "packages") {
+			id, _ := p["SPDXID"].(string)
+			if id != stageRoot {
+				if existing, ok := byPURL[spdxPURL(p)]; ok {
+					rename[id] = existing
+					contained = append(contained, existing)
+					continue
+				}
+			}
+			newID := uniqueID(id)
+			rename[id] = newID
+			p["SPDXID"] = newID
+			ids[newID] = struct{}{}
+			if id != stageRoot {
+				if purl := spdxPURL(p); purl != "" {
+					byPURL[purl] = newID
+				}
+				contained = append(contained, newID)
+			}
+			merged["packages"] = append(asList(merged["packages"]), p)
+		}
+		for _, f := range spdxElements(stage, "files") {
+			id, _ := f["SPDXID"].(string)
+			newID := uniqueID(id)
+			rename[id] = newID
+			f["SPDXID"] = newID
+			ids[newID] = struct{}{}
+			merged["files"] = append(asList(merged["files"]), f)
+		}
+
+		addRelationship(map[string]interface{}{
+			"spdxElementId":      rename[stageRoot],
+			"relationshipType":   "BUILD_DEPENDENCY_OF",
+			"relatedSpdxElement": coreRoot,
+		})
+		for _, id := range contained {
+			addRelationship(map[string]interface{}{
+				"spdxElementId":      rename[stageRoot],
+				"relationshipType":   "CONTAINS",
+				"relatedSpdxElement": id,
+			})
+		}
+		for _, r := range spdxElements(stage, "relationships") {
+			from, _ := r["spdxElementId"].(string)
+			to, _ := r["relatedSpdxElement"].(string)
+			if from == spdxDocumentID {
+				continue
+			}
+			if from, to = rename[from], rename[to]; from == "" || to == "" {
+				continue
+			}
+			r["spdxElementId"], r["relatedSpdxElement"] = from, to
+			addRelationship(r)
+		}
+	}
+	return merged, nil
+}
+
+var spdxIDSafe = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+
+// spdxRoot returns the ID of the element described by doc.
+func spdxRoot(doc spdxDoc) (string, error) {
+	for _, r := range spdxElements(doc, "relationships") {
+		if r["spdxElementId"] == spdxDocumentID && r["relationshipType"] == "DESCRIBES" {
+			// the source of the build is described too, but is not the root
+			if id, ok := r["relatedSpdxElement"].(string); ok && id != spdxBuildSourceID {
+				return id, nil
+			}
+		}
+	}
+	return "", errors.Errorf("spdx document %q describes no element", doc["name"])
+}
+
+func spdxPURL(p map[string]interface{}) string {
+	for _, ref := range spdxElements(p, "externalRefs") {
+		if ref["referenceType"] == "purl" {
+			purl, _ := ref["referenceLocator"].(string)
+			return purl
+		}
+	}
+	return ""
+}
+
+func spdxElements(doc map[string]interface{}, key string) []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, v := range asList(doc[key]) {
+		if obj, ok := v.(map[string]interface{}); ok {
+			out = append(out, obj)
+		}
+	}
+	return out
+}
+
+func asList(v interface{}) []interface{} {
+	list, _ := v.([]interface{})
+	return list
+}
+
+func relationshipKey(r map[string]interface{}) [4]string {
+	from, _ := r["spdxElementId"].(string)
+	typ, _ := r["relationshipType"].(string)
+	to, _ := r["relatedSpdxElement"].(string)
+	comment, _ := r["comment"].(string)
+	return [4]string{from, typ, to, comment}
+}
@@ -0,0 +1,101 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestMergeSPDX(t *testing.T) {
+	doc := func(name string, packages string, relationships string) spdxDoc {
+		var d spdxDoc
+		dt := `{
+			"name": "` + name + `",
+			"packages": [{"SPDXID": "SPDXRef-DocumentRoot-Directory-` + name + `"}` + packages + `],
+			"relationships": [
+				{"spdxElementId": "SPDXRef-DOCUMENT", "relationshipType": "DESCRIBES", "relatedSpdxElement": "SPDXRef-DocumentRoot-Directory-` + name + `"}` + relationships + `
+			]
+		}`
+		if err := json.Unmarshal([]byte(dt), &d); err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	purl := func(s string) string {
+		return `, "externalRefs": [{"referenceType": "purl", "referenceLocator": "` + s + `"}]`
+	}
+
+	core := doc("core",
+		`, {"SPDXID": "SPDXRef-a"`+purl("pkg:generic/a@1")+`}, {"SPDXID": "SPDXRef-b"}`,
+		`, {"spdxElementId": "SPDXRef-DocumentRoot-Directory-core", "relationshipType": "CONTAINS", "relatedSpdxElement": "SPDXRef-a"}`)
+	stage := doc("build",
+		// a is the same package as in core under another ID, b is an
+		// unrelated package clashing with an ID of core
+		`, {"SPDXID": "SPDXRef-a2"`+purl("pkg:generic/a@1")+`}, {"SPDXID": "SPDXRef-b"}, {"SPDXID": "SPDXRef-c"}`,
+		`, {"spdxElementId": "SPDXRef-c", "relationshipType": "DEPENDENCY_OF", "relatedSpdxElement": "SPDXRef-a2"}`)
+
+	merged, err := mergeSPDX(core, []spdxDoc{stage})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, p := range spdxElements(merged, "packages") {
+		ids = append(ids, p["SPDXID"].(string))
+	}
+	sort.Strings(ids)
+	if got, want := strings.Join(ids, " "), "SPDXRef-DocumentRoot-Directory-build SPDXRef-DocumentRoot-Directory-core SPDXRef-a SPDXRef-b SPDXRef-b-build SPDXRef-c"; got != want {
+		t.Errorf("expected packages %s, got %s", want, got)
+	}
+
+	var relationships []string
+	for _, r := range spdxElements(merged, "relationships") {
+		relationships = append(relationships, strings.Join([]string{r["spdxElementId"].(string), r["relationshipType"].(string), r["relatedSpdxElement"].(string)}, " "))
+	}
+	sort.Strings(relationships)
+	want := []string{
+		"SPDXRef-DOCUMENT DESCRIBES SPDXRef-DocumentRoot-Directory-core",
+		"SPDXRef-DocumentRoot-Directory-build BUILD_DEPENDENCY_OF SPDXRef-DocumentRoot-Directory-core",
+		"SPDXRef-DocumentRoot-Directory-build CONTAINS SPDXRef-a",
+		"SPDXRef-DocumentRoot-Directory-build CONTAINS SPDXRef-b-build",
+		"SPDXRef-DocumentRoot-Directory-build CONTAINS SPDXRef-c",
+		"SPDXRef-DocumentRoot-Directory-core CONTAINS SPDXRef-a",
+		"SPDXRef-c DEPENDENCY_OF SPDXRef-a",
+	}
+	if got := strings.Join(relationships, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("unexpected relationships:\n%s", got)
+	}
+}
+
+func TestScannerMergeRequiresSPDX(t *testing.T) {
+	formats, err := ParseFormats("cyclonedx-json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner := Scanner{
+		Core:        Target{Path: t.TempDir()},
+		Destination: t.TempDir(),
+		Formats:     formats,
+		Merge:       true,
+	}
+	err = scanner.Scan(context.Background())
+	if ErrorKindOf(err) != ErrorConfig || !strings.Contains(err.Error(), "merging requires the spdx-json format") {
+		t.Fatalf("expected config error, got %v", err)
+	}
+}
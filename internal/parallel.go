@@ -0,0 +1,145 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/anchore/syft/syft/sbom"
+	"github.com/sirupsen/logrus"
+)
+
+// memoryPerTarget is a rough estimate of the memory needed to catalog a
+// single target, used to bound the default parallelism.
+const memoryPerTarget = 1 << 30
+
+// scanTargets scans targets with scan, with at most parallelism scans running
+// at once. Results are returned in the order of targets, regardless of the
+// order in which scans complete, and the errors of all failed scans are
+// joined. No scan starts once ctx is cancelled.
+func scanTargets(ctx context.Context, targets []Target, parallelism int, scan func(Target, context.Context) (sbom.SBOM, error)) ([]sbom.SBOM, error) {
+	if parallelism <= 0 {
+		parallelism = defaultParallelism(len(targets))
+	}
+	logrus.Debugf("scanning %d targets with parallelism %d", len(targets), parallelism)
+
+	results := make([]sbom.SBOM, len(targets))
+	errs := make([]error, len(targets))
+	sem := make(chan struct{}, parallelism)
+
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := acquire(ctx, sem); err != nil {
+				errs[i] = fmt.Errorf("failed to scan %q: %w", target.Name(), err)
+				return
+			}
+			defer func() { <-sem }()
+
+			result, err := scan(target, ctx)
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to scan %q: %w", target.Name(), err)
+				return
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// acquire takes a slot of sem, unless ctx is cancelled before or while
+// waiting for it.
+func acquire(ctx context.Context, sem chan struct{}) error {
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+	// select picks randomly when both cases are ready
+	if ctx.Err() != nil {
+		<-sem
+		return context.Cause(ctx)
+	}
+	return nil
+}
+
+// defaultParallelism bounds the number of concurrent scans by the number of
+// CPUs and by the memory available to the scanner.
+func defaultParallelism(targets int) int {
+	p := runtime.NumCPU()
+	if mem, ok := availableMemory(); ok {
+		p = min(p, int(mem/memoryPerTarget))
+	}
+	return max(min(p, targets), 1)
+}
+
+// availableMemory returns the memory available to the scanner, taking the
+// cgroup limit into account when running in a container.
+func availableMemory() (uint64, bool) {
+	var limit uint64
+	for _, p := range []string{
+		"/sys/fs/cgroup/memory.max",                   // cgroup v2
+		"/sys/fs/cgroup/memory/memory.limit_in_bytes", // cgroup v1
+	} {
+		dt, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		if v, err := strconv.ParseUint(strings.TrimSpace(string(dt)), 10, 64); err == nil {
+			limit = v
+			break
+		}
+	}
+
+	dt, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return limit, limit > 0
+	}
+	s := bufio.NewScanner(bytes.NewReader(dt))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 2 || fields[0] != "MemAvailable:" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			break
+		}
+		if available := kb * 1024; limit == 0 || available < limit {
+			limit = available
+		}
+		break
+	}
+	return limit, limit > 0
+}
@@ -0,0 +1,131 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/anchore/syft/syft/sbom"
+
+	// register sqlite driver for RPMDBs scan support with syft
+	_ "modernc.org/sqlite"
+)
+
+func TestScanTargets(t *testing.T) {
+	root := t.TempDir()
+	var targets []Target
+	for _, name := range []string{"core", "stage1", "stage2", "stage3"} {
+		p := filepath.Join(root, name)
+		if err := os.Mkdir(p, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		targets = append(targets, Target{Path: p})
+	}
+
+	results, err := scanTargets(context.Background(), targets, 2, Target.Scan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, result := range results {
+		if result.Source.Name != targets[i].Name() {
+			t.Errorf("result %d: expected source %q, got %q", i, targets[i].Name(), result.Source.Name)
+		}
+	}
+}
+
+func TestScanTargetsErrors(t *testing.T) {
+	root := t.TempDir()
+	targets := []Target{
+		{Path: root},
+		{Path: filepath.Join(root, "missing1")},
+		{Path: filepath.Join(root, "missing2")},
+	}
+
+	_, err := scanTargets(context.Background(), targets, 1, Target.Scan)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, name := range []string{"missing1", "missing2"} {
+		if !strings.Contains(err.Error(), `failed to scan "`+name+`"`) {
+			t.Errorf("expected error for %s, got %v", name, err)
+		}
+	}
+}
+
+func TestDefaultParallelism(t *testing.T) {
+	if p := defaultParallelism(1); p != 1 {
+		t.Errorf("expected parallelism 1 for a single target, got %d", p)
+	}
+	if p := defaultParallelism(1000); p < 1 {
+		t.Errorf("expected positive parallelism, got %d", p)
+	}
+}
+
+func TestScanTargetsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(errors.New("build cancelled"))
+
+	var targets []Target
+	for _, name := range []string{"core", "stage1", "stage2", "stage3"} {
+		targets = append(targets, Target{Path: filepath.Join(t.TempDir(), name)})
+	}
+	var started atomic.Int32
+	scan := func(Target, context.Context) (sbom.SBOM, error) {
+		started.Add(1)
+		return sbom.SBOM{}, nil
+	}
+
+	// repeated, as a scan starting after cancellation used to depend on
+	// which case of a select was picked
+	for range 100 {
+		_, err := scanTargets(ctx, targets, 2, scan)
+		if err == nil || !strings.Contains(err.Error(), "build cancelled") {
+			t.Fatalf("expected cancellation error, got %v", err)
+		}
+	}
+	if n := started.Load(); n != 0 {
+		t.Fatalf("expected no scan to start after cancellation, %d started", n)
+	}
+}
+
+func TestScanTargetsCancelledWhileScanning(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	var targets []Target
+	for _, name := range []string{"core", "stage1", "stage2", "stage3"} {
+		targets = append(targets, Target{Path: filepath.Join(t.TempDir(), name)})
+	}
+	var started atomic.Int32
+	scan := func(Target, context.Context) (sbom.SBOM, error) {
+		started.Add(1)
+		cancel(errors.New("build cancelled"))
+		return sbom.SBOM{}, nil
+	}
+
+	_, err := scanTargets(ctx, targets, 1, scan)
+	if err == nil || !strings.Contains(err.Error(), "build cancelled") {
+		t.Fatalf("expected cancellation error, got %v", err)
+	}
+	if n := started.Load(); n != 1 {
+		t.Fatalf("expected only the scan running at cancellation to start, %d started", n)
+	}
+}
@@ -0,0 +1,524 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/anchore/syft/syft/format/common/spdxhelpers"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/sbom"
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// PredicatePolicyResult is the in-toto predicate type of the policy result
+// statement.
+const PredicatePolicyResult = "https://github.com/docker/buildkit-syft-scanner/policy-result/v1"
+
+// PolicySuffix is appended to the name of the core target for the policy
+// result statement, e.g. "sbom.policy.json".
+const PolicySuffix = ".policy.json"
+
+// PolicyAction is what the scanner does with the violations of a policy.
+type PolicyAction string
+
+const (
+	// PolicyFail fails the scan if the policy is violated.
+	PolicyFail PolicyAction = "fail"
+	// PolicyAttest emits a policy result statement next to the SBOMs.
+	PolicyAttest PolicyAction = "attest"
+	// PolicyAnnotate records the violations in the SBOMs of the image.
+	PolicyAnnotate PolicyAction = "annotate"
+)
+
+// Policy is a set of rules the packages of the image must follow.
+type Policy struct {
+	Action   PolicyAction  `yaml:"action" json:"action"`
+	Licenses LicensePolicy `yaml:"licenses" json:"licenses"`
+	Packages PackagePolicy `yaml:"packages" json:"packages"`
+
+	// name is the path of the policy file.
+	name string
+}
+
+// LicensePolicy restricts the licenses of packages.
+type LicensePolicy struct {
+	// Deny are globs of SPDX license identifiers that are not allowed,
+	// e.g. "AGPL-*".
+	Deny []string `yaml:"deny" json:"deny"`
+	// DenyUnknown rejects packages without a license that resolves to an
+	// SPDX identifier.
+	DenyUnknown bool `yaml:"deny-unknown" json:"deny-unknown"`
+}
+
+// PackagePolicy restricts the packages themselves.
+type PackagePolicy struct {
+	Deny []PackageRule `yaml:"deny" json:"deny"`
+	// RequireSupplier rejects packages without a known supplier.
+	RequireSupplier bool `yaml:"require-supplier" json:"require-supplier"`
+}
+
+// PackageRule matches packages by purl, and optionally version.
+type PackageRule struct {
+	// PURL is a glob matched against the purl of packages, without its
+	// version, qualifiers and subpath, e.g. "pkg:npm/event-stream".
+	PURL string `yaml:"purl" json:"purl"`
+	// Versions is a semver constraint, e.g. ">=3.3.6, <4", or for deb, rpm
+	// and apk packages, comparisons of versions ordered as their package
+	// manager orders them, e.g. ">=2.36-9, <2.36-9+deb12u7". If empty, all
+	// versions match.
+	Versions string `yaml:"versions" json:"versions"`
+	// Reason is reported along with violations.
+	Reason string `yaml:"reason" json:"reason"`
+
+	constraints *semver.Constraints
+	comparisons versionConstraint
+}
+
+// distroTypes are the purl types of packages whose versions are not semver.
+var distroTypes = map[string]struct{}{"deb": {}, "rpm": {}, "apk": {}}
+
+// Violation is a package breaking a rule of a policy.
+type Violation struct {
+	Rule      string   `json:"rule"`
+	Package   string   `json:"package"`
+	PURL      string   `json:"purl,omitempty"`
+	Message   string   `json:"message"`
+	Locations []string `json:"locations,omitempty"`
+}
+
+func (v Violation) String() string {
+	s := fmt.Sprintf("%s: %s: %s", v.Rule, v.Package, v.Message)
+	if len(v.Locations) > 0 {
+		s += " (" + strings.Join(v.Locations, ", ") + ")"
+	}
+	return s
+}
+
+// PolicyResult is the predicate of the policy result statement.
+type PolicyResult struct {
+	Policy     string      `json:"policy"`
+	Passed     bool        `json:"passed"`
+	Violations []Violation `json:"violations"`
+}
+
+// ParsePolicy parses a YAML or JSON policy.
+func ParsePolicy(dt []byte, isJSON bool) (*Policy, error) {
+	policy := Policy{Action: PolicyFail}
+	if isJSON {
+		dec := json.NewDecoder(bytes.NewReader(dt))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&policy); err != nil {
+			return nil, err
+		}
+	} else if len(bytes.TrimSpace(dt)) > 0 {
+		dec := yaml.NewDecoder(bytes.NewReader(dt))
+		dec.KnownFields(true)
+		if err := dec.Decode(&policy); err != nil {
+			return nil, err
+		}
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// LoadPolicy reads the policy file at name inside the filesystem rooted at
+// root.
+func LoadPolicy(root string, name string) (*Policy, error) {
+	dt, err := os.ReadFile(filepath.Join(root, filepath.Clean("/"+name)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read policy %q", name)
+	}
+	policy, err := ParsePolicy(dt, strings.EqualFold(filepath.Ext(name), ".json"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid policy %q", name)
+	}
+	policy.name = filepath.ToSlash(filepath.Clean("/" + name))
+	return policy, nil
+}
+
+// Validate checks the policy, and compiles its version constraints.
+func (p *Policy) Validate() error {
+	switch p.Action {
+	case PolicyFail, PolicyAttest, PolicyAnnotate:
+	default:
+		return errors.Errorf("action: must be one of fail, attest, annotate, got %q", p.Action)
+	}
+	for _, l := range p.Licenses.Deny {
+		if l == "" || !doublestar.ValidatePattern(l) {
+			return errors.Errorf("licenses.deny: invalid glob %q", l)
+		}
+	}
+	for i := range p.Packages.Deny {
+		rule := &p.Packages.Deny[i]
+		if !strings.HasPrefix(rule.PURL, "pkg:") || !doublestar.ValidatePattern(rule.PURL) {
+			return errors.Errorf("packages.deny: invalid purl %q", rule.PURL)
+		}
+		if rule.Versions == "" {
+			continue
+		}
+		// a type that is a glob may match packages of both kinds
+		typ := purlType(rule.PURL)
+		_, distro := distroTypes[typ]
+		glob := strings.ContainsAny(typ, `*?[{\`)
+		if distro || glob {
+			c, err := parseVersionConstraint(rule.Versions)
+			if err != nil {
+				return errors.Wrapf(err, "packages.deny: invalid versions %q for %q", rule.Versions, rule.PURL)
+			}
+			rule.comparisons = c
+		}
+		if !distro {
+			c, err := semver.NewConstraint(rule.Versions)
+			if err != nil {
+				return errors.Wrapf(err, "packages.deny: invalid versions %q for %q", rule.Versions, rule.PURL)
+			}
+			rule.constraints = c
+		}
+	}
+	return nil
+}
+
+// Evaluate returns the violations of the policy by the packages of s.
+func (p *Policy) Evaluate(s sbom.SBOM) []Violation {
+	var suppliers map[string]string
+	if p.Packages.RequireSupplier {
+		suppliers = packageSuppliers(s)
+	}
+
+	var violations []Violation
+	for _, pk := range s.Artifacts.Packages.Sorted() {
+		violation := func(rule, message string) {
+			v := Violation{
+				Rule:    rule,
+				Package: pk.Name + "@" + pk.Version,
+				PURL:    pk.PURL,
+				Message: message,
+			}
+			for _, l := range pk.Locations.ToSlice() {
+				v.Locations = append(v.Locations, l.RealPath)
+			}
+			violations = append(violations, v)
+		}
+
+		exprs, unknown := packageLicenses(pk)
+		var denied []string
+		for _, e := range exprs {
+			denied = append(denied, e.denied(p.Licenses.Deny)...)
+		}
+		sort.Strings(denied)
+		for _, id := range slices.Compact(denied) {
+			violation("license", fmt.Sprintf("license %s is denied", id))
+		}
+		if p.Licenses.DenyUnknown && (len(exprs) == 0 || len(unknown) > 0) {
+			if len(unknown) > 0 {
+				violation("unknown-license", fmt.Sprintf("license %s is not a known SPDX license", strings.Join(unknown, ", ")))
+			} else {
+				violation("unknown-license", "no license found")
+			}
+		}
+
+		for _, rule := range p.Packages.Deny {
+			if rule.matches(pk) {
+				message := "package is denied by " + rule.PURL
+				if rule.Versions != "" {
+					message += " " + rule.Versions
+				}
+				if rule.Reason != "" {
+					message += ": " + rule.Reason
+				}
+				violation("package", message)
+			}
+		}
+
+		if p.Packages.RequireSupplier {
+			if supplier := suppliers[string(pk.ID())]; supplier == "" || supplier == "NOASSERTION" {
+				violation("supplier", "no supplier found")
+			}
+		}
+	}
+	return violations
+}
+
+func (r PackageRule) matches(p pkg.Package) bool {
+	if p.PURL == "" {
+		return false
+	}
+	base, version := splitPURL(p.PURL)
+	if ok, _ := doublestar.Match(r.PURL, base); !ok {
+		return false
+	}
+	if r.Versions == "" {
+		return true
+	}
+	if version == "" {
+		version = p.Version
+	}
+	typ := purlType(p.PURL)
+	if _, ok := distroTypes[typ]; ok {
+		return r.comparisons != nil && r.comparisons.check(version, versionComparator(typ))
+	}
+	if r.constraints == nil {
+		return false
+	}
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+	return r.constraints.Check(v)
+}
+
+// versionConstraint is a constraint on versions of any scheme: comparisons
+// joined by "," that must all hold, alternatives of which are joined by
+// "||".
+type versionConstraint [][]versionComparison
+
+type versionComparison struct {
+	op      string
+	version string
+}
+
+func parseVersionConstraint(s string) (versionConstraint, error) {
+	var c versionConstraint
+	for _, alternative := range strings.Split(s, "||") {
+		var comparisons []versionComparison
+		for _, comparison := range strings.Split(alternative, ",") {
+			comparison = strings.TrimSpace(comparison)
+			op := "="
+			for _, o := range []string{">=", "<=", "!=", "==", ">", "<", "="} {
+				if strings.HasPrefix(comparison, o) {
+					op, comparison = o, strings.TrimSpace(comparison[len(o):])
+					break
+				}
+			}
+			if comparison == "" || !isDigit(comparison[0]) || strings.ContainsAny(comparison, " <>=!") {
+				return nil, errors.Errorf("invalid version comparison %q, must be an operator among =, !=, <, <=, >, >= and a version", comparison)
+			}
+			comparisons = append(comparisons, versionComparison{op: op, version: comparison})
+		}
+		c = append(c, comparisons)
+	}
+	return c, nil
+}
+
+// check reports whether version v satisfies c, with versions compared by
+// compare.
+func (c versionConstraint) check(v string, compare func(a, b string) int) bool {
+	for _, comparisons := range c {
+		ok := true
+		for _, comparison := range comparisons {
+			n := compare(v, comparison.version)
+			switch comparison.op {
+			case "=", "==":
+				ok = n == 0
+			case "!=":
+				ok = n != 0
+			case "<":
+				ok = n < 0
+			case "<=":
+				ok = n <= 0
+			case ">":
+				ok = n > 0
+			case ">=":
+				ok = n >= 0
+			}
+			if !ok {
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPURL returns purl without its version, qualifiers and subpath, and
+// its version.
+func splitPURL(purl string) (string, string) {
+	if i := strings.IndexAny(purl, "?#"); i >= 0 {
+		purl = purl[:i]
+	}
+	if i := strings.LastIndex(purl, "@"); i > strings.LastIndex(purl, "/") {
+		return purl[:i], purl[i+1:]
+	}
+	return purl, ""
+}
+
+// packageLicenses returns the SPDX expressions of the licenses of p, and
+// the licenses that are not SPDX expressions.
+func packageLicenses(p pkg.Package) (exprs []licenseExpr, unknown []string) {
+	for _, l := range p.Licenses.ToSlice() {
+		if l.SPDXExpression == "" {
+			if l.Value != "" {
+				unknown = append(unknown, l.Value)
+			}
+			continue
+		}
+		e, err := parseLicenseExpr(l.SPDXExpression)
+		if err != nil {
+			unknown = append(unknown, l.SPDXExpression)
+			continue
+		}
+		exprs = append(exprs, e)
+	}
+	sort.Strings(unknown)
+	return exprs, unknown
+}
+
+// licenseExpr is an SPDX license expression: either a license identifier,
+// or operands joined by AND or OR.
+type licenseExpr struct {
+	// id is the license identifier, without its "+" suffix and exception.
+	id       string
+	op       string
+	operands []licenseExpr
+}
+
+// parseLicenseExpr parses an SPDX license expression, in which AND takes
+// precedence over OR.
+func parseLicenseExpr(s string) (licenseExpr, error) {
+	p := licenseParser{tokens: strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(s))}
+	e, err := p.parse("OR")
+	if err == nil && p.pos < len(p.tokens) {
+		err = errors.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	if err != nil {
+		return licenseExpr{}, errors.Wrapf(err, "invalid license expression %q", s)
+	}
+	return e, nil
+}
+
+type licenseParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *licenseParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// parse parses operands joined by op: AND expressions for OR, and licenses
+// or parenthesized expressions for AND.
+func (p *licenseParser) parse(op string) (licenseExpr, error) {
+	var operands []licenseExpr
+	for {
+		var e licenseExpr
+		var err error
+		if op == "OR" {
+			e, err = p.parse("AND")
+		} else {
+			e, err = p.operand()
+		}
+		if err != nil {
+			return licenseExpr{}, err
+		}
+		operands = append(operands, e)
+		if !strings.EqualFold(p.peek(), op) {
+			break
+		}
+		p.pos++
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return licenseExpr{op: op, operands: operands}, nil
+}
+
+func (p *licenseParser) operand() (licenseExpr, error) {
+	token := p.peek()
+	switch strings.ToUpper(token) {
+	case "(":
+		p.pos++
+		e, err := p.parse("OR")
+		if err != nil {
+			return licenseExpr{}, err
+		}
+		if p.peek() != ")" {
+			return licenseExpr{}, errors.New("missing )")
+		}
+		p.pos++
+		return e, nil
+	case "", ")", "AND", "OR", "WITH":
+		return licenseExpr{}, errors.Errorf("expected a license, got %q", token)
+	}
+	p.pos++
+	if strings.EqualFold(p.peek(), "WITH") {
+		// skip the exception
+		if p.pos+1 >= len(p.tokens) {
+			return licenseExpr{}, errors.New("missing exception")
+		}
+		p.pos += 2
+	}
+	return licenseExpr{id: strings.TrimSuffix(token, "+")}, nil
+}
+
+// denied returns the identifiers of e matching the globs of deny if e is
+// denied: an OR expression is denied if all its operands are, as the
+// package can be used under any of them, and an AND expression if any of
+// them is.
+func (e licenseExpr) denied(deny []string) []string {
+	if e.op == "" {
+		for _, pattern := range deny {
+			if ok, _ := doublestar.Match(strings.ToLower(pattern), strings.ToLower(e.id)); ok {
+				return []string{e.id}
+			}
+		}
+		return nil
+	}
+	var ids []string
+	for _, operand := range e.operands {
+		denied := operand.denied(deny)
+		if len(denied) == 0 && e.op == "OR" {
+			return nil
+		}
+		ids = append(ids, denied...)
+	}
+	return ids
+}
+
+// packageSuppliers returns the supplier of each package of s, by package
+// ID, as recorded in SPDX documents.
+func packageSuppliers(s sbom.SBOM) map[string]string {
+	suppliers := map[string]string{}
+	doc := spdxhelpers.ToFormatModel(s)
+	if doc == nil {
+		return suppliers
+	}
+	for _, p := range doc.Packages {
+		if p == nil || p.PackageSupplier == nil {
+			continue
+		}
+		// SPDX IDs of packages end with the package ID
+		id := string(p.PackageSPDXIdentifier)
+		suppliers[id[strings.LastIndex(id, "-")+1:]] = p.PackageSupplier.Supplier
+	}
+	return suppliers
+}
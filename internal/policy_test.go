@@ -0,0 +1,229 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/anchore/syft/syft/file"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/sbom"
+)
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy([]byte(`
+licenses:
+  deny: [GPL-3.0-*, AGPL-*]
+packages:
+  deny:
+    - purl: pkg:npm/event-stream
+      versions: ">=3.3.6, <4"
+`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.Action != PolicyFail {
+		t.Errorf("expected the default action to be %q, got %q", PolicyFail, policy.Action)
+	}
+
+	for name, dt := range map[string]string{
+		"action":   `action: warn`,
+		"license":  `licenses: {deny: ["["]}`,
+		"purl":     `packages: {deny: [{purl: npm/event-stream}]}`,
+		"versions": `packages: {deny: [{purl: pkg:npm/event-stream, versions: "not a range"}]}`,
+		"distro":   `packages: {deny: [{purl: pkg:deb/debian/libc6, versions: "^2.36"}]}`,
+		"glob":     `packages: {deny: [{purl: "pkg:*/openssl", versions: "~3.0"}]}`,
+		"unknown":  `licences: {deny: [GPL-3.0-only]}`,
+	} {
+		if _, err := ParsePolicy([]byte(dt), false); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	ctx := context.Background()
+	newPackage := func(name, version, purl string, licenses ...string) pkg.Package {
+		p := pkg.Package{
+			Name:      name,
+			Version:   version,
+			PURL:      purl,
+			Type:      pkg.NpmPkg,
+			Licenses:  pkg.NewLicenseSet(pkg.NewLicensesFromValuesWithContext(ctx, licenses...)...),
+			Locations: file.NewLocationSet(file.NewLocation("/app/package-lock.json")),
+		}
+		p.SetID()
+		return p
+	}
+	s := sbom.SBOM{Artifacts: sbom.Artifacts{Packages: pkg.NewCollection(
+		newPackage("ms", "2.1.3", "pkg:npm/ms@2.1.3", "MIT"),
+		newPackage("event-stream", "3.3.6", "pkg:npm/event-stream@3.3.6", "MIT"),
+		newPackage("event-stream-fork", "3.3.6", "pkg:npm/event-stream-fork@3.3.6", "MIT"),
+		newPackage("dual", "1.0.0", "pkg:npm/dual@1.0.0", "(MIT OR GPL-3.0-or-later)"),
+		newPackage("gpl", "1.0.0", "pkg:npm/gpl@1.0.0", "GPL-3.0-or-later OR WTFPL"),
+		newPackage("gpl-and", "1.0.0", "pkg:npm/gpl-and@1.0.0", "MIT AND (Apache-2.0 OR GPL-3.0-only WITH Classpath-exception-2.0)"),
+		newPackage("gpl-with", "1.0.0", "pkg:npm/gpl-with@1.0.0", "GPL-3.0-only WITH Classpath-exception-2.0 AND (WTFPL OR MIT)"),
+		newPackage("libc6", "2.36-9+deb12u4", "pkg:deb/debian/libc6@2.36-9+deb12u4?arch=amd64&upstream=glibc&distro=debian-12", "LGPL-2.1-only"),
+		newPackage("musl", "1.2.4_git20230717-r4", "pkg:apk/alpine/musl@1.2.4_git20230717-r4?arch=x86_64&distro=alpine-3.19.1", "MIT"),
+		newPackage("openssl", "3.0.11-1~deb12u2", "pkg:deb/debian/openssl@3.0.11-1~deb12u2?arch=amd64&distro=debian-12", "Apache-2.0"),
+		newPackage("unlicensed", "1.0.0", "pkg:npm/unlicensed@1.0.0"),
+		newPackage("custom", "1.0.0", "pkg:npm/custom@1.0.0", "see LICENSE.txt"),
+		newPackage("left-pad", "1.3.0", "pkg:npm/left-pad@1.3.0?repository_url=https://example.com", "WTFPL"),
+	)}}
+
+	policy, err := ParsePolicy([]byte(`
+licenses:
+  deny: [gpl-3.0-*, WTFPL]
+  deny-unknown: true
+packages:
+  deny:
+    - purl: pkg:npm/event-stream
+      versions: ">=3.3.6, <4"
+      reason: compromised
+    - purl: pkg:npm/left-*
+    - purl: pkg:npm/ms
+      versions: "<2"
+    - purl: pkg:deb/debian/libc6
+      versions: ">=2.36-9, <2.36-9+deb12u7"
+    - purl: pkg:apk/alpine/musl
+      versions: "<1.2.4_git20230717-r5"
+    - purl: "pkg:*/openssl"
+      versions: ">=3.0.11-1"
+`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, v := range policy.Evaluate(s) {
+		got = append(got, v.String())
+	}
+	want := []string{
+		"unknown-license: custom@1.0.0: license see LICENSE.txt is not a known SPDX license (/app/package-lock.json)",
+		"package: event-stream@3.3.6: package is denied by pkg:npm/event-stream >=3.3.6, <4: compromised (/app/package-lock.json)",
+		"license: gpl@1.0.0: license GPL-3.0-or-later is denied (/app/package-lock.json)",
+		"license: gpl@1.0.0: license WTFPL is denied (/app/package-lock.json)",
+		"license: gpl-with@1.0.0: license GPL-3.0-only is denied (/app/package-lock.json)",
+		"license: left-pad@1.3.0: license WTFPL is denied (/app/package-lock.json)",
+		"package: left-pad@1.3.0: package is denied by pkg:npm/left-* (/app/package-lock.json)",
+		"package: libc6@2.36-9+deb12u4: package is denied by pkg:deb/debian/libc6 >=2.36-9, <2.36-9+deb12u7 (/app/package-lock.json)",
+		"package: musl@1.2.4_git20230717-r4: package is denied by pkg:apk/alpine/musl <1.2.4_git20230717-r5 (/app/package-lock.json)",
+		"unknown-license: unlicensed@1.0.0: no license found (/app/package-lock.json)",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("unexpected violations:\n%s\nexpected:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestScanPolicy(t *testing.T) {
+	core := buildFixture(t, filepath.Join(t.TempDir(), "sbom"), alpineFixture, files(map[string]string{
+		"etc/policy.yaml": `
+licenses:
+  deny: [GPL-*]
+packages:
+  require-supplier: true
+`,
+	}))
+	formats, err := ParseFormats("spdx-json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scan := func(t *testing.T, action PolicyAction) (string, error) {
+		policy, err := LoadPolicy(core, "/etc/policy.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		policy.Action = action
+		scanner := Scanner{
+			Core:        Target{Path: core},
+			Destination: t.TempDir(),
+			Formats:     formats,
+			Policy:      policy,
+		}
+		return scanner.Destination, scanner.Scan(context.Background())
+	}
+
+	t.Run("fail", func(t *testing.T) {
+		dest, err := scan(t, PolicyFail)
+		if ErrorKindOf(err) != ErrorPolicy {
+			t.Fatalf("expected a policy error, got %v", err)
+		}
+		if ErrorKindOf(err).ExitCode() != 7 {
+			t.Errorf("unexpected exit code %d", ErrorKindOf(err).ExitCode())
+		}
+		want := `policy "/etc/policy.yaml" violated (2 violations):
+license: busybox@1.36.1-r15: license GPL-2.0-only is denied (/lib/apk/db/installed)
+supplier: busybox@1.36.1-r15: no supplier found (/lib/apk/db/installed)`
+		if err.Error() != want {
+			t.Errorf("unexpected error:\n%s\nexpected:\n%s", err, want)
+		}
+		if _, err := os.Stat(filepath.Join(dest, "sbom.spdx.json")); !os.IsNotExist(err) {
+			t.Errorf("expected no statement to be written")
+		}
+	})
+
+	t.Run("attest", func(t *testing.T) {
+		dest, err := scan(t, PolicyAttest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertStatement(t, filepath.Join(dest, "sbom"+PolicySuffix), `{
+			"_type": "https://in-toto.io/Statement/v1",
+			"predicateType": "https://github.com/docker/buildkit-syft-scanner/policy-result/v1",
+			"predicate": {
+				"policy": "/etc/policy.yaml",
+				"passed": false,
+				"violations": [
+					{
+						"rule": "license",
+						"package": "busybox@1.36.1-r15",
+						"purl": "pkg:apk/alpine/busybox@1.36.1-r15?arch=x86_64&distro=alpine-3.19.1",
+						"message": "license GPL-2.0-only is denied",
+						"locations": ["/lib/apk/db/installed"]
+					},
+					{
+						"rule": "supplier",
+						"package": "busybox@1.36.1-r15",
+						"message": "no supplier found"
+					}
+				]
+			}
+		}`)
+	})
+
+	t.Run("annotate", func(t *testing.T) {
+		dest, err := scan(t, PolicyAnnotate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dt, err := os.ReadFile(filepath.Join(dest, "sbom.spdx.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range []string{
+			`buildkit-syft-scanner:policy=/etc/policy.yaml`,
+			`buildkit-syft-scanner:policy-violation=license: busybox@1.36.1-r15: license GPL-2.0-only is denied (/lib/apk/db/installed)`,
+		} {
+			if !strings.Contains(string(dt), line) {
+				t.Errorf("expected the SBOM to record %q", line)
+			}
+		}
+	})
+}
@@ -0,0 +1,150 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/anchore/syft/syft/format/cyclonedxjson"
+	"github.com/anchore/syft/syft/format/spdxjson"
+	"github.com/anchore/syft/syft/format/syftjson"
+	"github.com/docker/buildkit-syft-scanner/version"
+)
+
+// propertyNamespace namespaces the properties recorded by the scanner.
+const propertyNamespace = "buildkit-syft-scanner"
+
+// property is a scanner setting recorded in the creation info of an SBOM.
+type property struct {
+	name   string
+	values []string
+}
+
+// finalizePredicate rewrites a predicate encoded by syft: the scanner and
+// props are recorded in its creation info, along with build if set, and it
+// is then made reproducible and, for cyclonedx-xml, converted to XML.
+func finalizePredicate(f Format, predicate json.RawMessage, props []property, build *BuildInfo, epoch time.Time) (json.RawMessage, error) {
+	doc, err := decodePredicate(predicate)
+	if err != nil {
+		return nil, err
+	}
+	recordTool(f, doc)
+	recordProperties(f, doc, props)
+	if build != nil {
+		if err := recordBuildInfo(f, doc, *build); err != nil {
+			return nil, err
+		}
+	}
+	if err := makeReproducible(f, doc, epoch); err != nil {
+		return nil, err
+	}
+	dt, err := json.Marshal(doc)
+	if err != nil || !f.xml {
+		return dt, err
+	}
+	return cyclonedxXML(dt)
+}
+
+// decodePredicate decodes a predicate, preserving numbers as they were
+// encoded.
+func decodePredicate(predicate json.RawMessage) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(predicate))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// toolName is the name of the scanner in the tools that created an SBOM.
+const toolName = "buildkit-syft-scanner"
+
+// recordTool adds the scanner, with its version, next to syft in the tools
+// that created doc.
+func recordTool(f Format, doc map[string]interface{}) {
+	switch f.Encoder.ID() {
+	case spdxjson.ID:
+		info := objectField(doc, "creationInfo")
+		info["creators"] = append(asList(info["creators"]), "Tool: "+toolName+"-"+version.Version)
+	case cyclonedxjson.ID:
+		metadata := objectField(doc, "metadata")
+		if list, ok := metadata["tools"].([]interface{}); ok {
+			// CycloneDX 1.4 and earlier list tools directly
+			metadata["tools"] = append(list, map[string]interface{}{"vendor": "docker", "name": toolName, "version": version.Version})
+			break
+		}
+		tools := objectField(metadata, "tools")
+		tools["components"] = append(asList(tools["components"]), map[string]interface{}{
+			"type":    "application",
+			"author":  "docker",
+			"name":    toolName,
+			"version": version.Version,
+		})
+	case syftjson.ID:
+		cfg := objectField(objectField(objectField(doc, "descriptor"), "configuration"), propertyNamespace)
+		cfg["version"] = []string{version.Version}
+	}
+}
+
+// recordProperties adds props to the creation info of doc, in the place
+// each format provides for it: the creation comment for SPDX, the metadata
+// properties for CycloneDX, and the tool configuration for syft.
+func recordProperties(f Format, doc map[string]interface{}, props []property) {
+	if len(props) == 0 {
+		return
+	}
+	switch f.Encoder.ID() {
+	case spdxjson.ID:
+		info := objectField(doc, "creationInfo")
+		var lines []string
+		if comment, ok := info["comment"].(string); ok && comment != "" {
+			lines = append(lines, comment)
+		}
+		for _, p := range props {
+			for _, v := range p.values {
+				lines = append(lines, propertyNamespace+":"+p.name+"="+v)
+			}
+		}
+		info["comment"] = strings.Join(lines, "\n")
+	case cyclonedxjson.ID:
+		metadata := objectField(doc, "metadata")
+		list, _ := metadata["properties"].([]interface{})
+		for _, p := range props {
+			for _, v := range p.values {
+				list = append(list, map[string]interface{}{"name": propertyNamespace + ":" + p.name, "value": v})
+			}
+		}
+		metadata["properties"] = list
+	case syftjson.ID:
+		cfg := objectField(objectField(objectField(doc, "descriptor"), "configuration"), propertyNamespace)
+		for _, p := range props {
+			cfg[p.name] = p.values
+		}
+	}
+}
+
+// objectField returns the object at key in doc, creating it if needed.
+func objectField(doc map[string]interface{}, key string) map[string]interface{} {
+	obj, ok := doc[key].(map[string]interface{})
+	if !ok {
+		obj = map[string]interface{}{}
+		doc[key] = obj
+	}
+	return obj
+}
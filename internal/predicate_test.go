@@ -0,0 +1,123 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRecordProperties(t *testing.T) {
+	formats, err := ParseFormats("spdx-json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	props := []property{{name: "exclude", values: []string{"./proc", "**/testdata"}}}
+
+	for _, tc := range []struct {
+		predicate string
+		want      string
+	}{
+		{
+			predicate: `{"creationInfo": {}}`,
+			want:      "buildkit-syft-scanner:exclude=./proc\nbuildkit-syft-scanner:exclude=**/testdata",
+		},
+		{
+			predicate: `{"creationInfo": {"comment": "generated"}}`,
+			want:      "generated\nbuildkit-syft-scanner:exclude=./proc\nbuildkit-syft-scanner:exclude=**/testdata",
+		},
+	} {
+		out, err := finalizePredicate(formats[0], json.RawMessage(tc.predicate), props, nil, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc struct {
+			CreationInfo struct {
+				Comment string `json:"comment"`
+			} `json:"creationInfo"`
+		}
+		if err := json.Unmarshal(out, &doc); err != nil {
+			t.Fatal(err)
+		}
+		if doc.CreationInfo.Comment != tc.want {
+			t.Errorf("expected comment %q, got %q", tc.want, doc.CreationInfo.Comment)
+		}
+	}
+}
+
+func TestRecordTool(t *testing.T) {
+	formats, err := ParseFormats("cyclonedx-json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		predicate string
+		want      string
+	}{
+		{
+			// CycloneDX 1.4 and earlier
+			predicate: `{"metadata": {"tools": [{"vendor": "anchore", "name": "syft"}]}}`,
+			want:      `[{"name":"syft","vendor":"anchore"},{"name":"buildkit-syft-scanner","vendor":"docker","version":"v0.0.0+unknown"}]`,
+		},
+		{
+			predicate: `{"metadata": {"tools": {"components": [{"type": "application", "author": "anchore", "name": "syft"}]}}}`,
+			want:      `{"components":[{"author":"anchore","name":"syft","type":"application"},{"author":"docker","name":"buildkit-syft-scanner","type":"application","version":"v0.0.0+unknown"}]}`,
+		},
+	} {
+		out, err := finalizePredicate(formats[0], json.RawMessage(tc.predicate), nil, nil, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc struct {
+			Metadata struct {
+				Tools json.RawMessage `json:"tools"`
+			} `json:"metadata"`
+		}
+		if err := json.Unmarshal(out, &doc); err != nil {
+			t.Fatal(err)
+		}
+		if string(doc.Metadata.Tools) != tc.want {
+			t.Errorf("expected tools %s, got %s", tc.want, doc.Metadata.Tools)
+		}
+	}
+}
+
+func TestTargetConfigDigest(t *testing.T) {
+	digest := func(cfg Config) string {
+		t.Helper()
+		props, err := Target{Config: &cfg}.properties()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range props {
+			if p.name == "config-digest" {
+				return p.values[0]
+			}
+		}
+		t.Fatal("no config digest recorded")
+		return ""
+	}
+
+	cfg := DefaultConfig()
+	if a, b := digest(cfg), digest(DefaultConfig()); a != b {
+		t.Errorf("expected the same configuration to have the same digest, got %s and %s", a, b)
+	}
+	other := DefaultConfig()
+	other.SelectCatalogers = []string{"+javascript-lock-cataloger"}
+	if a, b := digest(cfg), digest(other); a == b {
+		t.Errorf("expected a different cataloger selection to change the digest %s", a)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anchore/syft/syft/format/cyclonedxjson"
+	"github.com/anchore/syft/syft/format/spdxjson"
+	"github.com/anchore/syft/syft/format/syftjson"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// ParseSourceDateEpoch parses a SOURCE_DATE_EPOCH value, the number of
+// seconds since the unix epoch.
+func ParseSourceDateEpoch(s string) (time.Time, error) {
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || sec < 0 {
+		return time.Time{}, errors.Errorf("invalid source date epoch %q, must be a non-negative number of seconds", s)
+	}
+	return time.Unix(sec, 0).UTC(), nil
+}
+
+// makeReproducible rewrites the parts of a predicate that differ between
+// scans of identical content, so that identical builds produce identical
+// statements: lists syft emits in map iteration order are sorted, random
+// document identifiers are derived from the content, and, if epoch is not
+// zero, creation timestamps are set to it.
+//
+// Package, file and relationship identifiers are already derived from
+// content by syft, and are left as-is.
+func makeReproducible(f Format, doc map[string]interface{}, epoch time.Time) error {
+	switch f.Encoder.ID() {
+	case spdxjson.ID:
+		sortObjects(doc["packages"], "SPDXID")
+		sortObjects(doc["files"], "SPDXID")
+		sortObjects(doc["relationships"], "spdxElementId", "relationshipType", "relatedSpdxElement")
+		if info, ok := doc["creationInfo"].(map[string]interface{}); ok && !epoch.IsZero() {
+			info["created"] = epoch.Format(time.RFC3339)
+		}
+		if ns, ok := doc["documentNamespace"].(string); ok {
+			id, err := contentUUID(doc, "documentNamespace")
+			if err != nil {
+				return err
+			}
+			doc["documentNamespace"] = replaceUUID(ns, id)
+		}
+	case cyclonedxjson.ID:
+		sortObjects(doc["components"], "bom-ref")
+		sortObjects(doc["dependencies"], "ref")
+		if deps, ok := doc["dependencies"].([]interface{}); ok {
+			for _, dep := range deps {
+				if dep, ok := dep.(map[string]interface{}); ok {
+					sortStrings(dep["dependsOn"])
+				}
+			}
+		}
+		if metadata, ok := doc["metadata"].(map[string]interface{}); ok && !epoch.IsZero() {
+			metadata["timestamp"] = epoch.Format(time.RFC3339)
+		}
+		if _, ok := doc["serialNumber"]; ok {
+			id, err := contentUUID(doc, "serialNumber")
+			if err != nil {
+				return err
+			}
+			doc["serialNumber"] = "urn:uuid:" + id.String()
+		}
+	case syftjson.ID:
+		sortObjects(doc["artifacts"], "id")
+		sortObjects(doc["files"], "id")
+		sortObjects(doc["artifactRelationships"], "parent", "child", "type")
+	}
+	return nil
+}
+
+// contentUUID derives a name-based UUID from doc, ignoring its field key.
+func contentUUID(doc map[string]interface{}, key string) (uuid.UUID, error) {
+	prev := doc[key]
+	delete(doc, key)
+	dt, err := json.Marshal(doc)
+	doc[key] = prev
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	return uuid.NewSHA1(uuid.NameSpaceURL, dt), nil
+}
+
+var uuidSuffix = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// replaceUUID replaces the random UUID syft appends to document namespaces.
+func replaceUUID(ns string, id uuid.UUID) string {
+	if uuidSuffix.MatchString(ns) {
+		return uuidSuffix.ReplaceAllLiteralString(ns, id.String())
+	}
+	return strings.TrimSuffix(ns, "/") + "/" + id.String()
+}
+
+// sortObjects sorts a list of JSON objects by the string values of keys,
+// falling back to their full encoding so that the order is total.
+func sortObjects(v interface{}, keys ...string) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return
+	}
+	type entry struct {
+		keys    []string
+		encoded string
+		value   interface{}
+	}
+	entries := make([]entry, len(list))
+	for i, item := range list {
+		obj, _ := item.(map[string]interface{})
+		e := entry{value: item}
+		for _, k := range keys {
+			e.keys = append(e.keys, fmt.Sprint(obj[k]))
+		}
+		dt, _ := json.Marshal(item)
+		e.encoded = string(dt)
+		entries[i] = e
+	}
+	sort.SliceStable(entries, func(a, b int) bool {
+		for i := range entries[a].keys {
+			if ka, kb := entries[a].keys[i], entries[b].keys[i]; ka != kb {
+				return ka < kb
+			}
+		}
+		return entries[a].encoded < entries[b].encoded
+	})
+	for i, e := range entries {
+		list[i] = e.value
+	}
+}
+
+func sortStrings(v interface{}) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return
+	}
+	sort.SliceStable(list, func(a, b int) bool {
+		return fmt.Sprint(list[a]) < fmt.Sprint(list[b])
+	})
+}
@@ -0,0 +1,167 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanReproducible(t *testing.T) {
+	core := buildFixture(t, filepath.Join(t.TempDir(), "sbom"), alpineFixture, debianFixture, embeddedSBOMFixture, goBinaryFixture("bin/app"))
+	formats, err := ParseFormats("spdx-json,cyclonedx-json,syft-json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	epoch, err := ParseSourceDateEpoch("1700000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dests []string
+	for i := 0; i < 2; i++ {
+		scanner := Scanner{
+			Core:            Target{Path: core},
+			Destination:     t.TempDir(),
+			Formats:         formats,
+			SourceDateEpoch: epoch,
+		}
+		if err := scanner.Scan(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		dests = append(dests, scanner.Destination)
+	}
+
+	for _, f := range formats {
+		name := "sbom" + f.Extension
+		first, err := os.ReadFile(filepath.Join(dests[0], name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := os.ReadFile(filepath.Join(dests[1], name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(first, second) {
+			t.Errorf("%s differs between identical scans", name)
+		}
+	}
+
+	assertStatement(t, filepath.Join(dests[0], "sbom.spdx.json"), `{
+		"predicate": {"creationInfo": {"created": "2023-11-14T22:13:20Z"}}
+	}`)
+	assertStatement(t, filepath.Join(dests[0], "sbom.cdx.json"), `{
+		"predicate": {"metadata": {"timestamp": "2023-11-14T22:13:20Z"}}
+	}`)
+}
+
+func TestMakeReproducible(t *testing.T) {
+	formats, err := ParseFormats("spdx-json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := func(namespace string, ids ...string) json.RawMessage {
+		var packages []map[string]interface{}
+		for _, id := range ids {
+			packages = append(packages, map[string]interface{}{"SPDXID": id, "size": 1 << 60})
+		}
+		dt, err := json.Marshal(map[string]interface{}{
+			"documentNamespace": "https://anchore.com/syft/dir/sbom-" + namespace,
+			"creationInfo":      map[string]interface{}{"created": "2026-01-01T00:00:00Z"},
+			"packages":          packages,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dt
+	}
+
+	first, err := finalizePredicate(formats[0], doc("c54bc538-ab1c-40ca-8596-6a2ac4b43f5d", "b", "c", "a"), nil, nil, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := finalizePredicate(formats[0], doc("6c7a597b-70d3-491a-9a97-292af2405f3c", "a", "b", "c"), nil, nil, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Fatalf("expected identical output, got:\n%s\n%s", first, second)
+	}
+
+	var decoded struct {
+		DocumentNamespace string `json:"documentNamespace"`
+		CreationInfo      struct {
+			Created string `json:"created"`
+		} `json:"creationInfo"`
+		Packages []struct {
+			SPDXID string `json:"SPDXID"`
+			Size   int64  `json:"size"`
+		} `json:"packages"`
+	}
+	if err := json.Unmarshal(first, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(decoded.DocumentNamespace, "https://anchore.com/syft/dir/sbom-") || strings.Contains(decoded.DocumentNamespace, "c54bc538") {
+		t.Errorf("unexpected namespace %q", decoded.DocumentNamespace)
+	}
+	if decoded.CreationInfo.Created != "2026-01-01T00:00:00Z" {
+		t.Errorf("expected creation time to be kept without an epoch, got %q", decoded.CreationInfo.Created)
+	}
+	var ids []string
+	for _, p := range decoded.Packages {
+		ids = append(ids, p.SPDXID)
+		if p.Size != 1<<60 {
+			t.Errorf("expected large numbers to be preserved, got %d", p.Size)
+		}
+	}
+	if strings.Join(ids, ",") != "a,b,c" {
+		t.Errorf("expected packages to be sorted, got %v", ids)
+	}
+
+	// the namespace still depends on the content
+	third, err := finalizePredicate(formats[0], doc("6c7a597b-70d3-491a-9a97-292af2405f3c", "a", "b"), nil, nil, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first, third) {
+		t.Fatal("expected different documents to have different namespaces")
+	}
+}
+
+func TestParseSourceDateEpoch(t *testing.T) {
+	for s, want := range map[string]string{
+		"0":          "1970-01-01T00:00:00Z",
+		"1700000000": "2023-11-14T22:13:20Z",
+	} {
+		got, err := ParseSourceDateEpoch(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Format(time.RFC3339) != want {
+			t.Errorf("%s: expected %s, got %s", s, want, got.Format(time.RFC3339))
+		}
+	}
+	for _, s := range []string{"", "-1", "1.5", "yesterday"} {
+		if _, err := ParseSourceDateEpoch(s); err == nil {
+			t.Errorf("expected %q to be invalid", s)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+
+	"github.com/anchore/syft/syft/file"
+	"github.com/anchore/syft/syft/source"
+)
+
+// skipSource is a source whose file resolver hides the files at the paths
+// of skip, as if they were excluded from the scan.
+//
+// Exclusion globs are matched by syft one by one against every file walked,
+// which does not scale to the thousands of files of a base image, whereas
+// skipped paths are looked up in a map.
+type skipSource struct {
+	source.Source
+	skip map[string]struct{}
+}
+
+func (s skipSource) FileResolver(scope source.Scope) (file.Resolver, error) {
+	resolver, err := s.Source.FileResolver(scope)
+	if err != nil {
+		return nil, err
+	}
+	return skipResolver{Resolver: resolver, skip: s.skip}, nil
+}
+
+// skipResolver is a file resolver hiding the files whose real path is in
+// skip.
+type skipResolver struct {
+	file.Resolver
+	skip map[string]struct{}
+}
+
+func (r skipResolver) skipped(l file.Location) bool {
+	_, ok := r.skip[l.RealPath]
+	return ok
+}
+
+func (r skipResolver) filter(locations []file.Location, err error) ([]file.Location, error) {
+	if err != nil {
+		return nil, err
+	}
+	var kept []file.Location
+	for _, l := range locations {
+		if !r.skipped(l) {
+			kept = append(kept, l)
+		}
+	}
+	return kept, nil
+}
+
+func (r skipResolver) HasPath(p string) bool {
+	if _, ok := r.skip[p]; ok {
+		return false
+	}
+	return r.Resolver.HasPath(p)
+}
+
+func (r skipResolver) FilesByPath(paths ...string) ([]file.Location, error) {
+	return r.filter(r.Resolver.FilesByPath(paths...))
+}
+
+func (r skipResolver) FilesByGlob(patterns ...string) ([]file.Location, error) {
+	return r.filter(r.Resolver.FilesByGlob(patterns...))
+}
+
+func (r skipResolver) FilesByMIMEType(types ...string) ([]file.Location, error) {
+	return r.filter(r.Resolver.FilesByMIMEType(types...))
+}
+
+func (r skipResolver) RelativeFileByPath(l file.Location, p string) *file.Location {
+	found := r.Resolver.RelativeFileByPath(l, p)
+	if found == nil || r.skipped(*found) {
+		return nil
+	}
+	return found
+}
+
+func (r skipResolver) AllLocations(ctx context.Context) <-chan file.Location {
+	out := make(chan file.Location)
+	go func() {
+		defer close(out)
+		for l := range r.Resolver.AllLocations(ctx) {
+			if r.skipped(l) {
+				continue
+			}
+			select {
+			case out <- l:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,80 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/anchore/syft/syft"
+	"github.com/anchore/syft/syft/file"
+	"github.com/anchore/syft/syft/source"
+)
+
+func TestSkipResolver(t *testing.T) {
+	root := buildFixture(t, filepath.Join(t.TempDir(), "sbom"), files(map[string]string{
+		"usr/bin/kept":    "kept",
+		"usr/bin/skipped": "skipped",
+		"etc/[x]*":        "glob metacharacters",
+	}))
+	src, err := syft.GetSource(context.Background(), root, syft.DefaultGetSourceConfig().WithBasePath(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	src = skipSource{Source: src, skip: map[string]struct{}{
+		"/usr/bin/skipped": {},
+		"/etc/[x]*":        {},
+	}}
+	resolver, err := src.FileResolver(source.SquashedScope)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := func(locations []file.Location, err error) []string {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var paths []string
+		for _, l := range locations {
+			paths = append(paths, l.RealPath)
+		}
+		slices.Sort(paths)
+		return paths
+	}
+	if got := paths(resolver.FilesByGlob("**/*")); !slices.Equal(got, []string{"/usr/bin/kept"}) {
+		t.Errorf("unexpected files by glob %v", got)
+	}
+	if got := paths(resolver.FilesByPath("/usr/bin/kept", "/usr/bin/skipped")); !slices.Equal(got, []string{"/usr/bin/kept"}) {
+		t.Errorf("unexpected files by path %v", got)
+	}
+	if resolver.HasPath("/usr/bin/skipped") || !resolver.HasPath("/usr/bin/kept") {
+		t.Errorf("expected only the skipped file to be hidden")
+	}
+
+	var all []file.Location
+	for l := range resolver.AllLocations(context.Background()) {
+		if l.RealPath == "/usr/bin/skipped" || l.RealPath == "/etc/[x]*" {
+			t.Errorf("expected %s to be skipped", l.RealPath)
+		}
+		all = append(all, l)
+	}
+	if len(all) == 0 {
+		t.Errorf("expected the other locations to be listed")
+	}
+}
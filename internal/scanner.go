@@ -17,71 +17,383 @@ package internal
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/anchore/syft/syft/format"
 	"github.com/anchore/syft/syft/format/spdxjson"
+	"github.com/anchore/syft/syft/sbom"
+	"github.com/bmatcuk/doublestar/v4"
+	units "github.com/docker/go-units"
 	intoto "github.com/in-toto/in-toto-golang/in_toto"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 )
 
 type Scanner struct {
 	Core        Target
 	Extras      []Target
 	Destination string
+	Formats     []Format
+	// Parallelism is the maximum number of targets scanned at once, if
+	// zero it is derived from the available CPUs and memory.
+	Parallelism int
+	// Timeout aborts the scan if it takes longer, if zero there is no
+	// timeout.
+	Timeout time.Duration
+	// SourceDateEpoch is used as the creation time of the SBOMs, if zero
+	// the current time is used.
+	SourceDateEpoch time.Time
+	// Merge additionally emits a single SPDX document for the whole build,
+	// linking the core target to the extra targets.
+	Merge bool
+	// BaseSBOM is the path, inside the core target, of the SBOM of its base
+	// image or of a directory of them. Files unchanged since the base SBOM
+	// are not scanned again, and packages are marked as inherited from the
+	// base image or added on top of it.
+	BaseSBOM string
+	// Policy, if set, is evaluated against the SBOM of the core target.
+	Policy *Policy
+	// VulnDB, if set, is matched against the packages of the core target,
+	// and a vulnerability report statement is emitted next to its SBOMs.
+	VulnDB *VulnDB
+	// VulnFailOn fails the scan if a vulnerability at least this severe is
+	// found, if unknown the scan never fails on vulnerabilities.
+	VulnFailOn Severity
+	// VEX are globs of OpenVEX documents looked for in the core target and
+	// the extras, merged into an OpenVEX statement for the packages of the
+	// core target.
+	VEX []string
+	// Signer, if set, signs every statement, emitting its DSSE envelope in
+	// a statement next to it.
+	Signer *Signer
+	// BuildInfo, if set, is recorded in the SBOMs of the core target.
+	BuildInfo *BuildInfo
 }
 
-func (s Scanner) Scan(ctx context.Context) error {
-	for _, target := range append([]Target{s.Core}, s.Extras...) {
-		result, err := target.Scan(ctx)
+func (s Scanner) Scan(ctx context.Context) (retErr error) {
+	started := time.Now()
+	w := newStatementWriter(s.Destination)
+	w.signer = s.Signer
+	defer func() {
+		if retErr != nil {
+			if err := w.Abort(retErr); err != nil {
+				logrus.Errorf("%v", err)
+			}
+		}
+	}()
+
+	formats := s.Formats
+	if len(formats) == 0 {
+		var err error
+		formats, err = ParseFormats("")
 		if err != nil {
-			return err
+			return newError(ErrorConfig, "", err)
 		}
+	}
+
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, s.Timeout, errors.Errorf("scan timed out after %s", s.Timeout))
+		defer cancel()
+	}
+
+	if s.Merge && !slices.ContainsFunc(formats, isSPDX) {
+		return newError(ErrorConfig, "", errors.Errorf("merging requires the %s format", spdxjson.ID))
+	}
 
-		enc, err := spdxjson.NewFormatEncoderWithConfig(spdxjson.DefaultEncoderConfig())
+	core := s.Core
+	var base *baseline
+	if s.BaseSBOM != "" {
+		var err error
+		base, err = loadBaseline(core.Path, s.BaseSBOM)
 		if err != nil {
-			return err
+			return newError(ErrorConfig, core.Name(), err)
+		}
+		core.skip = base.skip()
+	}
+
+	targets := append([]Target{core}, s.Extras...)
+	results, err := scanTargets(ctx, targets, s.Parallelism, Target.Scan)
+	if err != nil {
+		if cause := context.Cause(ctx); cause != nil {
+			return newError(ErrorAborted, "", errors.Wrap(cause, "scan aborted"))
+		}
+		return err
+	}
+
+	coreProps, err := s.Core.properties()
+	if err != nil {
+		return newError(ErrorConfig, s.Core.Name(), err)
+	}
+	if s.BuildInfo != nil {
+		coreProps = append(coreProps, s.BuildInfo.properties()...)
+	}
+	if base != nil {
+		base.apply(&results[0])
+		coreProps = append(coreProps, base.properties(results[0])...)
+	}
+	if len(s.Extras) > 0 {
+		attributions, err := attributeFiles(ctx, s.Core, s.Extras, results[1:])
+		if err != nil {
+			if cause := context.Cause(ctx); cause != nil {
+				return newError(ErrorAborted, "", errors.Wrap(cause, "scan aborted"))
+			}
+			return newError(ErrorSource, s.Core.Name(), errors.Wrap(err, "failed to attribute files to build stages"))
 		}
+		applyAttributions(&results[0], attributions)
+		coreProps = append(coreProps, attributionProperties(attributions)...)
+	}
 
-		output, err := format.Encode(result, enc)
+	if s.Policy != nil {
+		props, err := s.applyPolicy(w, results[0])
 		if err != nil {
 			return err
 		}
-		stmt := intoto.Statement{
+		coreProps = append(coreProps, props...)
+	}
+	if s.VulnDB != nil {
+		if err := s.applyVulnDB(w, results[0], started); err != nil {
+			return err
+		}
+	}
+	if len(s.VEX) > 0 {
+		if err := s.applyVEX(w, targets, results[0]); err != nil {
+			return err
+		}
+	}
+
+	var spdxDocs []spdxDoc
+	for i, target := range targets {
+		props, build := coreProps, s.BuildInfo
+		if i > 0 {
+			props, err = target.properties()
+			if err != nil {
+				return newError(ErrorConfig, target.Name(), err)
+			}
+			build = nil
+		}
+		for _, f := range formats {
+			predicate, err := f.Predicate(results[i])
+			if err != nil {
+				return newError(ErrorWrite, target.Name(), err)
+			}
+			predicate, err = finalizePredicate(f, predicate, props, build, s.SourceDateEpoch)
+			if err != nil {
+				return newError(ErrorWrite, target.Name(), err)
+			}
+			stmt := intoto.Statement{
+				StatementHeader: intoto.StatementHeader{
+					Type:          intoto.StatementInTotoV1,
+					PredicateType: f.PredicateType,
+				},
+				Predicate: predicate,
+			}
+			if err := w.Stage(target.Name()+f.Extension, stmt); err != nil {
+				return newError(ErrorWrite, target.Name(), err)
+			}
+			if s.Merge && isSPDX(f) {
+				doc, err := decodePredicate(predicate)
+				if err != nil {
+					return newError(ErrorWrite, target.Name(), err)
+				}
+				spdxDocs = append(spdxDocs, doc)
+			}
+		}
+	}
+	if s.Merge {
+		if err := s.stageMerged(w, formats[slices.IndexFunc(formats, isSPDX)], spdxDocs); err != nil {
+			return newError(ErrorWrite, "", err)
+		}
+	}
+
+	if err := context.Cause(ctx); err != nil {
+		return newError(ErrorAborted, "", errors.Wrap(err, "scan aborted"))
+	}
+	return newError(ErrorWrite, "", w.Commit())
+}
+
+// applyPolicy evaluates the policy against the SBOM of the core target, and
+// acts on its violations: it returns them as properties of the SBOMs, stages
+// a policy result statement, or fails.
+func (s Scanner) applyPolicy(w *statementWriter, result sbom.SBOM) ([]property, error) {
+	violations := s.Policy.Evaluate(result)
+	var lines []string
+	for _, v := range violations {
+		lines = append(lines, v.String())
+	}
+
+	switch s.Policy.Action {
+	case PolicyAnnotate:
+		props := []property{{name: "policy", values: []string{s.Policy.name}}}
+		if len(lines) > 0 {
+			props = append(props, property{name: "policy-violation", values: lines})
+		}
+		return props, nil
+	case PolicyAttest:
+		if violations == nil {
+			violations = []Violation{}
+		}
+		err := w.Stage(s.Core.Name()+PolicySuffix, intoto.Statement{
 			StatementHeader: intoto.StatementHeader{
 				Type:          intoto.StatementInTotoV1,
-				PredicateType: intoto.PredicateSPDX,
+				PredicateType: PredicatePolicyResult,
+			},
+			Predicate: PolicyResult{
+				Policy:     s.Policy.name,
+				Passed:     len(violations) == 0,
+				Violations: violations,
 			},
-			Predicate: json.RawMessage(output),
+		})
+		return nil, newError(ErrorWrite, s.Core.Name(), err)
+	default:
+		if len(violations) > 0 {
+			return nil, newError(ErrorPolicy, s.Core.Name(), errors.Errorf("policy %q violated (%d violations):\n%s", s.Policy.name, len(violations), strings.Join(lines, "\n")))
 		}
+		return nil, nil
+	}
+}
 
-		outputPath := filepath.Join(s.Destination, target.Name()+".spdx.json")
-		if err := func() (retErr error) {
-			f, err := os.Create(outputPath)
-			if err != nil {
-				return err
+// applyVulnDB matches the packages of the core target against the
+// vulnerability database, stages the vulnerability report statement, and
+// fails if a vulnerability reaches the VulnFailOn severity.
+func (s Scanner) applyVulnDB(w *statementWriter, result sbom.SBOM, started time.Time) error {
+	matches := s.VulnDB.Match(result)
+	finished := time.Now()
+	if !s.SourceDateEpoch.IsZero() {
+		started, finished = s.SourceDateEpoch, s.SourceDateEpoch
+	}
+	logrus.Infof("%s: %d vulnerabilities found", s.Core.Name(), len(matches))
+
+	if s.VulnFailOn != SeverityUnknown {
+		var lines []string
+		for _, m := range matches {
+			if m.Severity >= s.VulnFailOn {
+				lines = append(lines, fmt.Sprintf("%s (%s): %s", m.ID, m.Severity, m.Package))
 			}
-			defer func() {
-				if err := f.Close(); retErr == nil && err != nil {
-					retErr = err
-				}
-			}()
-			return json.NewEncoder(f).Encode(stmt)
-		}(); err != nil {
-			return err
+		}
+		if len(lines) > 0 {
+			return newError(ErrorPolicy, s.Core.Name(), errors.Errorf("%d vulnerabilities at or above %s severity:\n%s", len(lines), s.VulnFailOn, strings.Join(lines, "\n")))
 		}
 	}
-	return nil
+
+	err := w.Stage(s.Core.Name()+VulnsSuffix, intoto.Statement{
+		StatementHeader: intoto.StatementHeader{
+			Type:          intoto.StatementInTotoV1,
+			PredicateType: PredicateVulns,
+		},
+		Predicate: newVulnReport(s.VulnDB, matches, started, finished),
+	})
+	return newError(ErrorWrite, s.Core.Name(), err)
+}
+
+// applyVEX stages an OpenVEX statement merging the VEX documents found in
+// targets, if any, normalised to the packages of the core target.
+func (s Scanner) applyVEX(w *statementWriter, targets []Target, result sbom.SBOM) error {
+	var fragments []vexFragment
+	for _, target := range targets {
+		found, err := findVEX(target.Path, s.VEX)
+		if err != nil {
+			return newError(ErrorSource, target.Name(), err)
+		}
+		fragments = append(fragments, found...)
+	}
+	if len(fragments) == 0 {
+		return nil
+	}
+	doc, unmatched, err := newVEXDocument(result, fragments, s.SourceDateEpoch)
+	if err != nil {
+		return newError(ErrorWrite, s.Core.Name(), err)
+	}
+	logrus.Infof("%s: vex: %d statements from %d documents, %d products not found", s.Core.Name(), len(doc.Statements), len(fragments), unmatched)
+
+	err = w.Stage(s.Core.Name()+VEXSuffix, intoto.Statement{
+		StatementHeader: intoto.StatementHeader{
+			Type:          intoto.StatementInTotoV1,
+			PredicateType: PredicateOpenVEX,
+		},
+		Predicate: doc,
+	})
+	return newError(ErrorWrite, s.Core.Name(), err)
+}
+
+// stageMerged stages the merge of the SPDX documents of all targets, the
+// core target first.
+func (s Scanner) stageMerged(w *statementWriter, f Format, docs []spdxDoc) error {
+	merged, err := mergeSPDX(docs[0], docs[1:])
+	if err != nil {
+		return errors.Wrap(err, "failed to merge sboms")
+	}
+	name := s.Core.Name() + MergedSuffix
+	merged["name"] = name
+	if err := makeReproducible(f, merged, s.SourceDateEpoch); err != nil {
+		return err
+	}
+	predicate, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	return w.Stage(name+f.Extension, intoto.Statement{
+		StatementHeader: intoto.StatementHeader{
+			Type:          intoto.StatementInTotoV1,
+			PredicateType: f.PredicateType,
+		},
+		Predicate: json.RawMessage(predicate),
+	})
+}
+
+func isSPDX(f Format) bool {
+	return f.Encoder.ID() == spdxjson.ID
 }
 
 const (
 	envScanDestination  = "BUILDKIT_SCAN_DESTINATION"
 	envScanSource       = "BUILDKIT_SCAN_SOURCE"
 	envScanSourceExtras = "BUILDKIT_SCAN_SOURCE_EXTRAS"
+	envScanFormat       = "BUILDKIT_SCAN_FORMAT"
+	envScanConfig       = "BUILDKIT_SCAN_CONFIG"
+	envScanParallelism  = "BUILDKIT_SCAN_PARALLELISM"
+	envScanTimeout      = "BUILDKIT_SCAN_TIMEOUT"
+	envScanErrorReport  = "BUILDKIT_SCAN_ERROR_REPORT"
+	envScanExclude      = "BUILDKIT_SCAN_EXCLUDE"
+	envScanMerge        = "BUILDKIT_SCAN_MERGE"
+	envScanBaseSBOM     = "BUILDKIT_SCAN_BASE_SBOM"
+	envScanCacheDir     = "BUILDKIT_SCAN_CACHE_DIR"
+	envScanCacheSize    = "BUILDKIT_SCAN_CACHE_SIZE"
+	envScanPolicy       = "BUILDKIT_SCAN_POLICY"
+	envScanPolicyAction = "BUILDKIT_SCAN_POLICY_ACTION"
+	envScanVulnDB       = "BUILDKIT_SCAN_VULN_DB"
+	envScanVulnFailOn   = "BUILDKIT_SCAN_VULN_FAIL_ON"
+	envScanVEX          = "BUILDKIT_SCAN_VEX"
+	envScanSigningKey   = "BUILDKIT_SCAN_SIGNING_KEY"
+	envScanLabels       = "BUILDKIT_SCAN_LABELS"
+	envScanVCSURL       = "BUILDKIT_SCAN_VCS_URL"
+	envScanVCSRevision  = "BUILDKIT_SCAN_VCS_REVISION"
+	envScanBuildTime    = "BUILDKIT_SCAN_BUILD_TIMESTAMP"
+	envScanDockerfile   = "BUILDKIT_SCAN_DOCKERFILE"
+	envScanImageName    = "BUILDKIT_SCAN_IMAGE_NAME"
+
+	envScanSourceDateEpoch = "BUILDKIT_SCAN_SOURCE_DATE_EPOCH"
+	envSourceDateEpoch     = "SOURCE_DATE_EPOCH"
+
+	envSelectCatalogers = "BUILDKIT_SCAN_SELECT_CATALOGERS"
 )
 
+// NewScannerFromEnvironment configures a Scanner from the variables set by
+// BuildKit, returning an ErrorConfig error if they are invalid.
 func NewScannerFromEnvironment() (*Scanner, error) {
+	scanner, err := newScannerFromEnvironment()
+	if err != nil {
+		return nil, newError(ErrorConfig, "", err)
+	}
+	return scanner, nil
+}
+
+func newScannerFromEnvironment() (*Scanner, error) {
 	destPath, err := loadPathFromEnvironment(envScanDestination, true)
 	if err != nil {
 		return nil, err
@@ -91,7 +403,29 @@ func NewScannerFromEnvironment() (*Scanner, error) {
 	if err != nil {
 		return nil, err
 	}
-	core := Target{Path: corePath}
+	cfg, err := LoadConfig(corePath, os.Getenv(envScanConfig))
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := os.LookupEnv(envSelectCatalogers); ok {
+		cfg.SelectCatalogers = strings.Split(v, ",")
+	}
+	if v, ok := os.LookupEnv(envScanExclude); ok {
+		cfg.Exclude = nil
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				cfg.Exclude = append(cfg.Exclude, p)
+			}
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid variable %q", envScanExclude)
+		}
+	}
+	cache, err := loadCacheFromEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	core := Target{Path: corePath, Config: cfg, Cache: cache}
 
 	extrasPath, err := loadPathFromEnvironment(envScanSourceExtras, false)
 	if err != nil {
@@ -105,19 +439,228 @@ func NewScannerFromEnvironment() (*Scanner, error) {
 		}
 		for _, entry := range entries {
 			extras = append(extras, Target{
-				Path: filepath.Join(extrasPath, entry.Name()),
+				Path:   filepath.Join(extrasPath, entry.Name()),
+				Config: cfg,
+				Cache:  cache,
 			})
 		}
 	}
 
+	formats, err := ParseFormats(os.Getenv(envScanFormat))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid variable %q", envScanFormat)
+	}
+
+	var parallelism int
+	if v := os.Getenv(envScanParallelism); v != "" {
+		parallelism, err = strconv.Atoi(v)
+		if err != nil || parallelism < 0 {
+			return nil, errors.Errorf("invalid variable %q (%q), must be a non-negative integer", envScanParallelism, v)
+		}
+	}
+
+	var timeout time.Duration
+	if v := os.Getenv(envScanTimeout); v != "" {
+		timeout, err = time.ParseDuration(v)
+		if err != nil || timeout < 0 {
+			return nil, errors.Errorf("invalid variable %q (%q), must be a non-negative duration, e.g. 10m", envScanTimeout, v)
+		}
+	}
+
+	var epoch time.Time
+	for _, name := range []string{envScanSourceDateEpoch, envSourceDateEpoch} {
+		if v := os.Getenv(name); v != "" {
+			epoch, err = ParseSourceDateEpoch(v)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid variable %q", name)
+			}
+			break
+		}
+	}
+
+	var merge bool
+	if v := os.Getenv(envScanMerge); v != "" {
+		merge, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.Errorf("invalid variable %q (%q), must be a boolean", envScanMerge, v)
+		}
+	}
+
+	var policy *Policy
+	if v := os.Getenv(envScanPolicy); v != "" {
+		policy, err = LoadPolicy(corePath, v)
+		if err != nil {
+			return nil, err
+		}
+		if v := os.Getenv(envScanPolicyAction); v != "" {
+			policy.Action = PolicyAction(v)
+			if err := policy.Validate(); err != nil {
+				return nil, errors.Wrapf(err, "invalid variable %q", envScanPolicyAction)
+			}
+		}
+	}
+
+	var vulnDB *VulnDB
+	if dbPath, err := loadPathFromEnvironment(envScanVulnDB, false); err != nil {
+		return nil, err
+	} else if dbPath != "" {
+		vulnDB, err = LoadVulnDB(dbPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var failOn Severity
+	if v := os.Getenv(envScanVulnFailOn); v != "" {
+		if vulnDB == nil {
+			return nil, errors.Errorf("variable %q requires %q", envScanVulnFailOn, envScanVulnDB)
+		}
+		failOn, err = ParseSeverity(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid variable %q", envScanVulnFailOn)
+		}
+	}
+
+	vex := DefaultVEX
+	if v, ok := os.LookupEnv(envScanVEX); ok {
+		vex = nil
+		for _, g := range strings.Split(v, ",") {
+			if g = strings.TrimSpace(g); g == "" {
+				continue
+			}
+			if !doublestar.ValidatePattern(g) {
+				return nil, errors.Errorf("invalid variable %q, invalid glob %q", envScanVEX, g)
+			}
+			vex = append(vex, g)
+		}
+	}
+
+	signer, err := loadSignerFromEnvironment()
+	if err != nil {
+		return nil, err
+	}
+
+	buildInfo, err := loadBuildInfoFromEnvironment(corePath)
+	if err != nil {
+		return nil, err
+	}
+
 	scanner := Scanner{
 		Destination: destPath,
 		Core:        core,
 		Extras:      extras,
+		Formats:     formats,
+		Parallelism: parallelism,
+		Timeout:     timeout,
+
+		SourceDateEpoch: epoch,
+		Merge:           merge,
+		BaseSBOM:        os.Getenv(envScanBaseSBOM),
+		Policy:          policy,
+		VulnDB:          vulnDB,
+		VulnFailOn:      failOn,
+		VEX:             vex,
+		Signer:          signer,
+		BuildInfo:       buildInfo,
 	}
 	return &scanner, nil
 }
 
+func loadCacheFromEnvironment() (*Cache, error) {
+	dir, err := loadPathFromEnvironment(envScanCacheDir, false)
+	if err != nil || dir == "" {
+		return nil, err
+	}
+	cache := &Cache{Dir: dir}
+	if v := os.Getenv(envScanCacheSize); v != "" {
+		cache.MaxSize, err = units.RAMInBytes(v)
+		if err != nil || cache.MaxSize <= 0 {
+			return nil, errors.Errorf("invalid variable %q (%q), must be a positive size, e.g. 512MiB", envScanCacheSize, v)
+		}
+	}
+	return cache, nil
+}
+
+// loadBuildInfoFromEnvironment detects the build information available in
+// the image, overridden by the generator parameters, returning nil if there
+// is none.
+func loadBuildInfoFromEnvironment(corePath string) (*BuildInfo, error) {
+	labels, ok := os.LookupEnv(envScanLabels)
+	if !ok {
+		labels = DefaultLabelsFile
+	}
+	detected, err := DetectBuildInfo(corePath, labels)
+	if err != nil {
+		return nil, err
+	}
+	info := BuildInfo{
+		VCSURL:      sanitizeURL(os.Getenv(envScanVCSURL)),
+		VCSRevision: os.Getenv(envScanVCSRevision),
+		Dockerfile:  os.Getenv(envScanDockerfile),
+		ImageName:   os.Getenv(envScanImageName),
+	}
+	if v := os.Getenv(envScanBuildTime); v != "" {
+		info.Created, err = ParseBuildTimestamp(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid variable %q", envScanBuildTime)
+		}
+	}
+	info.merge(detected)
+	if info.IsZero() {
+		return nil, nil
+	}
+	return &info, nil
+}
+
+// loadSignerFromEnvironment loads the signing key from the path of a PEM
+// file, such as a secret mounted in the scanner. The key itself is refused:
+// the value of the variable is recorded by BuildKit with the build, e.g. in
+// its provenance, and in the history of the shell that set it.
+func loadSignerFromEnvironment() (*Signer, error) {
+	v := os.Getenv(envScanSigningKey)
+	if v == "" {
+		return nil, nil
+	}
+	if strings.Contains(v, "-----BEGIN") {
+		// never include the value, it is the key itself
+		return nil, errors.Errorf("invalid variable %q, must be the path of the key, e.g. a secret mount, not the key", envScanSigningKey)
+	}
+	p, err := loadPathFromEnvironment(envScanSigningKey, true)
+	if err != nil {
+		return nil, err
+	}
+	dt, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := LoadSigner(dt)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid variable %q (%q)", envScanSigningKey, p)
+	}
+	logrus.Infof("signing statements with key %s", signer.keyID)
+	return signer, nil
+}
+
+// WriteErrorReportFromEnvironment writes the ErrorReport for scanErr to the
+// destination, if requested with BUILDKIT_SCAN_ERROR_REPORT.
+func WriteErrorReportFromEnvironment(scanErr error) error {
+	v := os.Getenv(envScanErrorReport)
+	if v == "" {
+		return nil
+	}
+	enabled, err := strconv.ParseBool(v)
+	if err != nil {
+		return errors.Errorf("invalid variable %q (%q), must be a boolean", envScanErrorReport, v)
+	}
+	if !enabled {
+		return nil
+	}
+	dest, err := loadPathFromEnvironment(envScanDestination, true)
+	if err != nil {
+		return err
+	}
+	return WriteErrorReport(dest, scanErr)
+}
+
 func loadPathFromEnvironment(name string, required bool) (string, error) {
 	p, ok := os.LookupEnv(name)
 	if !ok {
@@ -0,0 +1,102 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScannerTimeout(t *testing.T) {
+	dest := t.TempDir()
+	scanner := Scanner{
+		Core:        Target{Path: t.TempDir()},
+		Destination: dest,
+		Timeout:     time.Nanosecond,
+	}
+	err := scanner.Scan(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "scan timed out after 1ns") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+
+	assertDestination(t, dest, FailureMarker)
+}
+
+func TestScannerWritesAllFormats(t *testing.T) {
+	dest := t.TempDir()
+	formats, err := ParseFormats("spdx-json,cyclonedx-json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner := Scanner{
+		Core:        Target{Path: filepath.Join(t.TempDir(), "core")},
+		Destination: dest,
+		Formats:     formats,
+	}
+	if err := os.Mkdir(scanner.Core.Path, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := scanner.Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assertDestination(t, dest, "core.cdx.json", "core.spdx.json")
+}
+
+func TestStatementWriterAbort(t *testing.T) {
+	dest := t.TempDir()
+	w := newStatementWriter(dest)
+	if err := w.Stage("a.spdx.json", map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Stage("b.spdx.json", map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Abort(errors.New("encode failed")); err != nil {
+		t.Fatal(err)
+	}
+	assertDestination(t, dest, FailureMarker)
+
+	dt, err := os.ReadFile(filepath.Join(dest, FailureMarker))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(dt) != "encode failed\n" {
+		t.Fatalf("unexpected failure marker content %q", dt)
+	}
+}
+
+func assertDestination(t *testing.T, dest string, want ...string) {
+	t.Helper()
+	entries, err := os.ReadDir(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected destination to contain %v, got %v", want, got)
+	}
+}
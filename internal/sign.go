@@ -0,0 +1,340 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"hash"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/pkg/errors"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+// PredicateSignature is the in-toto predicate type of the statements
+// carrying the DSSE envelope of another statement.
+const PredicateSignature = "https://github.com/docker/buildkit-syft-scanner/dsse/v1"
+
+// SignatureSuffix replaces the .json extension of a statement for the
+// statement carrying its envelope, e.g. "sbom.spdx.dsse.json".
+const SignatureSuffix = ".dsse.json"
+
+// payloadTypeInToto is the DSSE payload type of in-toto statements.
+const payloadTypeInToto = "application/vnd.in-toto+json"
+
+// Signature is the predicate of the statement carrying the DSSE envelope of
+// another statement.
+//
+// The envelope signs the statement as written by the scanner: BuildKit adds
+// the image as its subject afterwards, so the subject is not signed.
+type Signature struct {
+	Statement string        `json:"statement"`
+	KeyID     string        `json:"keyid"`
+	Envelope  dsse.Envelope `json:"envelope"`
+}
+
+// Signer signs statements with an ed25519 or ECDSA private key.
+type Signer struct {
+	key   crypto.Signer
+	keyID string
+}
+
+// LoadSigner parses a PEM encoded, unencrypted, ed25519 or ECDSA private
+// key, in PKCS #8 or SEC 1 form.
+func LoadSigner(dt []byte) (*Signer, error) {
+	block, _ := pem.Decode(dt)
+	if block == nil {
+		return nil, errors.New("no PEM encoded key found")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, errors.Errorf("unsupported PEM block %q, must be an unencrypted private key", block.Type)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid private key")
+	}
+	var signer crypto.Signer
+	switch key := key.(type) {
+	case ed25519.PrivateKey:
+		signer = key
+	case *ecdsa.PrivateKey:
+		signer = key
+	default:
+		return nil, errors.Errorf("unsupported key type %T, must be ed25519 or ECDSA", key)
+	}
+	keyID, err := dsse.SHA256KeyID(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{key: signer, keyID: keyID}, nil
+}
+
+// KeyID is the SHA256 fingerprint of the public key, as printed by
+// ssh-keygen -l.
+func (s *Signer) KeyID() (string, error) {
+	return s.keyID, nil
+}
+
+// Sign implements dsse.Signer.
+func (s *Signer) Sign(_ context.Context, data []byte) ([]byte, error) {
+	switch key := s.key.(type) {
+	case ed25519.PrivateKey:
+		return ed25519.Sign(key, data), nil
+	case *ecdsa.PrivateKey:
+		return ecdsa.SignASN1(rand.Reader, key, ecdsaDigest(key.Curve, data))
+	}
+	return nil, errors.Errorf("unsupported key type %T", s.key)
+}
+
+// sign returns the statement carrying the envelope of the statement name,
+// whose content is dt.
+func (s *Signer) sign(ctx context.Context, name string, dt []byte) (intoto.Statement, error) {
+	es, err := dsse.NewEnvelopeSigner(s)
+	if err != nil {
+		return intoto.Statement{}, err
+	}
+	env, err := es.SignPayload(ctx, payloadTypeInToto, dt)
+	if err != nil {
+		return intoto.Statement{}, errors.Wrapf(err, "failed to sign %q", name)
+	}
+	return intoto.Statement{
+		StatementHeader: intoto.StatementHeader{
+			Type:          intoto.StatementInTotoV1,
+			PredicateType: PredicateSignature,
+		},
+		Predicate: Signature{Statement: name, KeyID: s.keyID, Envelope: *env},
+	}, nil
+}
+
+// Verifier verifies envelopes signed by a Signer.
+type Verifier struct {
+	key   crypto.PublicKey
+	keyID string
+}
+
+// LoadVerifier parses a PEM encoded ed25519 or ECDSA public key, or the
+// private key it belongs to.
+func LoadVerifier(dt []byte) (*Verifier, error) {
+	block, _ := pem.Decode(dt)
+	if block == nil {
+		return nil, errors.New("no PEM encoded key found")
+	}
+	var key crypto.PublicKey
+	if block.Type == "PUBLIC KEY" {
+		var err error
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid public key")
+		}
+	} else {
+		signer, err := LoadSigner(dt)
+		if err != nil {
+			return nil, err
+		}
+		key = signer.key.Public()
+	}
+	switch key.(type) {
+	case ed25519.PublicKey, *ecdsa.PublicKey:
+	default:
+		return nil, errors.Errorf("unsupported key type %T, must be ed25519 or ECDSA", key)
+	}
+	keyID, err := dsse.SHA256KeyID(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Verifier{key: key, keyID: keyID}, nil
+}
+
+// KeyID implements dsse.Verifier.
+func (v *Verifier) KeyID() (string, error) {
+	return v.keyID, nil
+}
+
+// Public implements dsse.Verifier.
+func (v *Verifier) Public() crypto.PublicKey {
+	return v.key
+}
+
+// Verify implements dsse.Verifier.
+func (v *Verifier) Verify(_ context.Context, data []byte, sig []byte) error {
+	switch key := v.key.(type) {
+	case ed25519.PublicKey:
+		if ed25519.Verify(key, data, sig) {
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		if ecdsa.VerifyASN1(key, ecdsaDigest(key.Curve, data), sig) {
+			return nil
+		}
+	}
+	return errors.New("invalid signature")
+}
+
+// VerifiedStatement is a statement whose envelope was verified.
+type VerifiedStatement struct {
+	Name  string
+	KeyID string
+}
+
+// VerifyStatements verifies that every statement in dir is signed with the
+// key of v, and unchanged since, apart from the subject BuildKit adds. All
+// failures are reported, along with the statements that were verified.
+func (v *Verifier) VerifyStatements(ctx context.Context, dir string) ([]VerifiedStatement, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	statements := map[string]struct{}{}
+	var signatures []string
+	for _, entry := range entries {
+		switch name := entry.Name(); {
+		case !entry.Type().IsRegular() || !strings.HasSuffix(name, ".json"):
+		case strings.HasSuffix(name, SignatureSuffix):
+			signatures = append(signatures, name)
+		default:
+			statements[name] = struct{}{}
+		}
+	}
+	if len(statements) == 0 {
+		return nil, errors.Errorf("no statements found in %s", dir)
+	}
+
+	ev, err := dsse.NewEnvelopeVerifier(v)
+	if err != nil {
+		return nil, err
+	}
+	var verified []VerifiedStatement
+	var failures []string
+	for _, name := range signatures {
+		sig, err := readSignature(filepath.Join(dir, name))
+		if err != nil {
+			failures = append(failures, name+": "+err.Error())
+			continue
+		}
+		if sig.Statement != strings.TrimSuffix(name, SignatureSuffix)+".json" {
+			failures = append(failures, name+": signs "+sig.Statement+", not the statement it is named after")
+			continue
+		}
+		if _, ok := statements[sig.Statement]; !ok {
+			failures = append(failures, name+": statement "+sig.Statement+" not found")
+			continue
+		}
+		delete(statements, sig.Statement)
+		if sig.Envelope.PayloadType != payloadTypeInToto {
+			failures = append(failures, sig.Statement+": unexpected payload type "+sig.Envelope.PayloadType)
+			continue
+		}
+		if _, err := ev.Verify(ctx, &sig.Envelope); err != nil {
+			failures = append(failures, sig.Statement+": "+err.Error())
+			continue
+		}
+		payload, err := sig.Envelope.DecodeB64Payload()
+		if err != nil {
+			failures = append(failures, sig.Statement+": "+err.Error())
+			continue
+		}
+		dt, err := os.ReadFile(filepath.Join(dir, sig.Statement))
+		if err != nil {
+			failures = append(failures, sig.Statement+": "+err.Error())
+			continue
+		}
+		if !sameStatement(payload, dt) {
+			failures = append(failures, sig.Statement+": statement differs from the signed payload")
+			continue
+		}
+		verified = append(verified, VerifiedStatement{Name: sig.Statement, KeyID: v.keyID})
+	}
+	for name := range statements {
+		failures = append(failures, name+": not signed")
+	}
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		return verified, errors.Errorf("%d statements failed verification:\n%s", len(failures), strings.Join(failures, "\n"))
+	}
+	return verified, nil
+}
+
+func readSignature(p string) (*Signature, error) {
+	dt, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	var stmt struct {
+		PredicateType string    `json:"predicateType"`
+		Predicate     Signature `json:"predicate"`
+	}
+	if err := json.Unmarshal(dt, &stmt); err != nil {
+		return nil, errors.Wrap(err, "invalid signature statement")
+	}
+	if stmt.PredicateType != PredicateSignature {
+		return nil, errors.Errorf("unexpected predicate type %q", stmt.PredicateType)
+	}
+	return &stmt.Predicate, nil
+}
+
+// sameStatement reports whether the statement dt is the signed payload,
+// ignoring the subject added by BuildKit.
+func sameStatement(payload, dt []byte) bool {
+	if bytes.Equal(payload, dt) {
+		return true
+	}
+	var signed, actual map[string]interface{}
+	if json.Unmarshal(payload, &signed) != nil || json.Unmarshal(dt, &actual) != nil {
+		return false
+	}
+	if signed["subject"] != nil {
+		return false
+	}
+	delete(signed, "subject")
+	delete(actual, "subject")
+	return reflect.DeepEqual(signed, actual)
+}
+
+// ecdsaDigest hashes data with the hash matching the size of curve.
+func ecdsaDigest(curve elliptic.Curve, data []byte) []byte {
+	var h hash.Hash
+	switch bits := curve.Params().BitSize; {
+	case bits <= 256:
+		h = sha256.New()
+	case bits <= 384:
+		h = sha512.New384()
+	default:
+		h = sha512.New()
+	}
+	h.Write(data)
+	return h.Sum(nil)
+}
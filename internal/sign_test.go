@@ -0,0 +1,201 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// generateKey returns a PEM encoded private key and its public key.
+func generateKey(t *testing.T, ecdsaCurve elliptic.Curve) ([]byte, []byte) {
+	t.Helper()
+	var priv crypto.Signer
+	var err error
+	if ecdsaCurve != nil {
+		priv, err = ecdsa.GenerateKey(ecdsaCurve, rand.Reader)
+	} else {
+		_, priv, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(priv.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+}
+
+func TestSignStatements(t *testing.T) {
+	core := buildFixture(t, filepath.Join(t.TempDir(), "sbom"), alpineFixture)
+	formats, err := ParseFormats("spdx-json,syft-json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, curve := range map[string]elliptic.Curve{
+		"ed25519": nil,
+		"p256":    elliptic.P256(),
+		"p384":    elliptic.P384(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			privPEM, pubPEM := generateKey(t, curve)
+			signer, err := LoadSigner(privPEM)
+			if err != nil {
+				t.Fatal(err)
+			}
+			verifier, err := LoadVerifier(pubPEM)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(signer.keyID, "SHA256:") || signer.keyID != verifier.keyID {
+				t.Fatalf("unexpected key ids %q and %q", signer.keyID, verifier.keyID)
+			}
+
+			scanner := Scanner{
+				Core:        Target{Path: core},
+				Destination: t.TempDir(),
+				Formats:     formats,
+				Signer:      signer,
+			}
+			if err := scanner.Scan(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			assertStatement(t, filepath.Join(scanner.Destination, "sbom.spdx"+SignatureSuffix), `{
+				"_type": "https://in-toto.io/Statement/v1",
+				"predicateType": "https://github.com/docker/buildkit-syft-scanner/dsse/v1",
+				"predicate": {
+					"statement": "sbom.spdx.json",
+					"keyid": "`+signer.keyID+`",
+					"envelope": {
+						"payloadType": "application/vnd.in-toto+json",
+						"signatures": [{"keyid": "`+signer.keyID+`"}]
+					}
+				}
+			}`)
+
+			verified, err := verifier.VerifyStatements(context.Background(), scanner.Destination)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(verified) != 2 {
+				t.Errorf("expected 2 verified statements, got %+v", verified)
+			}
+
+			// BuildKit adds the subject after the scan
+			p := filepath.Join(scanner.Destination, "sbom.spdx.json")
+			setStatementField(t, p, "subject", []map[string]interface{}{{
+				"name":   "image",
+				"digest": map[string]string{"sha256": strings.Repeat("0", 64)},
+			}})
+			if _, err := verifier.VerifyStatements(context.Background(), scanner.Destination); err != nil {
+				t.Errorf("expected the subject to be ignored: %v", err)
+			}
+
+			setStatementField(t, p, "predicateType", "https://example.com")
+			if err := os.Remove(filepath.Join(scanner.Destination, "sbom.syft"+SignatureSuffix)); err != nil {
+				t.Fatal(err)
+			}
+			_, err = verifier.VerifyStatements(context.Background(), scanner.Destination)
+			want := `2 statements failed verification:
+sbom.spdx.json: statement differs from the signed payload
+sbom.syft.json: not signed`
+			if err == nil || err.Error() != want {
+				t.Errorf("unexpected error:\n%v\nexpected:\n%s", err, want)
+			}
+
+			_, otherPEM := generateKey(t, curve)
+			other, err := LoadVerifier(otherPEM)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := other.VerifyStatements(context.Background(), scanner.Destination); err == nil || !strings.Contains(err.Error(), "sbom.spdx.json: ") {
+				t.Errorf("expected verification with another key to fail, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadSignerErrors(t *testing.T) {
+	_, pubPEM := generateKey(t, nil)
+	for name, dt := range map[string][]byte{
+		"empty":  nil,
+		"public": pubPEM,
+		"block":  pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: []byte("x")}),
+		"der":    pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("x")}),
+	} {
+		if _, err := LoadSigner(dt); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoadSignerFromEnvironment(t *testing.T) {
+	privPEM, _ := generateKey(t, nil)
+	p := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(p, privPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(envScanSigningKey, p)
+	if signer, err := loadSignerFromEnvironment(); err != nil || signer == nil {
+		t.Fatalf("expected the key to be loaded from its path: %v", err)
+	}
+
+	// the key itself would leak with the build
+	t.Setenv(envScanSigningKey, string(privPEM))
+	_, err := loadSignerFromEnvironment()
+	if err == nil {
+		t.Fatal("expected the key itself to be refused")
+	}
+	if strings.Contains(err.Error(), "PRIVATE KEY") {
+		t.Errorf("expected the error not to include the key: %v", err)
+	}
+}
+
+func setStatementField(t *testing.T, p string, key string, value interface{}) {
+	t.Helper()
+	dt, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stmt map[string]interface{}
+	if err := json.Unmarshal(dt, &stmt); err != nil {
+		t.Fatal(err)
+	}
+	stmt[key] = value
+	if dt, err = json.Marshal(stmt); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, dt, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -16,59 +16,134 @@ package internal
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
-	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/anchore/syft/syft"
-	"github.com/anchore/syft/syft/cataloging/filecataloging"
-	"github.com/anchore/syft/syft/cataloging/pkgcataloging"
 	"github.com/anchore/syft/syft/sbom"
 	"github.com/anchore/syft/syft/source"
 	"github.com/docker/buildkit-syft-scanner/version"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 )
 
 type Target struct {
-	Path string
+	Path   string
+	Config *Config
+	// Cache, if set, stores the packages cataloged from artifacts such as
+	// jars and Go binaries, which are not parsed again while unchanged.
+	Cache *Cache
+
+	// skip are the paths of files hidden from the scan on top of the
+	// configured exclusions, which are not recorded in the SBOMs.
+	skip map[string]struct{}
 }
 
 func (t Target) Name() string {
 	return filepath.Base(t.Path)
 }
 
+func (t Target) config() Config {
+	if t.Config == nil {
+		return DefaultConfig()
+	}
+	return *t.Config
+}
+
 func (t Target) Scan(ctx context.Context) (sbom.SBOM, error) {
-	src, err := syft.GetSource(context.Background(), t.Path,
+	cfg := t.config()
+	exclude := cfg.ExcludeConfig()
+	createCfg := cfg.CreateSBOMConfig()
+
+	var fingerprint string
+	var hits int
+	var cached []*cachingCataloger
+	var sel cacheSelection
+	if t.Cache != nil {
+		var err error
+		fingerprint, err = t.Cache.fingerprint(cfg)
+		if err != nil {
+			return sbom.SBOM{}, newError(ErrorConfig, t.Name(), errors.Wrap(err, "failed to fingerprint config"))
+		}
+		sel, err = t.Cache.selection(ctx, fingerprint, cfg)
+		if err != nil {
+			if cause := context.Cause(ctx); cause != nil {
+				return sbom.SBOM{}, newError(ErrorAborted, t.Name(), cause)
+			}
+			return sbom.SBOM{}, newError(ErrorCataloger, t.Name(), errors.Wrap(err, "failed to select cached catalogers"))
+		}
+		cached = t.Cache.catalogers(createCfg, sel)
+		hits, err = t.Cache.lookup(ctx, t.Path, fingerprint, cached, exclude.Paths, t.skip)
+		if err != nil {
+			if cause := context.Cause(ctx); cause != nil {
+				return sbom.SBOM{}, newError(ErrorAborted, t.Name(), cause)
+			}
+			return sbom.SBOM{}, newError(ErrorSource, t.Name(), errors.Wrap(err, "failed to look up cache"))
+		}
+	}
+	src, err := syft.GetSource(ctx, t.Path,
 		syft.DefaultGetSourceConfig().
 			WithBasePath(t.Path).
-			WithAlias(source.Alias{Name: t.Name()}))
+			WithAlias(source.Alias{Name: t.Name()}).
+			WithExcludeConfig(exclude))
 	if err != nil {
-		return sbom.SBOM{}, fmt.Errorf("failed to get source from %q: %w", t.Path, err)
+		return sbom.SBOM{}, newError(ErrorSource, t.Name(), fmt.Errorf("failed to get source from %q: %w", t.Path, err))
 	}
-
-	sr := pkgcataloging.NewSelectionRequest().
-		WithDefaults(
-			pkgcataloging.ImageTag,
-			filecataloging.FileTag, // https://github.com/anchore/syft/pull/3505
-		).
-		WithAdditions(
-			"sbom-cataloger",
-		)
-
-	if v, ok := os.LookupEnv("BUILDKIT_SCAN_SELECT_CATALOGERS"); ok {
-		sr = pkgcataloging.NewSelectionRequest().WithExpression(strings.Split(v, ",")...)
+	defer src.Close()
+	if len(t.skip) > 0 {
+		src = skipSource{Source: src, skip: t.skip}
 	}
 
-	result, err := syft.CreateSBOM(
-		ctx,
-		src,
-		syft.DefaultCreateSBOMConfig().
-			WithCatalogerSelection(sr))
+	result, err := syft.CreateSBOM(ctx, src, createCfg)
+	// catalogers stop early on cancellation, so the result may be incomplete
+	if cause := context.Cause(ctx); cause != nil {
+		return sbom.SBOM{}, newError(ErrorAborted, t.Name(), cause)
+	}
 	if err != nil {
-		return sbom.SBOM{}, err
+		return sbom.SBOM{}, newError(ErrorCataloger, t.Name(), err)
 	}
 
 	result.Descriptor.Name = "syft"
 	result.Descriptor.Version = version.SyftVersion
+
+	if t.Cache != nil {
+		// the selection was changed to swap in the cached catalogers
+		result.Descriptor.Configuration = sel.configuration
+		// failing to update the cache does not fail the scan
+		stored, err := t.Cache.store(fingerprint, cached)
+		if err != nil {
+			logrus.Warnf("%s: failed to update cache: %v", t.Name(), err)
+		}
+		evicted, err := t.Cache.evict()
+		if err != nil {
+			logrus.Warnf("%s: failed to trim cache: %v", t.Name(), err)
+		}
+		logrus.Infof("%s: cache: %d hits, %d stored, %d evicted", t.Name(), hits, stored, evicted)
+	}
 	return *result, nil
 }
+
+// properties returns the settings of t recorded in its SBOMs: the
+// exclusions, and the effective configuration along with its digest, so that
+// SBOMs of the same image generated with different settings can be told
+// apart.
+func (t Target) properties() ([]property, error) {
+	var props []property
+	cfg := t.config()
+	exclude := cfg.ExcludeConfig().Paths
+	if len(exclude) > 0 {
+		props = append(props, property{name: "exclude", values: exclude})
+	}
+	cfg.Exclude = exclude
+	dt, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode config")
+	}
+	props = append(props,
+		property{name: "config-digest", values: []string{fmt.Sprintf("sha256:%x", sha256.Sum256(dt))}},
+		property{name: "config", values: []string{string(dt)}},
+	)
+	return props, nil
+}
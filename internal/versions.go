@@ -0,0 +1,387 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"strconv"
+	"strings"
+)
+
+// versionComparator returns the function comparing the versions of the
+// packages of purl type typ, following the rules of their package manager
+// for distribution packages, and compareVersions otherwise.
+func versionComparator(typ string) func(a, b string) int {
+	switch typ {
+	case "deb":
+		return compareDebVersions
+	case "rpm":
+		return compareRPMVersions
+	case "apk":
+		return compareAPKVersions
+	}
+	return compareVersions
+}
+
+// purlType returns the type of purl, e.g. "deb" for "pkg:deb/debian/libc6".
+func purlType(purl string) string {
+	typ, _, _ := strings.Cut(strings.TrimPrefix(purl, "pkg:"), "/")
+	return strings.ToLower(typ)
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// splitEpoch returns the epoch of a "[epoch:]version" string, 0 if it has
+// none, and the rest of it.
+func splitEpoch(v string) (int, string) {
+	if i := strings.IndexByte(v, ':'); i >= 0 {
+		if epoch, err := strconv.Atoi(v[:i]); err == nil {
+			return epoch, v[i+1:]
+		}
+	}
+	return 0, v
+}
+
+// splitRevision returns the version and the revision of a
+// "version[-revision]" string.
+func splitRevision(v string) (string, string) {
+	if i := strings.LastIndexByte(v, '-'); i >= 0 {
+		return v[:i], v[i+1:]
+	}
+	return v, ""
+}
+
+// compareDebVersions compares Debian versions, "[epoch:]upstream[-revision]",
+// as dpkg does, with "~" sorting before anything, even the end of the
+// version.
+func compareDebVersions(a, b string) int {
+	epochA, a := splitEpoch(a)
+	epochB, b := splitEpoch(b)
+	if epochA != epochB {
+		return sign(epochA - epochB)
+	}
+	upstreamA, revisionA := splitRevision(a)
+	upstreamB, revisionB := splitRevision(b)
+	if c := dpkgVerrevcmp(upstreamA, upstreamB); c != 0 {
+		return c
+	}
+	return dpkgVerrevcmp(revisionA, revisionB)
+}
+
+// dpkgOrder is the weight of a character of a non-digit part of a Debian
+// version.
+func dpkgOrder(s string, i int) int {
+	switch {
+	case i >= len(s) || isDigit(s[i]):
+		return 0
+	case isLetter(s[i]):
+		return int(s[i])
+	case s[i] == '~':
+		return -1
+	}
+	return int(s[i]) + 256
+}
+
+func dpkgVerrevcmp(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			if oa, ob := dpkgOrder(a, i), dpkgOrder(b, j); oa != ob {
+				return sign(oa - ob)
+			}
+			i++
+			j++
+		}
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+		firstDiff := 0
+		for i < len(a) && isDigit(a[i]) && j < len(b) && isDigit(b[j]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return sign(firstDiff)
+		}
+	}
+	return 0
+}
+
+// compareRPMVersions compares RPM versions, "[epoch:]version[-release]", as
+// rpm does, with "~" sorting before anything and "^" after the end of the
+// version but before anything else.
+func compareRPMVersions(a, b string) int {
+	epochA, a := splitEpoch(a)
+	epochB, b := splitEpoch(b)
+	if epochA != epochB {
+		return sign(epochA - epochB)
+	}
+	versionA, releaseA := splitRevision(a)
+	versionB, releaseB := splitRevision(b)
+	if c := rpmvercmp(versionA, versionB); c != 0 {
+		return c
+	}
+	return rpmvercmp(releaseA, releaseB)
+}
+
+func rpmvercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+	separator := func(r rune) bool {
+		return r < 128 && !isDigit(byte(r)) && !isLetter(byte(r)) && r != '~' && r != '^'
+	}
+	for len(a) > 0 || len(b) > 0 {
+		a = strings.TrimLeftFunc(a, separator)
+		b = strings.TrimLeftFunc(b, separator)
+
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^") {
+			switch {
+			case a == "":
+				return -1
+			case b == "":
+				return 1
+			case !strings.HasPrefix(a, "^"):
+				return 1
+			case !strings.HasPrefix(b, "^"):
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if a == "" || b == "" {
+			break
+		}
+
+		numeric := isDigit(a[0])
+		segment := func(s string) (string, string) {
+			i := 0
+			for i < len(s) && (numeric && isDigit(s[i]) || !numeric && isLetter(s[i])) {
+				i++
+			}
+			return s[:i], s[i:]
+		}
+		var segA, segB string
+		segA, a = segment(a)
+		segB, b = segment(b)
+		if segB == "" {
+			// numeric segments are newer than alphabetic ones
+			if numeric {
+				return 1
+			}
+			return -1
+		}
+		if numeric {
+			segA = strings.TrimLeft(segA, "0")
+			segB = strings.TrimLeft(segB, "0")
+			if len(segA) != len(segB) {
+				return sign(len(segA) - len(segB))
+			}
+		}
+		if c := strings.Compare(segA, segB); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	}
+	return 1
+}
+
+// apk version tokens, in the order apk-tools gives them.
+const (
+	apkTokenInvalid = iota - 1
+	apkTokenDigitOrZero
+	apkTokenDigit
+	apkTokenLetter
+	apkTokenSuffix
+	apkTokenSuffixNo
+	apkTokenRevisionNo
+	apkTokenEnd
+)
+
+var (
+	apkPreSuffixes  = []string{"alpha", "beta", "pre", "rc"}
+	apkPostSuffixes = []string{"cvs", "svn", "git", "hg", "p"}
+)
+
+// compareAPKVersions compares Alpine versions, e.g. "1.2.4_git20230717-r4",
+// as apk-tools does, with "_alpha", "_beta", "_pre" and "_rc" suffixes
+// sorting before the version without suffix.
+func compareAPKVersions(a, b string) int {
+	ta, tb := apkTokenDigit, apkTokenDigit
+	va, vb := 0, 0
+	for ta == tb && ta != apkTokenEnd && ta != apkTokenInvalid && va == vb {
+		va = apkToken(&ta, &a)
+		vb = apkToken(&tb, &b)
+	}
+	if va != vb {
+		return sign(va - vb)
+	}
+	if ta == tb {
+		return 0
+	}
+	// the longer version is newer, unless it continues with a pre-release
+	// suffix
+	if ta == apkTokenSuffix && apkToken(&ta, &a) < 0 {
+		return -1
+	}
+	if tb == apkTokenSuffix && apkToken(&tb, &b) < 0 {
+		return 1
+	}
+	return sign(tb - ta)
+}
+
+// apkToken consumes the token of type typ at the start of v, returning its
+// value, and sets typ to the type of the next one.
+func apkToken(typ *int, v *string) int {
+	s := *v
+	if s == "" {
+		*typ = apkTokenEnd
+		return 0
+	}
+	value, i, next := 0, 0, apkTokenInvalid
+	switch *typ {
+	case apkTokenDigitOrZero, apkTokenDigit, apkTokenSuffixNo, apkTokenRevisionNo:
+		// leading zeros of a component make it sort as a fraction, e.g.
+		// 1.05 before 1.5
+		for *typ == apkTokenDigitOrZero && i+1 < len(s) && s[i] == '0' && isDigit(s[i+1]) {
+			i++
+		}
+		if i > 0 {
+			next = apkTokenDigit
+			value = -i
+			break
+		}
+		for i < len(s) && isDigit(s[i]) {
+			value = value*10 + int(s[i]-'0')
+			i++
+		}
+	case apkTokenLetter:
+		value = int(s[0])
+		i++
+	case apkTokenSuffix:
+		found := false
+		for n, suffix := range apkPreSuffixes {
+			if strings.HasPrefix(s, suffix) {
+				value, i, found = n-len(apkPreSuffixes), len(suffix), true
+				break
+			}
+		}
+		for n, suffix := range apkPostSuffixes {
+			if found {
+				break
+			}
+			if strings.HasPrefix(s, suffix) {
+				value, i, found = n+1, len(suffix), true
+			}
+		}
+		if !found {
+			*typ = apkTokenInvalid
+			return -1
+		}
+	default:
+		*typ = apkTokenInvalid
+		return -1
+	}
+	s = s[i:]
+	switch {
+	case s == "":
+		*typ = apkTokenEnd
+	case next != apkTokenInvalid:
+		*typ = next
+	default:
+		apkNextToken(typ, &s)
+	}
+	*v = s
+	return value
+}
+
+// apkNextToken sets typ to the type of the token at the start of v, which
+// follows a token of type typ, consuming its separator.
+func apkNextToken(typ *int, v *string) {
+	s := *v
+	next := apkTokenInvalid
+	switch {
+	case s == "":
+		next = apkTokenEnd
+	case (*typ == apkTokenDigit || *typ == apkTokenDigitOrZero) && s[0] >= 'a' && s[0] <= 'z':
+		next = apkTokenLetter
+	case *typ == apkTokenLetter && isDigit(s[0]):
+		next = apkTokenDigit
+	case *typ == apkTokenSuffix && isDigit(s[0]):
+		next = apkTokenSuffixNo
+	default:
+		switch s[0] {
+		case '.':
+			next = apkTokenDigitOrZero
+		case '_':
+			next = apkTokenSuffix
+		case '-':
+			if strings.HasPrefix(s, "-r") {
+				next = apkTokenRevisionNo
+				s = s[1:]
+			}
+		}
+		s = s[1:]
+	}
+	if next < *typ && !(next == apkTokenDigitOrZero && *typ == apkTokenDigit ||
+		next == apkTokenSuffix && *typ == apkTokenSuffixNo ||
+		next == apkTokenDigit && *typ == apkTokenLetter) {
+		next = apkTokenInvalid
+	}
+	*typ = next
+	*v = s
+}
@@ -0,0 +1,62 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import "testing"
+
+func TestCompareDistroVersions(t *testing.T) {
+	for _, tc := range []struct {
+		typ  string
+		a, b string
+		want int
+	}{
+		{"deb", "2.36-9+deb12u4", "2.36-9+deb12u4", 0},
+		{"deb", "2.36-9+deb12u4", "2.36-9+deb12u10", -1},
+		{"deb", "2.36-9+deb12u3", "2.36-9+deb12u4", -1},
+		{"deb", "1.0~rc1", "1.0", -1},
+		{"deb", "1.0~rc1-1", "1.0~rc2-1", -1},
+		{"deb", "1:2.0", "2.36", 1},
+		{"deb", "2.36-9", "2.36-9+b1", -1},
+		{"deb", "1.0a", "1.0", 1},
+		{"deb", "1.0-1", "1.0+dfsg-1", -1},
+		{"deb", "1.002", "1.2", 0},
+		{"rpm", "2.38-14.fc39", "2.38-14.fc39", 0},
+		{"rpm", "2.38-14.fc39", "2.38-16.fc39", -1},
+		{"rpm", "1.0~rc1", "1.0", -1},
+		{"rpm", "1:1.0-1", "2.0-1", 1},
+		{"rpm", "1.0^git1", "1.0", 1},
+		{"rpm", "1.0^git1", "1.0.1", -1},
+		{"rpm", "2.28-225.el8", "2.28-225.el8_9.1", -1},
+		{"rpm", "1.0a", "1.0.1", -1},
+		{"apk", "1.2.4_git20230717-r4", "1.2.4_git20230717-r5", -1},
+		{"apk", "1.36.1-r15", "1.36.1-r15", 0},
+		{"apk", "1.0_rc1", "1.0", -1},
+		{"apk", "1.0_alpha1", "1.0_beta1", -1},
+		{"apk", "1.0_p1", "1.0", 1},
+		{"apk", "1.0", "1.0-r1", -1},
+		{"apk", "1.10", "1.9", 1},
+		{"apk", "1.05", "1.5", -1},
+		{"apk", "1.2a", "1.2.1", -1},
+		{"apk", "1.2.4_git20230717-r4", "1.2.4-r0", 1},
+		{"npm", "1.0.0-rc.1", "1.0.0", -1},
+	} {
+		if got := versionComparator(tc.typ)(tc.a, tc.b); got != tc.want {
+			t.Errorf("%s: compare(%q, %q): expected %d, got %d", tc.typ, tc.a, tc.b, tc.want, got)
+		}
+		if got := versionComparator(tc.typ)(tc.b, tc.a); got != -tc.want {
+			t.Errorf("%s: compare(%q, %q): expected %d, got %d", tc.typ, tc.b, tc.a, -tc.want, got)
+		}
+	}
+}
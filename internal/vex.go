@@ -0,0 +1,318 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anchore/syft/syft/sbom"
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/docker/buildkit-syft-scanner/version"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// PredicateOpenVEX is the in-toto predicate type of the OpenVEX statement.
+const PredicateOpenVEX = "https://openvex.dev/ns/v0.2.0"
+
+// VEXSuffix is appended to the name of the core target for the OpenVEX
+// statement, e.g. "sbom.openvex.json".
+const VEXSuffix = ".openvex.json"
+
+// DefaultVEX are the globs of VEX documents looked for in the image and the
+// scanned build stages.
+var DefaultVEX = []string{"/var/share/vex/**/*.json"}
+
+var vexStatuses = []string{"not_affected", "affected", "fixed", "under_investigation"}
+
+// vexDocument is an OpenVEX document.
+type vexDocument struct {
+	Context    string         `json:"@context"`
+	ID         string         `json:"@id"`
+	Author     string         `json:"author"`
+	Timestamp  *time.Time     `json:"timestamp,omitempty"`
+	Version    int            `json:"version"`
+	Tooling    string         `json:"tooling,omitempty"`
+	Statements []vexStatement `json:"statements"`
+}
+
+type vexStatement struct {
+	Vulnerability   vexVulnerability `json:"vulnerability"`
+	Timestamp       *time.Time       `json:"timestamp,omitempty"`
+	Products        []vexProduct     `json:"products"`
+	Status          string           `json:"status"`
+	StatusNotes     string           `json:"status_notes,omitempty"`
+	Justification   string           `json:"justification,omitempty"`
+	ImpactStatement string           `json:"impact_statement,omitempty"`
+	ActionStatement string           `json:"action_statement,omitempty"`
+}
+
+type vexVulnerability struct {
+	ID          string   `json:"@id,omitempty"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Aliases     []string `json:"aliases,omitempty"`
+}
+
+// UnmarshalJSON also accepts the plain vulnerability names of OpenVEX
+// v0.0.1.
+func (v *vexVulnerability) UnmarshalJSON(dt []byte) error {
+	if trimmed := bytes.TrimSpace(dt); len(trimmed) > 0 && trimmed[0] == '"' {
+		*v = vexVulnerability{}
+		return json.Unmarshal(dt, &v.Name)
+	}
+	type plain vexVulnerability
+	return json.Unmarshal(dt, (*plain)(v))
+}
+
+type vexProduct struct {
+	ID            string            `json:"@id,omitempty"`
+	Identifiers   map[string]string `json:"identifiers,omitempty"`
+	Subcomponents []vexProduct      `json:"subcomponents,omitempty"`
+}
+
+// vexFragment is a VEX document found in a scanned target.
+type vexFragment struct {
+	path string
+	doc  vexDocument
+}
+
+// findVEX reads the VEX documents matching globs inside the filesystem
+// rooted at root.
+func findVEX(root string, globs []string) ([]vexFragment, error) {
+	var fragments []vexFragment
+	seen := map[string]struct{}{}
+	for _, g := range globs {
+		matches, err := doublestar.Glob(os.DirFS(root), strings.TrimPrefix(path.Clean("/"+g), "/"), doublestar.WithFilesOnly())
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid VEX glob %q", g)
+		}
+		sort.Strings(matches)
+		for _, m := range matches {
+			if _, ok := seen[m]; ok {
+				continue
+			}
+			seen[m] = struct{}{}
+			dt, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(m)))
+			if err != nil {
+				return nil, err
+			}
+			f := vexFragment{path: "/" + m}
+			if err := json.Unmarshal(dt, &f.doc); err != nil {
+				return nil, errors.Wrapf(err, "invalid VEX document %q", f.path)
+			}
+			if err := f.validate(); err != nil {
+				return nil, errors.Wrapf(err, "invalid VEX document %q", f.path)
+			}
+			fragments = append(fragments, f)
+		}
+	}
+	return fragments, nil
+}
+
+func (f vexFragment) validate() error {
+	if !strings.HasPrefix(f.doc.Context, "https://openvex.dev/ns") {
+		return errors.Errorf("unsupported @context %q", f.doc.Context)
+	}
+	for i, stmt := range f.doc.Statements {
+		if stmt.Vulnerability.Name == "" {
+			return errors.Errorf("statement %d: missing vulnerability name", i)
+		}
+		if !slices.Contains(vexStatuses, stmt.Status) {
+			return errors.Errorf("statement %d: status must be one of %s, got %q", i, strings.Join(vexStatuses, ", "), stmt.Status)
+		}
+		if stmt.Status == "not_affected" && stmt.Justification == "" && stmt.ImpactStatement == "" {
+			return errors.Errorf("statement %d: not_affected requires a justification or an impact statement", i)
+		}
+		if len(stmt.Products) == 0 {
+			return errors.Errorf("statement %d: missing products", i)
+		}
+	}
+	return nil
+}
+
+// vexIndex resolves VEX product identifiers to the purls of the packages of
+// an SBOM.
+type vexIndex struct {
+	byPURL map[string][]vexPackage
+	byCPE  map[string][]string
+}
+
+type vexPackage struct {
+	purl    string
+	version string
+}
+
+func newVEXIndex(s sbom.SBOM) vexIndex {
+	idx := vexIndex{byPURL: map[string][]vexPackage{}, byCPE: map[string][]string{}}
+	for _, p := range s.Artifacts.Packages.Sorted() {
+		if p.PURL == "" {
+			continue
+		}
+		key := normalizePURL(p.PURL)
+		_, v := splitPURL(p.PURL)
+		if v == "" {
+			v = p.Version
+		}
+		idx.byPURL[key] = append(idx.byPURL[key], vexPackage{purl: p.PURL, version: v})
+		for _, c := range p.CPEs {
+			cpe := strings.ToLower(c.Attributes.BindToFmtString())
+			idx.byCPE[cpe] = append(idx.byCPE[cpe], p.PURL)
+		}
+	}
+	return idx
+}
+
+// resolve returns the purls of the packages identified by product. If the
+// product has subcomponents, the statement applies to them rather than to
+// the product itself, typically the image.
+func (idx vexIndex) resolve(product vexProduct) []string {
+	if len(product.Subcomponents) > 0 {
+		var purls []string
+		for _, sub := range product.Subcomponents {
+			purls = append(purls, idx.resolve(sub)...)
+		}
+		return purls
+	}
+
+	var purls []string
+	for _, id := range []string{product.ID, product.Identifiers["purl"]} {
+		if !strings.HasPrefix(id, "pkg:") {
+			continue
+		}
+		_, v := splitPURL(id)
+		for _, p := range idx.byPURL[normalizePURL(id)] {
+			if v == "" || strings.TrimPrefix(v, "v") == strings.TrimPrefix(p.version, "v") {
+				purls = append(purls, p.purl)
+			}
+		}
+	}
+	for _, id := range []string{product.ID, product.Identifiers["cpe23"], product.Identifiers["cpe22"]} {
+		if strings.HasPrefix(id, "cpe:") {
+			purls = append(purls, idx.byCPE[strings.ToLower(id)]...)
+		}
+	}
+	return purls
+}
+
+// newVEXDocument merges the statements of fragments into a single OpenVEX
+// document, with products replaced by the purls of the packages of s they
+// identify. Statements that identify no package are dropped. It also returns
+// the number of products not found in s.
+func newVEXDocument(s sbom.SBOM, fragments []vexFragment, epoch time.Time) (vexDocument, int, error) {
+	idx := newVEXIndex(s)
+	var authors []string
+	var unmatched int
+	var latest time.Time
+	merged := map[string]*vexStatement{}
+	var keys []string
+	for _, f := range fragments {
+		authors = append(authors, f.doc.Author)
+		for _, stmt := range f.doc.Statements {
+			var purls []string
+			for _, product := range stmt.Products {
+				resolved := idx.resolve(product)
+				if len(resolved) == 0 {
+					unmatched++
+				}
+				purls = append(purls, resolved...)
+			}
+			if len(purls) == 0 {
+				continue
+			}
+
+			// statements inherit the timestamp of their document
+			ts := stmt.Timestamp
+			if ts == nil {
+				ts = f.doc.Timestamp
+			}
+			stmt.Timestamp = nil
+			stmt.Products = nil
+			key, err := json.Marshal(stmt)
+			if err != nil {
+				return vexDocument{}, 0, err
+			}
+			m, ok := merged[string(key)]
+			if !ok {
+				m = &stmt
+				merged[string(key)] = m
+				keys = append(keys, string(key))
+			}
+			if ts != nil && (m.Timestamp == nil || ts.After(*m.Timestamp)) {
+				t := ts.UTC()
+				m.Timestamp = &t
+			}
+			if ts != nil && ts.After(latest) {
+				latest = *ts
+			}
+			for _, purl := range purls {
+				if !slices.ContainsFunc(m.Products, func(p vexProduct) bool { return p.ID == purl }) {
+					m.Products = append(m.Products, vexProduct{ID: purl})
+				}
+			}
+		}
+	}
+
+	doc := vexDocument{
+		Context:    PredicateOpenVEX,
+		Version:    1,
+		Tooling:    version.Package + "@" + version.Version,
+		Statements: []vexStatement{},
+	}
+	for _, key := range keys {
+		stmt := merged[key]
+		sort.Slice(stmt.Products, func(i, j int) bool { return stmt.Products[i].ID < stmt.Products[j].ID })
+		doc.Statements = append(doc.Statements, *stmt)
+	}
+	sort.SliceStable(doc.Statements, func(i, j int) bool {
+		a, b := doc.Statements[i], doc.Statements[j]
+		if a.Vulnerability.Name != b.Vulnerability.Name {
+			return a.Vulnerability.Name < b.Vulnerability.Name
+		}
+		return a.Products[0].ID < b.Products[0].ID
+	})
+
+	slices.Sort(authors)
+	authors = slices.Compact(authors)
+	authors = slices.DeleteFunc(authors, func(a string) bool { return a == "" })
+	doc.Author = strings.Join(authors, ", ")
+	if doc.Author == "" {
+		doc.Author = version.Package
+	}
+
+	switch {
+	case !epoch.IsZero():
+		latest = epoch
+	case latest.IsZero():
+		latest = time.Now()
+	}
+	latest = latest.UTC().Truncate(time.Second)
+	doc.Timestamp = &latest
+
+	dt, err := json.Marshal(doc)
+	if err != nil {
+		return vexDocument{}, 0, err
+	}
+	doc.ID = "https://openvex.dev/docs/public/vex-" + uuid.NewSHA1(uuid.NameSpaceURL, dt).String()
+	return doc, unmatched, nil
+}
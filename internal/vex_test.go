@@ -0,0 +1,155 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanVEX(t *testing.T) {
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.SelectCatalogers = []string{"+javascript-lock-cataloger"}
+	core := Target{Path: buildFixture(t, filepath.Join(dir, "sbom"), alpineFixture, npmLockFixture, files(map[string]string{
+		"var/share/vex/app.json": `{
+			"@context": "https://openvex.dev/ns/v0.2.0",
+			"@id": "https://example.com/vex/app",
+			"author": "App Team",
+			"timestamp": "2024-03-01T10:00:00Z",
+			"version": 1,
+			"statements": [
+				{
+					"vulnerability": {"name": "CVE-2024-0001"},
+					"products": [{
+						"@id": "pkg:oci/app",
+						"subcomponents": [{"@id": "pkg:apk/alpine/busybox"}]
+					}],
+					"status": "not_affected",
+					"justification": "vulnerable_code_not_present"
+				},
+				{
+					"vulnerability": {"name": "CVE-2024-0002"},
+					"products": [{"@id": "pkg:npm/ms@2.1.3"}],
+					"status": "fixed"
+				},
+				{
+					"vulnerability": {"name": "CVE-2024-0003"},
+					"products": [{"@id": "pkg:npm/left-pad@1.3.0"}],
+					"status": "affected",
+					"action_statement": "remove left-pad"
+				}
+			]
+		}`,
+	})), Config: &cfg}
+	stage := Target{Path: buildFixture(t, filepath.Join(dir, "extras", "sbom-context"), files(map[string]string{
+		"var/share/vex/musl.json": `{
+			"@context": "https://openvex.dev/ns",
+			"@id": "https://example.com/vex/musl",
+			"author": "Base Team",
+			"timestamp": "2024-04-01T10:00:00Z",
+			"version": 1,
+			"statements": [
+				{
+					"vulnerability": "CVE-2024-0001",
+					"products": [{"@id": "pkg:apk/alpine/musl@1.2.4_git20230717-r4?arch=x86_64"}],
+					"status": "not_affected",
+					"justification": "vulnerable_code_not_present"
+				}
+			]
+		}`,
+	})), Config: &cfg}
+	formats, err := ParseFormats("spdx-json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := Scanner{
+		Core:        core,
+		Extras:      []Target{stage},
+		Destination: t.TempDir(),
+		Formats:     formats,
+		VEX:         DefaultVEX,
+	}
+	if err := scanner.Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(scanner.Destination, "sbom"+VEXSuffix)
+	assertStatement(t, p, `{
+		"_type": "https://in-toto.io/Statement/v1",
+		"predicateType": "https://openvex.dev/ns/v0.2.0",
+		"predicate": {
+			"@context": "https://openvex.dev/ns/v0.2.0",
+			"author": "App Team, Base Team",
+			"timestamp": "2024-04-01T10:00:00Z",
+			"version": 1,
+			"statements": [
+				{
+					"vulnerability": {"name": "CVE-2024-0001"},
+					"timestamp": "2024-04-01T10:00:00Z",
+					"products": [
+						{"@id": "pkg:apk/alpine/busybox@1.36.1-r15?arch=x86_64&distro=alpine-3.19.1"},
+						{"@id": "pkg:apk/alpine/musl@1.2.4_git20230717-r4?arch=x86_64&distro=alpine-3.19.1"}
+					],
+					"status": "not_affected",
+					"justification": "vulnerable_code_not_present"
+				},
+				{
+					"vulnerability": {"name": "CVE-2024-0002"},
+					"timestamp": "2024-03-01T10:00:00Z",
+					"products": [{"@id": "pkg:npm/ms@2.1.3"}],
+					"status": "fixed"
+				}
+			]
+		}
+	}`)
+
+	var stmt struct {
+		Predicate vexDocument `json:"predicate"`
+	}
+	dt, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(dt, &stmt); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(stmt.Predicate.Statements); n != 2 {
+		t.Errorf("expected the statement on a package not in the image to be dropped, got %d statements", n)
+	}
+	if !strings.HasPrefix(stmt.Predicate.ID, "https://openvex.dev/docs/public/vex-") {
+		t.Errorf("unexpected document id %q", stmt.Predicate.ID)
+	}
+}
+
+func TestFindVEXErrors(t *testing.T) {
+	for name, doc := range map[string]string{
+		"json":          `{`,
+		"context":       `{"@context": "https://example.com", "statements": []}`,
+		"status":        `{"@context": "https://openvex.dev/ns/v0.2.0", "statements": [{"vulnerability": {"name": "CVE-1"}, "products": [{"@id": "pkg:npm/ms"}], "status": "ok"}]}`,
+		"justification": `{"@context": "https://openvex.dev/ns/v0.2.0", "statements": [{"vulnerability": {"name": "CVE-1"}, "products": [{"@id": "pkg:npm/ms"}], "status": "not_affected"}]}`,
+		"products":      `{"@context": "https://openvex.dev/ns/v0.2.0", "statements": [{"vulnerability": {"name": "CVE-1"}, "status": "fixed"}]}`,
+	} {
+		root := buildFixture(t, t.TempDir(), files(map[string]string{"var/share/vex/doc.json": doc}))
+		_, err := findVEX(root, DefaultVEX)
+		if err == nil || !strings.Contains(err.Error(), `"/var/share/vex/doc.json"`) {
+			t.Errorf("%s: expected an error naming the document, got %v", name, err)
+		}
+	}
+}
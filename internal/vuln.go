@@ -0,0 +1,677 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/anchore/syft/syft/sbom"
+	"github.com/docker/buildkit-syft-scanner/version"
+	"github.com/pkg/errors"
+)
+
+// PredicateVulns is the in-toto predicate type of the vulnerability report
+// statement.
+const PredicateVulns = "https://in-toto.io/attestation/vulns/v0.1"
+
+// VulnsSuffix is appended to the name of the core target for the
+// vulnerability report statement, e.g. "sbom.vulns.json".
+const VulnsSuffix = ".vulns.json"
+
+// Severity is the qualitative severity of a vulnerability.
+type Severity int
+
+const (
+	SeverityUnknown Severity = iota
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+var severityNames = []string{"unknown", "low", "medium", "high", "critical"}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return severityNames[SeverityUnknown]
+	}
+	return severityNames[s]
+}
+
+// ParseSeverity parses a severity name, as used by OSV records.
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(s) {
+	case "low":
+		return SeverityLow, nil
+	case "medium", "moderate":
+		return SeverityMedium, nil
+	case "high":
+		return SeverityHigh, nil
+	case "critical":
+		return SeverityCritical, nil
+	}
+	return SeverityUnknown, errors.Errorf("unknown severity %q, must be one of low, medium, high, critical", s)
+}
+
+func severityFromScore(score float64) Severity {
+	switch {
+	case score >= 9:
+		return SeverityCritical
+	case score >= 7:
+		return SeverityHigh
+	case score >= 4:
+		return SeverityMedium
+	case score > 0:
+		return SeverityLow
+	}
+	return SeverityUnknown
+}
+
+// osvEcosystems maps OSV ecosystems to the purl type and namespace of their
+// packages.
+var osvEcosystems = map[string]string{
+	"npm":            "pkg:npm/",
+	"PyPI":           "pkg:pypi/",
+	"Go":             "pkg:golang/",
+	"Maven":          "pkg:maven/",
+	"crates.io":      "pkg:cargo/",
+	"RubyGems":       "pkg:gem/",
+	"NuGet":          "pkg:nuget/",
+	"Packagist":      "pkg:composer/",
+	"Pub":            "pkg:pub/",
+	"Hex":            "pkg:hex/",
+	"SwiftURL":       "pkg:swift/",
+	"GitHub Actions": "pkg:github/",
+	"Alpine":         "pkg:apk/alpine/",
+	"Debian":         "pkg:deb/debian/",
+	"Ubuntu":         "pkg:deb/ubuntu/",
+	"Red Hat":        "pkg:rpm/redhat/",
+	"AlmaLinux":      "pkg:rpm/almalinux/",
+	"Rocky Linux":    "pkg:rpm/rocky-linux/",
+	"Wolfi":          "pkg:apk/wolfi/",
+	"Chainguard":     "pkg:apk/chainguard/",
+}
+
+// osvRecord is a vulnerability in the OSV format.
+type osvRecord struct {
+	ID        string   `json:"id"`
+	Modified  string   `json:"modified"`
+	Withdrawn string   `json:"withdrawn"`
+	Aliases   []string `json:"aliases"`
+	Summary   string   `json:"summary"`
+	Severity  []struct {
+		Type  string `json:"type"`
+		Score string `json:"score"`
+	} `json:"severity"`
+	Affected         []osvAffected   `json:"affected"`
+	DatabaseSpecific json.RawMessage `json:"database_specific"`
+}
+
+type osvAffected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+		PURL      string `json:"purl"`
+	} `json:"package"`
+	Ranges []struct {
+		Type   string              `json:"type"`
+		Events []map[string]string `json:"events"`
+	} `json:"ranges"`
+	Versions []string `json:"versions"`
+
+	record *osvRecord
+	// release is the distribution release the ecosystem is restricted to,
+	// e.g. "v3.19" for "Alpine:v3.19".
+	release string
+}
+
+// VulnDB is a local snapshot of vulnerabilities in the OSV format.
+type VulnDB struct {
+	// Path is the location of the database.
+	Path string
+	// LastUpdate is the most recent modification time of its records.
+	LastUpdate time.Time
+
+	byPURL map[string][]*osvAffected
+}
+
+// LoadVulnDB loads OSV records from p: a directory of JSON records, such as
+// an extracted OSV export, a zip archive of them, as published by OSV for
+// each ecosystem, or a single JSON record or list of records.
+func LoadVulnDB(p string) (*VulnDB, error) {
+	db := &VulnDB{Path: p, byPURL: map[string][]*osvAffected{}}
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read vulnerability database %q", p)
+	}
+	if fi.IsDir() {
+		err = filepath.WalkDir(p, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			return db.loadFile(p)
+		})
+	} else {
+		err = db.loadFile(p)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read vulnerability database %q", p)
+	}
+	return db, nil
+}
+
+func (db *VulnDB) loadFile(p string) error {
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".json":
+		dt, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return errors.Wrap(db.add(dt), p)
+	case ".zip":
+		zr, err := zip.OpenReader(p)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if !strings.EqualFold(filepath.Ext(f.Name), ".json") {
+				continue
+			}
+			r, err := f.Open()
+			if err != nil {
+				return err
+			}
+			dt, err := io.ReadAll(r)
+			r.Close()
+			if err != nil {
+				return err
+			}
+			if err := db.add(dt); err != nil {
+				return errors.Wrapf(err, "%s: %s", p, f.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// add indexes the records in dt, a record or a list of them.
+func (db *VulnDB) add(dt []byte) error {
+	var records []*osvRecord
+	if trimmed := bytes.TrimSpace(dt); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(dt, &records); err != nil {
+			return err
+		}
+	} else {
+		var record osvRecord
+		if err := json.Unmarshal(dt, &record); err != nil {
+			return err
+		}
+		records = append(records, &record)
+	}
+
+	for _, record := range records {
+		if record.ID == "" || record.Withdrawn != "" {
+			continue
+		}
+		if modified, err := time.Parse(time.RFC3339, record.Modified); err == nil && modified.After(db.LastUpdate) {
+			db.LastUpdate = modified
+		}
+		for i := range record.Affected {
+			affected := &record.Affected[i]
+			affected.record = record
+			ecosystem, release, _ := strings.Cut(affected.Package.Ecosystem, ":")
+			affected.release = release
+			key := affected.Package.PURL
+			if key == "" {
+				prefix, ok := osvEcosystems[ecosystem]
+				if !ok {
+					continue
+				}
+				name := affected.Package.Name
+				if ecosystem == "Maven" {
+					name = strings.Replace(name, ":", "/", 1)
+				}
+				key = prefix + name
+			}
+			key = normalizePURL(key)
+			db.byPURL[key] = append(db.byPURL[key], affected)
+		}
+	}
+	return nil
+}
+
+// pypiSeparators are the runs of characters PEP 503 normalizes Python
+// package names on.
+var pypiSeparators = regexp.MustCompile(`[-_.]+`)
+
+// normalizePURL returns purl without version, qualifiers and subpath, with
+// its path unescaped and lowercased, and the names of Python packages
+// normalized as per PEP 503.
+func normalizePURL(purl string) string {
+	base, _ := splitPURL(purl)
+	if unescaped, err := url.PathUnescape(base); err == nil {
+		base = unescaped
+	}
+	base = strings.ToLower(base)
+	if name, ok := strings.CutPrefix(base, "pkg:pypi/"); ok {
+		base = "pkg:pypi/" + pypiSeparators.ReplaceAllString(name, "-")
+	}
+	return base
+}
+
+// vulnLookup is a purl the records affecting a package are indexed by, and
+// the version of the package to match them against.
+type vulnLookup struct {
+	key     string
+	version string
+}
+
+// vulnLookups returns the lookups for the package with purl and version v:
+// its purl, and for distribution packages, the purl of their source package,
+// e.g. glibc for libc6, as distribution records name source packages.
+func vulnLookups(purl string, v string) []vulnLookup {
+	key := normalizePURL(purl)
+	lookups := []vulnLookup{{key: key, version: v}}
+	_, query, ok := strings.Cut(purl, "?")
+	if !ok {
+		return lookups
+	}
+	values, err := url.ParseQuery(strings.SplitN(query, "#", 2)[0])
+	if err != nil || values.Get("upstream") == "" {
+		return lookups
+	}
+	source, version := values.Get("upstream"), v
+	switch purlType(purl) {
+	case "deb":
+		// e.g. glibc@2.36-9+deb12u4 for binary NMUs
+		if name, sourceVersion, ok := strings.Cut(source, "@"); ok {
+			source, version = name, sourceVersion
+		}
+	case "rpm":
+		// e.g. glibc-2.36-18.fc39.src.rpm
+		source = strings.TrimSuffix(strings.TrimSuffix(source, ".src.rpm"), ".nosrc.rpm")
+		for range 2 {
+			if i := strings.LastIndexByte(source, '-'); i > 0 {
+				source = source[:i]
+			}
+		}
+	case "apk":
+		// the origin package, e.g. openssl for libcrypto3
+	default:
+		return lookups
+	}
+	if sourceKey := key[:strings.LastIndexByte(key, '/')+1] + strings.ToLower(source); sourceKey != key {
+		lookups = append(lookups, vulnLookup{key: sourceKey, version: version})
+	}
+	return lookups
+}
+
+// VulnMatch is a vulnerability affecting a package.
+type VulnMatch struct {
+	ID        string
+	Aliases   []string
+	Summary   string
+	Severity  Severity
+	Score     float64
+	Package   string
+	PURL      string
+	FixedIn   []string
+	Locations []string
+}
+
+// Match returns the vulnerabilities affecting the packages of s, most
+// severe first.
+func (db *VulnDB) Match(s sbom.SBOM) []VulnMatch {
+	var matches []VulnMatch
+	for _, p := range s.Artifacts.Packages.Sorted() {
+		if p.PURL == "" {
+			continue
+		}
+		_, purlVersion := splitPURL(p.PURL)
+		v := p.Version
+		if v == "" {
+			v = purlVersion
+		}
+		compare := versionComparator(purlType(p.PURL))
+		seen := map[string]struct{}{}
+		for _, lookup := range vulnLookups(p.PURL, v) {
+			for _, affected := range db.byPURL[lookup.key] {
+				if _, ok := seen[affected.record.ID]; ok {
+					continue
+				}
+				if !affected.matchesRelease(p.PURL) {
+					continue
+				}
+				fixed, ok := affected.affects(lookup.version, compare)
+				if !ok {
+					continue
+				}
+				seen[affected.record.ID] = struct{}{}
+				m := VulnMatch{
+					ID:      affected.record.ID,
+					Aliases: affected.record.Aliases,
+					Summary: affected.record.Summary,
+					Package: p.Name + "@" + p.Version,
+					PURL:    p.PURL,
+					FixedIn: fixed,
+				}
+				m.Severity, m.Score = affected.record.severity()
+				for _, l := range p.Locations.ToSlice() {
+					m.Locations = append(m.Locations, l.RealPath)
+				}
+				matches = append(matches, m)
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Severity != matches[j].Severity {
+			return matches[i].Severity > matches[j].Severity
+		}
+		if matches[i].ID != matches[j].ID {
+			return matches[i].ID < matches[j].ID
+		}
+		return matches[i].PURL < matches[j].PURL
+	})
+	return matches
+}
+
+// matchesRelease reports whether the distribution of the package with purl
+// is the release the affected entry is restricted to, if any.
+func (a *osvAffected) matchesRelease(purl string) bool {
+	if a.release == "" {
+		return true
+	}
+	_, query, ok := strings.Cut(purl, "?")
+	if !ok {
+		return true
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil || values.Get("distro") == "" {
+		return true
+	}
+	// e.g. distro=alpine-3.19.1 for Alpine:v3.19, distro=debian-12 for
+	// Debian:12, distro=ubuntu-22.04 for Ubuntu:22.04:LTS
+	_, distroVersion, _ := strings.Cut(values.Get("distro"), "-")
+	for _, release := range strings.Split(a.release, ":") {
+		release = strings.TrimPrefix(release, "v")
+		if release == "" || !isDigit(release[0]) {
+			continue
+		}
+		return distroVersion == release || strings.HasPrefix(distroVersion, release+".")
+	}
+	return true
+}
+
+// affects reports whether version v is affected, and the versions that fix
+// it. The versions of ECOSYSTEM ranges are compared with compare.
+func (a *osvAffected) affects(v string, compare func(a, b string) int) ([]string, bool) {
+	var fixed []string
+	affected := false
+	for _, known := range a.Versions {
+		if known == v {
+			affected = true
+		}
+	}
+	for _, r := range a.Ranges {
+		cmp := compareVersions
+		switch r.Type {
+		case "SEMVER":
+		case "ECOSYSTEM":
+			cmp = func(a, b string) int {
+				// "0" is before any version
+				switch {
+				case a == b:
+					return 0
+				case a == "0":
+					return -1
+				case b == "0":
+					return 1
+				}
+				return compare(a, b)
+			}
+		default:
+			continue
+		}
+		type event struct{ kind, version string }
+		var events []event
+		for _, e := range r.Events {
+			for kind, version := range e {
+				events = append(events, event{kind, version})
+				if kind == "fixed" {
+					fixed = append(fixed, version)
+				}
+			}
+		}
+		sort.SliceStable(events, func(i, j int) bool {
+			return cmp(events[i].version, events[j].version) < 0
+		})
+		inRange := false
+		for _, e := range events {
+			switch e.kind {
+			case "introduced":
+				if e.version == "0" || cmp(v, e.version) >= 0 {
+					inRange = true
+				}
+			case "fixed":
+				if cmp(v, e.version) >= 0 {
+					inRange = false
+				}
+			case "last_affected":
+				if cmp(v, e.version) > 0 {
+					inRange = false
+				}
+			}
+		}
+		affected = affected || inRange
+	}
+	return fixed, affected
+}
+
+// severity returns the severity of the record, from its CVSS v3 score if it
+// has one, otherwise from the severity assigned by its database.
+func (r *osvRecord) severity() (Severity, float64) {
+	for _, s := range r.Severity {
+		if s.Type != "CVSS_V3" {
+			continue
+		}
+		score, err := cvss3BaseScore(s.Score)
+		if err != nil {
+			if score, err := strconv.ParseFloat(s.Score, 64); err == nil {
+				return severityFromScore(score), score
+			}
+			continue
+		}
+		return severityFromScore(score), score
+	}
+	var specific struct {
+		Severity string `json:"severity"`
+	}
+	if len(r.DatabaseSpecific) > 0 && json.Unmarshal(r.DatabaseSpecific, &specific) == nil {
+		if s, err := ParseSeverity(specific.Severity); err == nil {
+			return s, 0
+		}
+	}
+	return SeverityUnknown, 0
+}
+
+// compareVersions compares versions as semver if both are, and otherwise
+// segment by segment, comparing numbers numerically.
+func compareVersions(a, b string) int {
+	if a == "0" || b == "0" {
+		switch {
+		case a == b:
+			return 0
+		case a == "0":
+			return -1
+		default:
+			return 1
+		}
+	}
+	if va, err := semver.StrictNewVersion(strings.TrimPrefix(a, "v")); err == nil {
+		if vb, err := semver.StrictNewVersion(strings.TrimPrefix(b, "v")); err == nil {
+			return va.Compare(vb)
+		}
+	}
+	sa, sb := versionSegments(a), versionSegments(b)
+	for i := 0; i < len(sa) && i < len(sb); i++ {
+		na, errA := strconv.ParseUint(sa[i], 10, 64)
+		nb, errB := strconv.ParseUint(sb[i], 10, 64)
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+		case sa[i] != sb[i]:
+			if sa[i] < sb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(sa) < len(sb):
+		return -1
+	case len(sa) > len(sb):
+		return 1
+	}
+	return 0
+}
+
+// versionSegments splits v into runs of digits and of letters, dropping
+// separators.
+func versionSegments(v string) []string {
+	var segments []string
+	var current []rune
+	var digits bool
+	flush := func() {
+		if len(current) > 0 {
+			segments = append(segments, string(current))
+			current = nil
+		}
+	}
+	for _, r := range v {
+		switch {
+		case unicode.IsDigit(r):
+			if !digits {
+				flush()
+			}
+			digits = true
+			current = append(current, r)
+		case unicode.IsLetter(r):
+			if digits {
+				flush()
+			}
+			digits = false
+			current = append(current, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return segments
+}
+
+// vulnReport is the in-toto vulns predicate.
+type vulnReport struct {
+	Scanner struct {
+		URI     string `json:"uri"`
+		Version string `json:"version"`
+		DB      struct {
+			URI        string `json:"uri"`
+			LastUpdate string `json:"lastUpdate,omitempty"`
+		} `json:"db"`
+		Result []vulnResult `json:"result"`
+	} `json:"scanner"`
+	Metadata struct {
+		ScanStartedOn  string `json:"scanStartedOn"`
+		ScanFinishedOn string `json:"scanFinishedOn"`
+	} `json:"metadata"`
+}
+
+type vulnResult struct {
+	ID       string `json:"id"`
+	Severity []struct {
+		Method string `json:"method"`
+		Score  string `json:"score"`
+	} `json:"severity"`
+	Annotations []map[string]interface{} `json:"annotations"`
+}
+
+// newVulnReport builds the vulns predicate for matches.
+func newVulnReport(db *VulnDB, matches []VulnMatch, started, finished time.Time) vulnReport {
+	var report vulnReport
+	report.Scanner.URI = version.Package
+	report.Scanner.Version = version.Version
+	report.Scanner.DB.URI = filepath.ToSlash(db.Path)
+	if !db.LastUpdate.IsZero() {
+		report.Scanner.DB.LastUpdate = db.LastUpdate.UTC().Format(time.RFC3339)
+	}
+	report.Scanner.Result = []vulnResult{}
+	for _, m := range matches {
+		r := vulnResult{ID: m.ID}
+		if m.Score > 0 {
+			r.Severity = append(r.Severity, struct {
+				Method string `json:"method"`
+				Score  string `json:"score"`
+			}{"CVSSv3", strconv.FormatFloat(m.Score, 'f', 1, 64)})
+		}
+		r.Severity = append(r.Severity, struct {
+			Method string `json:"method"`
+			Score  string `json:"score"`
+		}{"qualitative", m.Severity.String()})
+		annotation := map[string]interface{}{
+			"package": m.Package,
+			"purl":    m.PURL,
+		}
+		if len(m.Aliases) > 0 {
+			annotation["aliases"] = m.Aliases
+		}
+		if m.Summary != "" {
+			annotation["summary"] = m.Summary
+		}
+		if len(m.FixedIn) > 0 {
+			annotation["fixedIn"] = m.FixedIn
+		}
+		if len(m.Locations) > 0 {
+			annotation["locations"] = m.Locations
+		}
+		r.Annotations = []map[string]interface{}{annotation}
+		report.Scanner.Result = append(report.Scanner.Result, r)
+	}
+	report.Metadata.ScanStartedOn = started.UTC().Format(time.RFC3339)
+	report.Metadata.ScanFinishedOn = finished.UTC().Format(time.RFC3339)
+	return report
+}
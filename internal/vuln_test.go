@@ -0,0 +1,368 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/sbom"
+)
+
+func TestCVSS3BaseScore(t *testing.T) {
+	for vector, want := range map[string]float64{
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H": 9.8,
+		"CVSS:3.1/AV:N/AC:L/PR:L/UI:N/S:C/C:L/I:L/A:N": 6.4,
+		"CVSS:3.0/AV:L/AC:L/PR:L/UI:N/S:U/C:H/I:N/A:N": 5.5,
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N": 0,
+	} {
+		got, err := cvss3BaseScore(vector)
+		if err != nil {
+			t.Errorf("%s: %v", vector, err)
+		} else if got != want {
+			t.Errorf("%s: expected %.1f, got %.1f", vector, want, got)
+		}
+	}
+	for _, vector := range []string{
+		"CVSS:2.0/AV:N/AC:L/Au:N/C:P/I:P/A:P",
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/C:H/I:H/A:H",
+		"CVSS:3.1/AV:X/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+	} {
+		if _, err := cvss3BaseScore(vector); err == nil {
+			t.Errorf("%s: expected an error", vector)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"0", "0.0.1", -1},
+		{"1.2.3", "1.2.3", 0},
+		{"v1.10.0", "v1.9.0", 1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.2.4_git20230717-r4", "1.2.4_git20230717-r5", -1},
+		{"2.36-9+deb12u4", "2.36-9+deb12u10", -1},
+		{"1.36.1-r15", "1.36.1-r15", 0},
+		{"1.0", "1.0.1", -1},
+	} {
+		if got := compareVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("compareVersions(%q, %q): expected %d, got %d", tc.a, tc.b, tc.want, got)
+		}
+	}
+}
+
+// osvFixture is a vulnerability database with npm records as JSON files and
+// Alpine records in a zip archive, as exported by OSV.
+func osvFixture(t *testing.T) string {
+	dir := t.TempDir()
+	buildFixture(t, dir, files(map[string]string{
+		"npm/GHSA-ms-affected.json": `{
+			"id": "GHSA-ms-affected",
+			"modified": "2024-01-02T00:00:00Z",
+			"aliases": ["CVE-2024-0001"],
+			"summary": "ms is slow",
+			"severity": [{"type": "CVSS_V3", "score": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}],
+			"affected": [{
+				"package": {"ecosystem": "npm", "name": "ms"},
+				"ranges": [{"type": "SEMVER", "events": [{"introduced": "2.0.0"}, {"fixed": "2.1.4"}]}]
+			}]
+		}`,
+		"npm/records.json": `[{
+			"id": "GHSA-ms-old",
+			"modified": "2023-01-01T00:00:00Z",
+			"affected": [{
+				"package": {"ecosystem": "npm", "name": "ms"},
+				"ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "2.0.0"}]}]
+			}]
+		}, {
+			"id": "GHSA-ms-withdrawn",
+			"modified": "2025-01-01T00:00:00Z",
+			"withdrawn": "2025-01-01T00:00:00Z",
+			"affected": [{
+				"package": {"ecosystem": "npm", "name": "ms"},
+				"ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}]}]
+			}]
+		}]`,
+		"README.md": "not a record",
+	}))
+
+	f, err := os.Create(filepath.Join(dir, "alpine.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, record := range map[string]string{
+		"ALPINE-musl.json": `{
+			"id": "ALPINE-musl",
+			"modified": "2024-01-01T00:00:00Z",
+			"affected": [{
+				"package": {"ecosystem": "Alpine:v3.19", "name": "musl"},
+				"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "1.2.4_git20230717-r5"}]}]
+			}],
+			"database_specific": {"severity": "high"}
+		}`,
+		"ALPINE-musl-old.json": `{
+			"id": "ALPINE-musl-old",
+			"modified": "2024-01-01T00:00:00Z",
+			"affected": [{
+				"package": {"ecosystem": "Alpine:v3.18", "name": "musl"},
+				"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}]}]
+			}]
+		}`,
+		"ALPINE-busybox.json": `{
+			"id": "ALPINE-busybox",
+			"modified": "2024-01-01T00:00:00Z",
+			"affected": [{
+				"package": {"ecosystem": "Alpine:v3.19", "name": "busybox"},
+				"versions": ["1.36.1-r15"]
+			}],
+			"database_specific": {"severity": "moderate"}
+		}`,
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(record)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// osvDistroFixture holds records as published by OSV for Debian, Alpine
+// and PyPI, which name source packages and use the version schemes of their
+// ecosystem.
+var osvDistroFixture = files(map[string]string{
+	"debian/DSA-5514-1.json": `{
+		"id": "DSA-5514-1",
+		"summary": "glibc - security update",
+		"schema_version": "1.6.0",
+		"published": "2023-10-03T00:00:00Z",
+		"modified": "2023-10-03T18:07:35Z",
+		"related": ["CVE-2023-4527", "CVE-2023-4806", "CVE-2023-4911"],
+		"affected": [{
+			"package": {"ecosystem": "Debian:11", "name": "glibc", "purl": "pkg:deb/debian/glibc?arch=source"},
+			"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "2.31-13+deb11u7"}]}]
+		}, {
+			"package": {"ecosystem": "Debian:12", "name": "glibc", "purl": "pkg:deb/debian/glibc?arch=source"},
+			"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "2.36-9+deb12u3"}]}],
+			"versions": ["2.36-8", "2.36-9", "2.36-9+deb12u1", "2.36-9+deb12u2"]
+		}],
+		"references": [{"type": "ADVISORY", "url": "https://www.debian.org/security/2023/dsa-5514"}]
+	}`,
+	"debian/DSA-5678-1.json": `{
+		"id": "DSA-5678-1",
+		"summary": "glibc - security update",
+		"schema_version": "1.6.0",
+		"published": "2024-04-29T00:00:00Z",
+		"modified": "2024-04-29T21:18:59Z",
+		"related": ["CVE-2024-2961", "CVE-2024-33599", "CVE-2024-33600", "CVE-2024-33601", "CVE-2024-33602"],
+		"affected": [{
+			"package": {"ecosystem": "Debian:11", "name": "glibc", "purl": "pkg:deb/debian/glibc?arch=source"},
+			"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "2.31-13+deb11u10"}]}]
+		}, {
+			"package": {"ecosystem": "Debian:12", "name": "glibc", "purl": "pkg:deb/debian/glibc?arch=source"},
+			"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "2.36-9+deb12u7"}]}]
+		}],
+		"references": [{"type": "ADVISORY", "url": "https://www.debian.org/security/2024/dsa-5678"}]
+	}`,
+	"alpine/ALPINE-CVE-2025-26519.json": `{
+		"id": "ALPINE-CVE-2025-26519",
+		"schema_version": "1.6.0",
+		"published": "2025-02-14T04:15:09Z",
+		"modified": "2025-03-01T00:00:00Z",
+		"upstream": ["CVE-2025-26519"],
+		"details": "musl libc 0.9.13 through 1.2.5 before 1.2.6 has an out-of-bounds write vulnerability when an attacker can trigger iconv conversion of untrusted EUC-KR text to UTF-8.",
+		"affected": [{
+			"package": {"ecosystem": "Alpine:v3.19", "name": "musl", "purl": "pkg:apk/alpine/musl?arch=source"},
+			"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "1.2.4_git20230717-r5"}]}]
+		}, {
+			"package": {"ecosystem": "Alpine:v3.21", "name": "musl", "purl": "pkg:apk/alpine/musl?arch=source"},
+			"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "1.2.5-r9"}]}]
+		}]
+	}`,
+	"pypi/GHSA-2jv5-9r88-3w3p.json": `{
+		"id": "GHSA-2jv5-9r88-3w3p",
+		"summary": "python-multipart vulnerable to Content-Type Header ReDoS",
+		"schema_version": "1.6.0",
+		"modified": "2024-02-16T22:31:36Z",
+		"aliases": ["CVE-2024-24762"],
+		"affected": [{
+			"package": {"ecosystem": "PyPI", "name": "python-multipart", "purl": "pkg:pypi/python-multipart"},
+			"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "0.0.7"}]}]
+		}],
+		"database_specific": {"severity": "HIGH"}
+	}`,
+})
+
+func TestVulnDBDistroRecords(t *testing.T) {
+	db, err := LoadVulnDB(buildFixture(t, t.TempDir(), osvDistroFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	match := func(s sbom.SBOM) []string {
+		var ids []string
+		for _, m := range db.Match(s) {
+			ids = append(ids, m.ID+" "+m.Package+" "+strings.Join(m.FixedIn, ","))
+		}
+		return ids
+	}
+
+	for name, tc := range map[string]struct {
+		fixture fixture
+		want    []string
+	}{
+		// libc6 is built from glibc, fixed by DSA-5514-1 in 2.36-9+deb12u3
+		"debian": {fixture: debianFixture, want: []string{"DSA-5678-1 libc6@2.36-9+deb12u4 2.36-9+deb12u7"}},
+		"alpine": {fixture: alpineFixture, want: []string{"ALPINE-CVE-2025-26519 musl@1.2.4_git20230717-r4 1.2.4_git20230717-r5"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s, err := Target{Path: buildFixture(t, filepath.Join(t.TempDir(), "sbom"), tc.fixture)}.Scan(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := match(s); !slices.Equal(got, tc.want) {
+				t.Errorf("expected matches %v, got %v", tc.want, got)
+			}
+		})
+	}
+
+	t.Run("pypi", func(t *testing.T) {
+		// distributions may name the package python_multipart
+		p := pkg.Package{Name: "python_multipart", Version: "0.0.6", PURL: "pkg:pypi/python_multipart@0.0.6", Type: pkg.PythonPkg}
+		p.SetID()
+		got := match(sbom.SBOM{Artifacts: sbom.Artifacts{Packages: pkg.NewCollection(p)}})
+		if want := []string{"GHSA-2jv5-9r88-3w3p python_multipart@0.0.6 0.0.7"}; !slices.Equal(got, want) {
+			t.Errorf("expected matches %v, got %v", want, got)
+		}
+	})
+}
+
+func TestScanVulnDB(t *testing.T) {
+	db, err := LoadVulnDB(osvFixture(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	cfg.SelectCatalogers = []string{"+javascript-lock-cataloger"}
+	core := Target{Path: buildFixture(t, filepath.Join(t.TempDir(), "sbom"), alpineFixture, npmLockFixture), Config: &cfg}
+	formats, err := ParseFormats("spdx-json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scan := func(t *testing.T, failOn Severity) (string, error) {
+		scanner := Scanner{
+			Core:            core,
+			Destination:     t.TempDir(),
+			Formats:         formats,
+			SourceDateEpoch: time.Unix(1700000000, 0),
+			VulnDB:          db,
+			VulnFailOn:      failOn,
+		}
+		return scanner.Destination, scanner.Scan(context.Background())
+	}
+
+	t.Run("report", func(t *testing.T) {
+		dest, err := scan(t, SeverityUnknown)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertStatement(t, filepath.Join(dest, "sbom"+VulnsSuffix), `{
+			"_type": "https://in-toto.io/Statement/v1",
+			"predicateType": "https://in-toto.io/attestation/vulns/v0.1",
+			"predicate": {
+				"scanner": {
+					"uri": "github.com/docker/buildkit-syft-scanner",
+					"db": {"lastUpdate": "2024-01-02T00:00:00Z"},
+					"result": [
+						{
+							"id": "GHSA-ms-affected",
+							"severity": [{"method": "CVSSv3", "score": "9.8"}, {"method": "qualitative", "score": "critical"}],
+							"annotations": [{
+								"package": "ms@2.1.3",
+								"purl": "pkg:npm/ms@2.1.3",
+								"aliases": ["CVE-2024-0001"],
+								"fixedIn": ["2.1.4"],
+								"locations": ["/app/package-lock.json"]
+							}]
+						},
+						{
+							"id": "ALPINE-musl",
+							"severity": [{"method": "qualitative", "score": "high"}],
+							"annotations": [{"package": "musl@1.2.4_git20230717-r4", "fixedIn": ["1.2.4_git20230717-r5"]}]
+						},
+						{
+							"id": "ALPINE-busybox",
+							"severity": [{"method": "qualitative", "score": "medium"}],
+							"annotations": [{"package": "busybox@1.36.1-r15"}]
+						}
+					]
+				},
+				"metadata": {
+					"scanStartedOn": "2023-11-14T22:13:20Z",
+					"scanFinishedOn": "2023-11-14T22:13:20Z"
+				}
+			}
+		}`)
+		var stmt struct {
+			Predicate vulnReport `json:"predicate"`
+		}
+		dt, err := os.ReadFile(filepath.Join(dest, "sbom"+VulnsSuffix))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(dt, &stmt); err != nil {
+			t.Fatal(err)
+		}
+		if n := len(stmt.Predicate.Scanner.Result); n != 3 {
+			t.Errorf("expected 3 vulnerabilities, got %d", n)
+		}
+		if _, err := os.Stat(filepath.Join(dest, "sbom.spdx.json")); err != nil {
+			t.Errorf("expected the SBOM to be written: %v", err)
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		dest, err := scan(t, SeverityHigh)
+		if ErrorKindOf(err) != ErrorPolicy {
+			t.Fatalf("expected a policy error, got %v", err)
+		}
+		want := `2 vulnerabilities at or above high severity:
+GHSA-ms-affected (critical): ms@2.1.3
+ALPINE-musl (high): musl@1.2.4_git20230717-r4`
+		if err.Error() != want {
+			t.Errorf("unexpected error:\n%s\nexpected:\n%s", err, want)
+		}
+		if _, err := os.Stat(filepath.Join(dest, "sbom"+VulnsSuffix)); !os.IsNotExist(err) {
+			t.Errorf("expected no statement to be written")
+		}
+	})
+}
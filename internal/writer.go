@@ -0,0 +1,150 @@
+// Copyright 2026 buildkit-syft-scanner authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// FailureMarker is the file written to the destination when the scan fails,
+// in place of any statement.
+const FailureMarker = "syft-scanner.failed"
+
+// statementWriter writes files to the destination as a single unit: files
+// are staged as temporary files, and only renamed to their final names once
+// all of them have been written and synced.
+type statementWriter struct {
+	dir string
+	// signer, if set, signs every staged statement, staging the envelope
+	// next to it.
+	signer *Signer
+
+	staged    []stagedFile
+	committed []string
+}
+
+type stagedFile struct {
+	tmp  string
+	name string
+}
+
+func newStatementWriter(dir string) *statementWriter {
+	return &statementWriter{dir: dir}
+}
+
+// Stage writes v as JSON to a temporary file, to be renamed to name on
+// Commit.
+func (w *statementWriter) Stage(name string, v interface{}) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return errors.Wrapf(err, "failed to write %q", name)
+	}
+	if err := w.stage(name, buf.Bytes()); err != nil {
+		return err
+	}
+	if w.signer == nil {
+		return nil
+	}
+	sig, err := w.signer.sign(context.Background(), name, buf.Bytes())
+	if err != nil {
+		return err
+	}
+	buf.Reset()
+	if err := json.NewEncoder(&buf).Encode(sig); err != nil {
+		return errors.Wrapf(err, "failed to sign %q", name)
+	}
+	return w.stage(strings.TrimSuffix(name, ".json")+SignatureSuffix, buf.Bytes())
+}
+
+func (w *statementWriter) stage(name string, dt []byte) (retErr error) {
+	// temporary files never have a .json extension, so that they cannot be
+	// mistaken for statements if left behind
+	f, err := os.CreateTemp(w.dir, "."+name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	w.staged = append(w.staged, stagedFile{tmp: f.Name(), name: name})
+	defer func() {
+		if err := f.Close(); retErr == nil && err != nil {
+			retErr = err
+		}
+	}()
+
+	if _, err := f.Write(dt); err != nil {
+		return errors.Wrapf(err, "failed to write %q", name)
+	}
+	return f.Sync()
+}
+
+// Commit renames all staged files to their final names.
+func (w *statementWriter) Commit() error {
+	for len(w.staged) > 0 {
+		f := w.staged[0]
+		final := filepath.Join(w.dir, f.name)
+		if err := os.Rename(f.tmp, final); err != nil {
+			return errors.Wrapf(err, "failed to commit %q", f.name)
+		}
+		w.staged = w.staged[1:]
+		w.committed = append(w.committed, final)
+	}
+	return syncDir(w.dir)
+}
+
+// Abort removes every file written so far, and replaces them with the
+// FailureMarker describing cause.
+func (w *statementWriter) Abort(cause error) error {
+	var errs []error
+	for _, f := range w.staged {
+		if err := os.Remove(f.tmp); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	for _, p := range w.committed {
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	w.staged, w.committed = nil, nil
+
+	if err := os.WriteFile(filepath.Join(w.dir, FailureMarker), []byte(cause.Error()+"\n"), 0o644); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errors.Errorf("failed to clean up destination: %v", errs)
+	}
+	return syncDir(w.dir)
+}
+
+// syncDir flushes renames in dir to disk.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		// directories cannot be opened for syncing on windows
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}